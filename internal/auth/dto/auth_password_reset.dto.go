@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"Market_backend/internal/common/validate"
+)
+
+// PasswordResetRequest — запрос кода для сброса забытого пароля
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+func (p *PasswordResetRequest) Validate() error {
+	return validate.Validate.Struct(p)
+}
+
+// PasswordResetConfirm — код из письма и новый пароль
+type PasswordResetConfirm struct {
+	Email          string `json:"email" validate:"required,email"`
+	Code           string `json:"code" validate:"required,len=6"`
+	Password       string `json:"password" validate:"required,password"`
+	RepeatPassword string `json:"repeat_password" validate:"required,password"`
+}
+
+func (p *PasswordResetConfirm) Validate() error {
+	return validate.Validate.Struct(p)
+}
@@ -3,6 +3,8 @@ package handler
 import (
 	"Market_backend/internal/auth/dto"
 	"Market_backend/internal/auth/service"
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -116,6 +118,59 @@ func (handler *AuthHandler) Logout(c *fiber.Ctx) error {
 	})
 }
 
+// RequestPasswordReset — отправка кода для сброса забытого пароля
+func (handler *AuthHandler) RequestPasswordReset(c *fiber.Ctx) error {
+	var body dto.PasswordResetRequest
+	if err := c.BodyParser(&body); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := handler.service.RequestPasswordReset(body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Одинаковый ответ, есть такой пользователь или нет
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "if the email is registered, a reset code has been sent",
+	})
+}
+
+// ResetPassword — новый пароль по коду из письма; все сессии пользователя завершаются
+func (handler *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var body dto.PasswordResetConfirm
+	if err := c.BodyParser(&body); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := handler.service.ResetPassword(body); err != nil {
+		if errors.Is(err, service.ErrTooManyAttempts) {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Refresh-токен в этом браузере тоже больше не действует
+	c.Cookie(&fiber.Cookie{
+		Name:     "refresh_token",
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/",
+		HTTPOnly: true,
+		Secure:   true,
+		SameSite: "Lax",
+	})
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "password changed successfully",
+	})
+}
+
 func (handler *AuthHandler) ConfirmCode(c *fiber.Ctx) error {
 	type ConfirmDTO struct {
 		Email string `json:"email"`
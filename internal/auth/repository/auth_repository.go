@@ -53,6 +53,11 @@ func (r *AuthRepository) DeleteRefreshTokenByHash(hash string) error {
 	return r.db.Where("token_hash = ?", hash).Delete(&models.RefreshToken{}).Error
 }
 
+// Отзываем все refresh-токены пользователя внутри транзакции
+func (r *AuthRepository) DeleteRefreshTokensByUserTx(tx *gorm.DB, userID uuid.UUID) error {
+	return tx.Where("user_id = ?", userID).Delete(&models.RefreshToken{}).Error
+}
+
 // ===================== User =====================
 
 func (r *AuthRepository) GetUserByEmail(email string) (*models.User, error) {
@@ -82,6 +87,13 @@ func (r *AuthRepository) GetUserByIDTx(tx *gorm.DB, ID uuid.UUID) (*models.User,
 	return &user, nil
 }
 
+func (r *AuthRepository) UpdatePasswordTx(tx *gorm.DB, userID uuid.UUID, passwordHash string) error {
+	return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password_hash": passwordHash,
+		"updated_at":    time.Now(),
+	}).Error
+}
+
 func (r *AuthRepository) DeleteUserByEmail(email string) error {
 	return r.db.Where("email = ?", email).Delete(&models.User{}).Error
 }
@@ -153,6 +165,27 @@ func (r *AuthRepository) InvalidateCodesTx(tx *gorm.DB, email, codeType string)
 		}).Error
 }
 
+// Сохраняем неверную попытку ввода кода (и то, что код исчерпан)
+func (r *AuthRepository) SaveCodeAttemptTx(tx *gorm.DB, token *models.EmailConfirmation) error {
+	return tx.Model(&models.EmailConfirmation{}).
+		Where("id = ?", token.ID).
+		Updates(map[string]interface{}{
+			"attempts":   token.Attempts,
+			"used":       token.Used,
+			"updated_at": time.Now(),
+		}).Error
+}
+
+// Неверные вводы кодов данного типа с момента since — для rate-limit по почте
+func (r *AuthRepository) CountCodeAttempts(email, codeType string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.EmailConfirmation{}).
+		Select("COALESCE(SUM(attempts), 0)").
+		Where("email = ? AND type = ? AND created_at >= ?", email, codeType, since).
+		Scan(&count).Error
+	return count, err
+}
+
 // Счётчик для rate-limit
 func (r *AuthRepository) CountCodesByEmail(email string, since time.Time) (int64, error) {
 	var count int64
@@ -162,9 +195,11 @@ func (r *AuthRepository) CountCodesByEmail(email string, since time.Time) (int64
 	return count, err
 }
 
+// Последний действующий код с блокировкой строки: параллельные попытки ввода считаются по очереди
 func (r *AuthRepository) GetLatestValidEmailCodeTx(tx *gorm.DB, email, codeType string) (*models.EmailConfirmation, error) {
 	var token models.EmailConfirmation
 	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
 		Where("email = ? AND type = ? AND used = false AND expires_at > ?", email, codeType, time.Now()).
 		Order("created_at DESC").
 		Limit(1).
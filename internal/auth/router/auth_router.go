@@ -2,8 +2,10 @@ package router
 
 import (
 	"Market_backend/internal/auth/handler"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
 func RegisterAuthRouter(app *fiber.App, h *handler.AuthHandler) {
@@ -17,4 +19,13 @@ func RegisterAuthRouter(app *fiber.App, h *handler.AuthHandler) {
 	// Новый маршрут для подтверждения email
 	// Пользователь кликает по ссылке из письма: /auth/verify-email?token=...
 	auth.Post("/verify-email", h.ConfirmCode)
+
+	// Сброс забытого пароля: код на почту, затем новый пароль с этим кодом.
+	// Лимит по IP — против перебора кодов и рассылки писем с одного адреса
+	passwordLimiter := limiter.New(limiter.Config{
+		Max:        10,
+		Expiration: 15 * time.Minute,
+	})
+	auth.Post("/password/forgot", passwordLimiter, h.RequestPasswordReset)
+	auth.Post("/password/reset", passwordLimiter, h.ResetPassword)
 }
@@ -16,9 +16,16 @@ import (
 
 	"Market_backend/models"
 	"errors"
+	"log"
 	"time"
 )
 
+// ErrTooManyAttempts — слишком много неверных вводов кода сброса пароля
+var ErrTooManyAttempts = errors.New("слишком много попыток. Попробуйте позже")
+
+// maxResetAttemptsPerHour — неверных вводов кода сброса на одну почту за час
+const maxResetAttemptsPerHour = 10
+
 type AuthService struct {
 	repo       *repository.AuthRepository
 	cartRepo   *CartRepo.CartRepository
@@ -242,6 +249,132 @@ func (s *AuthService) ConfirmCode(code, email string) (string, string, error) {
 	return accessToken, refreshToken, nil
 }
 
+// ===================== PasswordReset =====================
+
+// RequestPasswordReset отправляет код для сброса пароля.
+// Если пользователя с такой почтой нет, ничего не отправляем и не сообщаем об этом.
+// Ошибки после проверки формата (лимит писем, почтовый сервер) только логируются:
+// по ним можно было бы понять, что почта зарегистрирована.
+func (s *AuthService) RequestPasswordReset(dto dto.PasswordResetRequest) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+
+	user, err := s.repo.GetUserByEmail(dto.Email)
+	if err != nil {
+		log.Printf("password reset: %v", err)
+		return nil
+	}
+	if user == nil {
+		return nil
+	}
+
+	if err := s.checkEmailRateLimit(user.Email); err != nil {
+		log.Printf("password reset for user %s: %v", user.ID, err)
+		return nil
+	}
+
+	resetCode := utils.GenerateSixDigitCode()
+
+	err = s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.InvalidateCodesTx(tx, user.Email, "reset"); err != nil {
+			return err
+		}
+
+		confirmation := &models.EmailConfirmation{
+			UserID:    user.ID,
+			Email:     user.Email,
+			Type:      "reset",
+			Code:      resetCode,
+			ExpiresAt: time.Now().Add(10 * time.Minute),
+			Used:      false,
+		}
+
+		if err := tx.Create(confirmation).Error; err != nil {
+			return err
+		}
+
+		body := fmt.Sprintf(`
+<h1>Market</h1>
+<p>Здравствуйте, %s!</p>
+<p>Код для сброса пароля:</p>
+<h2>%s</h2>
+<p>Код действует 10 минут. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.</p>
+`, user.Name, resetCode)
+
+		return s.mailSender.SendEmail(user.Email, "Сброс пароля", body)
+	})
+	if err != nil {
+		log.Printf("password reset for user %s: %v", user.ID, err)
+	}
+	return nil
+}
+
+// ResetPassword по коду из письма задаёт новый пароль и завершает все сессии пользователя
+func (s *AuthService) ResetPassword(dto dto.PasswordResetConfirm) error {
+	if err := dto.Validate(); err != nil {
+		return err
+	}
+	if dto.Password != dto.RepeatPassword {
+		return errors.New("passwords do not match")
+	}
+
+	// Перебор кода по почте: сколько бы кодов ни было запрошено, неверных вводов за час не больше лимита
+	attempts, err := s.repo.CountCodeAttempts(dto.Email, "reset", time.Now().Add(-1*time.Hour))
+	if err != nil {
+		return err
+	}
+	if attempts >= maxResetAttemptsPerHour {
+		return ErrTooManyAttempts
+	}
+
+	hashPassword, err := utils.HashPassword(dto.Password)
+	if err != nil {
+		return err
+	}
+
+	invalid := false
+	err = s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		confirmation, err := s.repo.GetLatestValidEmailCodeTx(tx, dto.Email, "reset")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			invalid = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// неверная попытка сохраняется, а не откатывается вместе со сбросом
+		if !confirmation.Attempt(dto.Code) {
+			invalid = true
+			return s.repo.SaveCodeAttemptTx(tx, confirmation)
+		}
+
+		if err := s.repo.UpdatePasswordTx(tx, confirmation.UserID, hashPassword); err != nil {
+			return err
+		}
+
+		if err := s.repo.MarkCodeUsedTx(tx, confirmation.ID); err != nil {
+			return err
+		}
+
+		// коды входа выданы по старому паролю — больше не действуют
+		if err := s.repo.InvalidateCodesTx(tx, dto.Email, "login"); err != nil {
+			return err
+		}
+
+		// выходим на всех устройствах
+		return s.repo.DeleteRefreshTokensByUserTx(tx, confirmation.UserID)
+	})
+	if err != nil {
+		return err
+	}
+	if invalid {
+		return errors.New("invalid or expired code")
+	}
+	return nil
+}
+
 // ===================== issueTokens =====================
 func (s *AuthService) issueTokens(user *models.User) (string, string, error) {
 	access, err := auth.GenerateToken(user.ID.String(), string(user.Role), user.Name, user.CartID.String())
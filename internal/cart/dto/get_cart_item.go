@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	"Market_backend/models"
 	"github.com/google/uuid"
@@ -12,7 +13,7 @@ type GetCartItemsResponse struct {
 	ProductType types.ProductType `json:"product_type"`
 	Quantity    int               `json:"quantity"`
 	ImageUrl    string            `json:"image_url"`
-	Price       float64           `json:"price"`
+	Price       money.Money       `json:"price"`
 
 	Name string `json:"name"`
 }
@@ -3,6 +3,7 @@ package repository
 import (
 	"Market_backend/internal/cart/dto"
 	"Market_backend/internal/common"
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	"Market_backend/models"
 	"errors"
@@ -71,7 +72,7 @@ func (r *CartRepository) GetItem(userId, itemId uuid.UUID) (*dto.CartItemWithPro
 	}, nil
 }
 
-func (r *CartRepository) AddNewCartItem(cartItem dto.CartItemDto, price float64) (uuid.UUID, error) {
+func (r *CartRepository) AddNewCartItem(cartItem dto.CartItemDto, price money.Money) (uuid.UUID, error) {
 	var existingItem models.CartItem
 
 	err := r.db.
@@ -128,7 +129,7 @@ func (r *CartRepository) RemoveCartItem(cartItemId, userId uuid.UUID) error {
 	return nil
 }
 
-func (r *CartRepository) ChangeQuantity(cartItemId, userId uuid.UUID, quantity int, unitPrice float64) error {
+func (r *CartRepository) ChangeQuantity(cartItemId, userId uuid.UUID, quantity int, unitPrice money.Money) error {
 	res := r.db.Model(&models.CartItem{}).
 		Where("id = ? AND cart_id IN (SELECT id FROM carts WHERE user_id = ?)", cartItemId, userId).
 		Updates(map[string]interface{}{
@@ -167,7 +168,7 @@ func (r *CartRepository) GetAllCartItems(userId, cartId uuid.UUID) ([]dto.GetCar
 	procMap := map[uuid.UUID]models.Processor{}
 	flashMap := map[uuid.UUID]models.FlashDrive{}
 	imageMap := map[uuid.UUID]string{}
-	priceMap := map[uuid.UUID]float64{}
+	priceMap := map[uuid.UUID]money.Money{}
 
 	// PROCESSORS
 	if len(procIDs) > 0 {
@@ -265,7 +266,7 @@ func (r *CartRepository) GetAllCartItemsTx(tx *gorm.DB, userId, cartId uuid.UUID
 	procMap := map[uuid.UUID]models.Processor{}
 	flashMap := map[uuid.UUID]models.FlashDrive{}
 	imageMap := map[uuid.UUID]string{}
-	priceMap := map[uuid.UUID]float64{}
+	priceMap := map[uuid.UUID]money.Money{}
 
 	// PROCESSORS
 	if len(procIDs) > 0 {
@@ -3,6 +3,7 @@ package service
 import (
 	"Market_backend/internal/cart/dto"
 	"Market_backend/internal/cart/repository"
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	ProductRepo "Market_backend/internal/product/repository"
 
@@ -24,7 +25,7 @@ func NewCartService(repo *repository.CartRepository, procRepo *ProductRepo.Proce
 }
 
 func (s *CartService) AddNewItem(cartItem dto.CartItemDto) (uuid.UUID, error) {
-	var currentPrice float64
+	var currentPrice money.Money
 	//var stock int
 
 	switch cartItem.ProductType {
@@ -89,7 +90,7 @@ func (s *CartService) ChangeItem(cartItemId, userId uuid.UUID, quantity int) err
 		return s.repo.RemoveCartItem(cartItemId, userId)
 	}
 
-	var newPrice float64
+	var newPrice money.Money
 
 	switch p := cartItem.Product.(type) {
 	case *models.Processor:
@@ -117,27 +118,27 @@ func (s *CartService) ClearCart(userId, cartId uuid.UUID) error {
 	return s.repo.ClearCart(userId, cartId)
 }
 
-func (s *CartService) ValidateCart(userId, cartId uuid.UUID) (float64, error) {
+func (s *CartService) ValidateCart(userId, cartId uuid.UUID) (money.Money, error) {
 	cartItems, err := s.repo.GetAllCartItems(userId, cartId)
 	if err != nil {
-		return 0, err
+		return money.Money{}, err
 	}
 
 	if len(cartItems) == 0 {
-		return 0, errors.New("корзина пуста")
+		return money.Money{}, errors.New("корзина пуста")
 	}
 
-	total := 0.0
+	var total money.Money
 	for _, ci := range cartItems {
-		var price float64
+		var price money.Money
 		switch ci.ProductType {
 		case types.Processor:
 			proc, err := s.procRepo.GetProcessorById(ci.ProductId)
 			if err != nil {
-				return 0, err
+				return money.Money{}, err
 			}
 			if ci.Quantity > proc.Stock {
-				return 0, fmt.Errorf("товара %s не хватает на складе", proc.Name)
+				return money.Money{}, fmt.Errorf("товара %s не хватает на складе", proc.Name)
 			}
 			if ci.Quantity >= proc.WholesaleMinQty {
 				price = proc.WholesalePrice
@@ -147,10 +148,10 @@ func (s *CartService) ValidateCart(userId, cartId uuid.UUID) (float64, error) {
 		case types.FlashDriver:
 			flash, err := s.flashRepo.GetFlashDriveById(ci.ProductId)
 			if err != nil {
-				return 0, err
+				return money.Money{}, err
 			}
 			if ci.Quantity > flash.Stock {
-				return 0, fmt.Errorf("товара %s не хватает на складе", flash.Name)
+				return money.Money{}, fmt.Errorf("товара %s не хватает на складе", flash.Name)
 			}
 			if ci.Quantity >= flash.WholesaleMinQty {
 				price = flash.WholesalePrice
@@ -158,36 +159,36 @@ func (s *CartService) ValidateCart(userId, cartId uuid.UUID) (float64, error) {
 				price = flash.RetailPrice
 			}
 		default:
-			return 0, fmt.Errorf("unknown product type: %s", ci.ProductType)
+			return money.Money{}, fmt.Errorf("unknown product type: %s", ci.ProductType)
 		}
-		total += price * float64(ci.Quantity)
+		total = total.Add(price.Mul(ci.Quantity))
 	}
 
 	return total, nil
 }
 
-func (s *CartService) ValidateCartTx(tx *gorm.DB, userId, cartId uuid.UUID) (float64, error) {
+func (s *CartService) ValidateCartTx(tx *gorm.DB, userId, cartId uuid.UUID) (money.Money, error) {
 	cartItems, err := s.repo.GetAllCartItemsTx(tx, userId, cartId)
 	if err != nil {
-		return 0, err
+		return money.Money{}, err
 	}
 
 	if len(cartItems) == 0 {
-		return 0, errors.New("корзина пуста")
+		return money.Money{}, errors.New("корзина пуста")
 	}
 
-	total := 0.0
+	var total money.Money
 	for _, ci := range cartItems {
-		var price float64
+		var price money.Money
 
 		switch ci.ProductType {
 		case types.Processor:
 			proc, err := s.procRepo.GetProcessorByIdTx(tx, ci.ProductId)
 			if err != nil {
-				return 0, err
+				return money.Money{}, err
 			}
 			if ci.Quantity > proc.Stock {
-				return 0, fmt.Errorf("товара %s не хватает на складе", proc.Name)
+				return money.Money{}, fmt.Errorf("товара %s не хватает на складе", proc.Name)
 			}
 			if ci.Quantity >= proc.WholesaleMinQty {
 				price = proc.WholesalePrice
@@ -197,10 +198,10 @@ func (s *CartService) ValidateCartTx(tx *gorm.DB, userId, cartId uuid.UUID) (flo
 		case types.FlashDriver:
 			flash, err := s.flashRepo.GetFlashDriveByIdTx(tx, ci.ProductId)
 			if err != nil {
-				return 0, err
+				return money.Money{}, err
 			}
 			if ci.Quantity > flash.Stock {
-				return 0, fmt.Errorf("товара %s не хватает на складе", flash.Name)
+				return money.Money{}, fmt.Errorf("товара %s не хватает на складе", flash.Name)
 			}
 			if ci.Quantity >= flash.WholesaleMinQty {
 				price = flash.WholesalePrice
@@ -208,10 +209,10 @@ func (s *CartService) ValidateCartTx(tx *gorm.DB, userId, cartId uuid.UUID) (flo
 				price = flash.RetailPrice
 			}
 		default:
-			return 0, fmt.Errorf("unknown product type: %s", ci.ProductType)
+			return money.Money{}, fmt.Errorf("unknown product type: %s", ci.ProductType)
 		}
 
-		total += price * float64(ci.Quantity)
+		total = total.Add(price.Mul(ci.Quantity))
 	}
 
 	return total, nil
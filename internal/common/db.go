@@ -3,6 +3,7 @@ package common
 import (
 	"Market_backend/internal/config"
 	"Market_backend/models"
+	"fmt"
 	"log"
 
 	"gorm.io/driver/postgres"
@@ -44,6 +45,11 @@ func InitDB() {
         END$$;
     `)
 
+	// Новые статусы заказа для уже существующего ENUM
+	for _, status := range []string{"shipped", "delivered", "refunded", "awaiting_approval"} {
+		DB.Exec("ALTER TYPE order_status ADD VALUE IF NOT EXISTS '" + status + "'")
+	}
+
 	DB.Exec(`
     DO $$ BEGIN
         IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'payment_method') THEN
@@ -52,6 +58,10 @@ func InitDB() {
     END$$;
 `)
 
+	for _, method := range []string{"bank_transfer", "cash_on_delivery", "gift_card", "store_credit"} {
+		DB.Exec("ALTER TYPE payment_method ADD VALUE IF NOT EXISTS '" + method + "'")
+	}
+
 	DB.Exec(`
     DO $$ BEGIN
         IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'payment_status') THEN
@@ -60,29 +70,173 @@ func InitDB() {
     END$$;
 `)
 
+	for _, status := range []string{"partially_refunded", "refunded", "waiting_for_capture"} {
+		DB.Exec("ALTER TYPE payment_status ADD VALUE IF NOT EXISTS '" + status + "'")
+	}
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'refund_status') THEN
+            CREATE TYPE refund_status AS ENUM ('pending','succeeded','canceled');
+        END IF;
+    END$$;
+`)
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'balance_reason') THEN
+            CREATE TYPE balance_reason AS ENUM ('issue','payment','refund','adjustment');
+        END IF;
+    END$$;
+`)
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'loyalty_kind') THEN
+            CREATE TYPE loyalty_kind AS ENUM ('earn','spend','expire','reverse','restore');
+        END IF;
+    END$$;
+`)
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'payment_event_status') THEN
+            CREATE TYPE payment_event_status AS ENUM ('received','processed','ignored','rejected');
+        END IF;
+    END$$;
+`)
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'delivery_method') THEN
+            CREATE TYPE delivery_method AS ENUM ('courier','pickup_point','store_pickup');
+        END IF;
+    END$$;
+`)
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'shipment_status') THEN
+            CREATE TYPE shipment_status AS ENUM ('pending','shipped','in_transit','delivered','cancelled');
+        END IF;
+    END$$;
+`)
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'return_status') THEN
+            CREATE TYPE return_status AS ENUM ('requested','approved','rejected','received');
+        END IF;
+    END$$;
+`)
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'document_type') THEN
+            CREATE TYPE document_type AS ENUM ('invoice','upd');
+        END IF;
+    END$$;
+`)
+
+	DB.Exec(`
+    DO $$ BEGIN
+        IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'company_role') THEN
+            CREATE TYPE company_role AS ENUM ('buyer','approver','accountant');
+        END IF;
+    END$$;
+`)
+
+	migrateMoneyColumns()
+
 	// AutoMigrate всех моделей
 	if err := DB.AutoMigrate(
 		// Пользователи и токены
 		&models.User{},
 		&models.RefreshToken{},
 		&models.EmailConfirmation{},
+		&models.Address{},
+		&models.Company{},
+		&models.CompanyMember{},
 
 		// Товары
 		&models.Processor{},
 		&models.FlashDrive{},
 		&models.Image{},
+		&models.StockMovement{},
 
 		// Корзина и заказы
 		&models.Cart{},
 		&models.CartItem{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.OrderStatusHistory{},
 		&models.Payment{},
+		&models.Refund{},
+		&models.RefundItem{},
+		&models.PaymentEvent{},
+		&models.SavedPaymentMethod{},
+		&models.GiftCard{},
+		&models.StoreCreditWallet{},
+		&models.BalanceTransaction{},
+		&models.LoyaltyTransaction{},
+		&models.Shipment{},
+		&models.ShipmentItem{},
+		&models.OrderDocument{},
+		&models.DocumentCounter{},
+
+		// Возвраты
+		&models.ReturnRequest{},
+		&models.ReturnItem{},
+		&models.ReturnPhoto{},
 
 		&models.Message{},
+		&models.OrderMessage{},
+		&models.OrderMessageAttachment{},
+		&models.OrderThreadRead{},
+
+		&models.IdempotencyKey{},
 	); err != nil {
 		log.Fatal("DB migrate error:", err)
 	}
 
 	log.Println("✅ DB initialized and migrated!")
 }
+
+// moneyColumns — колонки с денежными суммами, которые раньше хранились в рублях (double precision)
+var moneyColumns = [][2]string{
+	{"processors", "retail_price"},
+	{"processors", "wholesale_price"},
+	{"flash_drives", "retail_price"},
+	{"flash_drives", "wholesale_price"},
+	{"cart_items", "unit_price"},
+	{"orders", "total"},
+	{"orders", "shipping_cost"},
+	{"orders", "refunded_amount"},
+	{"order_items", "unit_price"},
+	{"payments", "amount"},
+	{"payments", "refunded_amount"},
+	{"payments", "held_amount"},
+	{"refunds", "amount"},
+	{"refund_items", "amount"},
+	{"return_requests", "refund_amount"},
+	{"companies", "approval_limit"},
+	{"order_documents", "vat_amount"},
+	{"order_documents", "total"},
+}
+
+// migrateMoneyColumns переводит суммы в копейки (bigint). Повторный запуск видит bigint и ничего не делает.
+func migrateMoneyColumns() {
+	for _, col := range moneyColumns {
+		DB.Exec(fmt.Sprintf(`
+    DO $$ BEGIN
+        IF EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_name = '%[1]s' AND column_name = '%[2]s'
+              AND data_type IN ('double precision', 'real', 'numeric')
+        ) THEN
+            ALTER TABLE %[1]s ALTER COLUMN %[2]s TYPE bigint USING round(%[2]s * 100)::bigint;
+        END IF;
+    END$$;
+`, col[0], col[1]))
+	}
+}
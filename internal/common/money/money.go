@@ -0,0 +1,220 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// RUB — валюта магазина; сумма без явной валюты считается рублёвой
+const RUB = "RUB"
+
+var ErrInvalidAmount = errors.New("invalid money amount")
+
+// Money — денежная сумма в минимальных единицах (копейках) и валюта.
+// Арифметика целочисленная, поэтому суммы по позициям не накапливают ошибку округления.
+//
+// В БД хранится только количество копеек (bigint): магазин работает в рублях,
+// а у платежей и возвратов валюта лежит в отдельной колонке.
+// В JSON сумма остаётся числом в рублях с двумя знаками: 1234.50.
+type Money struct {
+	Kopecks  int64
+	Currency string
+}
+
+// New — сумма в копейках в рублях
+func New(kopecks int64) Money {
+	return Money{Kopecks: kopecks, Currency: RUB}
+}
+
+// FromFloat переводит сумму в рублях с округлением до копейки. Нужна только на границах:
+// данные сторонних API и старые значения.
+func FromFloat(rubles float64) Money {
+	return New(int64(math.Round(rubles * 100)))
+}
+
+// Parse разбирает десятичную сумму "1234.5", "1234,50" или "1234" без потери точности
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(strings.Replace(s, ",", ".", 1))
+	if s == "" {
+		return Money{}, ErrInvalidAmount
+	}
+
+	// допускается только один ведущий минус; рубли и копейки — только цифры
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if !isDigits(whole) || len(frac) > 2 || (frac != "" && !isDigits(frac)) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	rubles, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	kopecks, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	total := rubles*100 + kopecks
+	if negative {
+		total = -total
+	}
+	return New(total), nil
+}
+
+// isDigits — непустая строка из ASCII-цифр
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return RUB
+	}
+	return m.Currency
+}
+
+func (m Money) mustMatch(other Money) {
+	if m.currency() != other.currency() {
+		panic(fmt.Sprintf("money: currency mismatch %s and %s", m.currency(), other.currency()))
+	}
+}
+
+func (m Money) Add(other Money) Money {
+	m.mustMatch(other)
+	return Money{Kopecks: m.Kopecks + other.Kopecks, Currency: m.currency()}
+}
+
+func (m Money) Sub(other Money) Money {
+	m.mustMatch(other)
+	return Money{Kopecks: m.Kopecks - other.Kopecks, Currency: m.currency()}
+}
+
+// Mul — цена за единицу, умноженная на количество
+func (m Money) Mul(quantity int) Money {
+	return Money{Kopecks: m.Kopecks * int64(quantity), Currency: m.currency()}
+}
+
+// Share — доля num/den от суммы с округлением до копейки, например НДС 20/120
+func (m Money) Share(num, den float64) Money {
+	return Money{Kopecks: int64(math.Round(float64(m.Kopecks) * num / den)), Currency: m.currency()}
+}
+
+func (m Money) Min(other Money) Money {
+	if other.Less(m) {
+		return other
+	}
+	return m
+}
+
+func (m Money) Less(other Money) bool {
+	m.mustMatch(other)
+	return m.Kopecks < other.Kopecks
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.Kopecks == other.Kopecks && m.currency() == other.currency()
+}
+
+func (m Money) IsZero() bool     { return m.Kopecks == 0 }
+func (m Money) IsPositive() bool { return m.Kopecks > 0 }
+func (m Money) IsNegative() bool { return m.Kopecks < 0 }
+
+// Float64 — сумма в рублях для вывода в PDF и сторонние библиотеки; не для расчётов
+func (m Money) Float64() float64 {
+	return float64(m.Kopecks) / 100
+}
+
+// Decimal — сумма в рублях с двумя знаками после точки: "1234.50"
+func (m Money) Decimal() string {
+	sign := ""
+	k := m.Kopecks
+	if k < 0 {
+		sign = "-"
+		k = -k
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, k/100, k%100)
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency()
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON принимает число или строку с десятичной суммой
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := strings.Trim(string(data), `"`)
+	if raw == "null" || raw == "" {
+		*m = Money{}
+		return nil
+	}
+	if _, err := strconv.ParseFloat(raw, 64); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidAmount, data)
+	}
+
+	parsed, err := Parse(raw)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.Kopecks, nil
+}
+
+func (m *Money) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		*m = Money{}
+	case int64:
+		*m = New(v)
+	case float64:
+		*m = New(int64(math.Round(v)))
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	default:
+		return fmt.Errorf("money: cannot scan %T", value)
+	}
+	return nil
+}
+
+// scanString — numeric из агрегатов (SUM по bigint возвращает numeric)
+func (m *Money) scanString(s string) error {
+	kopecks, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil {
+			return fmt.Errorf("money: cannot scan %q", s)
+		}
+		kopecks = int64(math.Round(f))
+	}
+	*m = New(kopecks)
+	return nil
+}
+
+// GormDataType — колонка bigint с копейками
+func (Money) GormDataType() string {
+	return "bigint"
+}
@@ -0,0 +1,147 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "1234", want: 123400},
+		{in: "1234.5", want: 123450},
+		{in: "1234,50", want: 123450},
+		{in: " 0.05 ", want: 5},
+		{in: "1.", want: 100},
+		{in: "-1.25", want: -125},
+		{in: "-0.01", want: -1},
+
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "1.234", wantErr: true},
+		{in: "1.-5", wantErr: true},
+		{in: "1.+5", wantErr: true},
+		{in: "--5", wantErr: true},
+		{in: "+5", wantErr: true},
+		{in: "-+5", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "1 000", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("Parse(%q) = %v, %v; want ErrInvalidAmount", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(New(tt.want)) {
+			t.Errorf("Parse(%q) = %d kopecks, want %d", tt.in, got.Kopecks, tt.want)
+		}
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: `1234.5`, want: 123450},
+		{in: `"1234.50"`, want: 123450},
+		{in: `0`, want: 0},
+		{in: `-10`, want: -1000},
+		{in: `null`, want: 0},
+		{in: `""`, want: 0},
+
+		{in: `1.234`, wantErr: true},
+		{in: `1e3`, wantErr: true},
+		{in: `"1.-5"`, wantErr: true},
+		{in: `"--5"`, wantErr: true},
+		{in: `"abc"`, wantErr: true},
+		{in: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var m Money
+		err := m.UnmarshalJSON([]byte(tt.in))
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidAmount) {
+				t.Errorf("UnmarshalJSON(%s) = %v, %v; want ErrInvalidAmount", tt.in, m, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("UnmarshalJSON(%s) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if m.Kopecks != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %d kopecks, want %d", tt.in, m.Kopecks, tt.want)
+		}
+	}
+}
+
+func TestMarshalJSONRoundTrip(t *testing.T) {
+	for _, kopecks := range []int64{0, 5, 100, 123450, -1, -125} {
+		data, err := New(kopecks).MarshalJSON()
+		if err != nil {
+			t.Fatalf("MarshalJSON(%d): %v", kopecks, err)
+		}
+		var m Money
+		if err := m.UnmarshalJSON(data); err != nil {
+			t.Fatalf("UnmarshalJSON(%s): %v", data, err)
+		}
+		if m.Kopecks != kopecks {
+			t.Errorf("round trip %d -> %s -> %d", kopecks, data, m.Kopecks)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      any
+		want    int64
+		wantErr bool
+	}{
+		{name: "nil", in: nil, want: 0},
+		{name: "int64", in: int64(12345), want: 12345},
+		{name: "float64", in: float64(12344.6), want: 12345},
+		{name: "bytes", in: []byte("500"), want: 500},
+		{name: "string", in: "500", want: 500},
+		{name: "numeric", in: "500.0000", want: 500},
+		{name: "bad string", in: "abc", wantErr: true},
+		{name: "bool", in: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(999)
+			err := m.Scan(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Scan(%v) = %v, want error", tt.in, m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan(%v) unexpected error: %v", tt.in, err)
+			}
+			if !m.Equal(New(tt.want)) {
+				t.Errorf("Scan(%v) = %d kopecks, want %d", tt.in, m.Kopecks, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package types
+
+// BalanceReason — основание движения по подарочной карте или балансу покупателя
+type BalanceReason string
+
+const (
+	BalanceIssue      BalanceReason = "issue"      // выпуск подарочной карты или начисление на баланс
+	BalancePayment    BalanceReason = "payment"    // оплата заказа
+	BalanceRefund     BalanceReason = "refund"     // возврат по заказу
+	BalanceAdjustment BalanceReason = "adjustment" // ручная корректировка администратором
+)
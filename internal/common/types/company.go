@@ -0,0 +1,21 @@
+package types
+
+type CompanyRole string
+
+const (
+	CompanyBuyer      CompanyRole = "buyer"      // оформляет заказы
+	CompanyApprover   CompanyRole = "approver"   // согласует заказы сверх лимита и управляет участниками
+	CompanyAccountant CompanyRole = "accountant" // видит заказы и документы, заказы не оформляет
+)
+
+func (r CompanyRole) IsValid() bool {
+	switch r {
+	case CompanyBuyer, CompanyApprover, CompanyAccountant:
+		return true
+	}
+	return false
+}
+
+func (r CompanyRole) CanPlaceOrders() bool {
+	return r == CompanyBuyer || r == CompanyApprover
+}
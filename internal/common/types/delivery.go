@@ -0,0 +1,22 @@
+package types
+
+type DeliveryMethod string
+
+const (
+	DeliveryCourier     DeliveryMethod = "courier"      // курьер до двери
+	DeliveryPickupPoint DeliveryMethod = "pickup_point" // пункт выдачи
+	DeliveryStorePickup DeliveryMethod = "store_pickup" // самовывоз со склада магазина
+)
+
+func (m DeliveryMethod) IsValid() bool {
+	switch m {
+	case DeliveryCourier, DeliveryPickupPoint, DeliveryStorePickup:
+		return true
+	}
+	return false
+}
+
+// RequiresAddress — нужен ли адрес доставки для способа
+func (m DeliveryMethod) RequiresAddress() bool {
+	return m == DeliveryCourier || m == DeliveryPickupPoint
+}
@@ -0,0 +1,27 @@
+package types
+
+type DocumentType string
+
+const (
+	DocumentInvoice DocumentType = "invoice" // счёт на оплату
+	DocumentUPD     DocumentType = "upd"     // универсальный передаточный документ
+)
+
+func (t DocumentType) IsValid() bool {
+	switch t {
+	case DocumentInvoice, DocumentUPD:
+		return true
+	}
+	return false
+}
+
+// Title — название документа для печатной формы
+func (t DocumentType) Title() string {
+	switch t {
+	case DocumentInvoice:
+		return "Счёт на оплату"
+	case DocumentUPD:
+		return "Универсальный передаточный документ"
+	}
+	return string(t)
+}
@@ -0,0 +1,17 @@
+package types
+
+// LoyaltyKind — вид движения по бонусному счёту
+type LoyaltyKind string
+
+const (
+	LoyaltyEarn    LoyaltyKind = "earn"    // начисление за завершённый заказ
+	LoyaltySpend   LoyaltyKind = "spend"   // оплата части заказа баллами
+	LoyaltyExpire  LoyaltyKind = "expire"  // сгорание баллов по сроку
+	LoyaltyReverse LoyaltyKind = "reverse" // списание начисленных баллов при возврате денег
+	LoyaltyRestore LoyaltyKind = "restore" // возврат потраченных баллов при отмене или возврате заказа
+)
+
+// IsLot — движение, которое пополняет счёт и само сгорает по сроку
+func (k LoyaltyKind) IsLot() bool {
+	return k == LoyaltyEarn || k == LoyaltyRestore
+}
@@ -1,11 +1,46 @@
 package types
 
+import "errors"
+
 type OrderStatus string
 
 const (
-	InProgress OrderStatus = "in_progress"
-	Paid       OrderStatus = "paid"
-	Completed  OrderStatus = "completed"
-	Failed     OrderStatus = "failed"
-	Cancelled  OrderStatus = "cancelled"
+	AwaitingApproval OrderStatus = "awaiting_approval" // заказ компании ждёт согласования
+	InProgress       OrderStatus = "in_progress"
+	Paid             OrderStatus = "paid"
+	Shipped          OrderStatus = "shipped"
+	Delivered        OrderStatus = "delivered"
+	Completed        OrderStatus = "completed"
+	Failed           OrderStatus = "failed"
+	Cancelled        OrderStatus = "cancelled"
+	Refunded         OrderStatus = "refunded"
 )
+
+var ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+// Таблица допустимых переходов статусов заказа
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	AwaitingApproval: {InProgress, Cancelled},
+	InProgress:       {Paid, Shipped, Failed, Cancelled}, // в shipped без оплаты — при оплате при получении или до подтверждения списания блокировки
+	Failed:           {InProgress, Cancelled},
+	Paid:             {Shipped, Cancelled, Refunded},
+	Shipped:          {Delivered, Refunded},
+	Delivered:        {Completed, Refunded},
+	Completed:        {Refunded},
+	Cancelled:        {},
+	Refunded:         {},
+}
+
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderTransitions[s]
+	return ok
+}
+
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, allowed := range orderTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
@@ -5,12 +5,85 @@ type PaymentMethod string
 const (
 	PaymentMethodCard PaymentMethod = "bank_card"
 	PaymentMethodSBP  PaymentMethod = "sbp"
+
+	// Офлайн-оплата: деньги приходят мимо шлюза, платёж подтверждает администратор
+	PaymentMethodBankTransfer   PaymentMethod = "bank_transfer"    // по счёту, платёжным поручением
+	PaymentMethodCashOnDelivery PaymentMethod = "cash_on_delivery" // курьеру или в магазине при получении
+
+	// Оплата внутренним балансом: часть заказа, остаток оплачивается одним из способов выше
+	PaymentMethodGiftCard    PaymentMethod = "gift_card"
+	PaymentMethodStoreCredit PaymentMethod = "store_credit"
 )
 
+func (m PaymentMethod) IsValid() bool {
+	return m == PaymentMethodCard || m == PaymentMethodSBP || m.IsOffline()
+}
+
+// IsOffline — оплата проходит мимо платёжного шлюза
+func (m PaymentMethod) IsOffline() bool {
+	return m == PaymentMethodBankTransfer || m == PaymentMethodCashOnDelivery
+}
+
+// IsBalance — оплата подарочной картой или балансом покупателя: списывается сразу, без шлюза
+func (m PaymentMethod) IsBalance() bool {
+	return m == PaymentMethodGiftCard || m == PaymentMethodStoreCredit
+}
+
 type PaymentStatus string
 
 const (
-	PaymentStatusPending   PaymentStatus = "pending"
-	PaymentStatusSucceeded PaymentStatus = "succeeded"
-	PaymentStatusCanceled  PaymentStatus = "canceled"
+	PaymentStatusPending           PaymentStatus = "pending"
+	PaymentStatusWaitingForCapture PaymentStatus = "waiting_for_capture" // деньги заблокированы на карте, ждут списания
+	PaymentStatusSucceeded         PaymentStatus = "succeeded"
+	PaymentStatusCanceled          PaymentStatus = "canceled"
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded" // часть суммы возвращена
+	PaymentStatusRefunded          PaymentStatus = "refunded"           // возвращена вся сумма
 )
+
+// Статусы платежа меняются только вперёд: запоздавшее или повторное уведомление
+// не откатит succeeded обратно в pending
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentStatusPending:           {PaymentStatusWaitingForCapture, PaymentStatusSucceeded, PaymentStatusCanceled},
+	PaymentStatusWaitingForCapture: {PaymentStatusSucceeded, PaymentStatusCanceled},
+	PaymentStatusSucceeded:         {PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+	PaymentStatusPartiallyRefunded: {PaymentStatusRefunded},
+	PaymentStatusCanceled:          {},
+	PaymentStatusRefunded:          {},
+}
+
+func (s PaymentStatus) CanTransitionTo(next PaymentStatus) bool {
+	for _, allowed := range paymentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRefundable — по платежу ещё можно вернуть деньги
+func (s PaymentStatus) IsRefundable() bool {
+	return s == PaymentStatusSucceeded || s == PaymentStatusPartiallyRefunded
+}
+
+type RefundStatus string
+
+const (
+	RefundPending   RefundStatus = "pending"
+	RefundSucceeded RefundStatus = "succeeded"
+	RefundCanceled  RefundStatus = "canceled"
+)
+
+// PaymentEventStatus — результат обработки уведомления шлюза
+type PaymentEventStatus string
+
+const (
+	PaymentEventReceived  PaymentEventStatus = "received"  // сохранено, ещё не обработано или обработка упала
+	PaymentEventProcessed PaymentEventStatus = "processed" // применено к платежу или возврату
+	PaymentEventIgnored   PaymentEventStatus = "ignored"   // устаревший статус или неизвестный объект
+	PaymentEventRejected  PaymentEventStatus = "rejected"  // сумма не сходится с заказом, нужна ручная проверка
+)
+
+// IsFinal — уведомление больше не нужно обрабатывать
+func (s PaymentEventStatus) IsFinal() bool {
+	return s == PaymentEventProcessed || s == PaymentEventIgnored || s == PaymentEventRejected
+}
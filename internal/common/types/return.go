@@ -0,0 +1,46 @@
+package types
+
+import "errors"
+
+type ReturnStatus string
+
+const (
+	ReturnRequested ReturnStatus = "requested" // заявка открыта покупателем
+	ReturnApproved  ReturnStatus = "approved"  // одобрена, деньги возвращены
+	ReturnRejected  ReturnStatus = "rejected"
+	ReturnReceived  ReturnStatus = "received" // товар получен и оприходован/списан
+)
+
+var ErrInvalidReturnTransition = errors.New("invalid return status transition")
+
+var returnTransitions = map[ReturnStatus][]ReturnStatus{
+	ReturnRequested: {ReturnApproved, ReturnRejected},
+	ReturnApproved:  {ReturnReceived},
+	ReturnRejected:  {},
+	ReturnReceived:  {},
+}
+
+func (s ReturnStatus) IsValid() bool {
+	_, ok := returnTransitions[s]
+	return ok
+}
+
+func (s ReturnStatus) CanTransitionTo(next ReturnStatus) bool {
+	for _, allowed := range returnTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+type ReturnResolution string
+
+const (
+	ReturnRestock  ReturnResolution = "restock"   // вернуть на склад
+	ReturnWriteOff ReturnResolution = "write_off" // списать брак
+)
+
+func (r ReturnResolution) IsValid() bool {
+	return r == ReturnRestock || r == ReturnWriteOff
+}
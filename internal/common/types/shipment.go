@@ -0,0 +1,42 @@
+package types
+
+import "errors"
+
+type ShipmentStatus string
+
+const (
+	ShipmentPending   ShipmentStatus = "pending"    // собирается на складе
+	ShipmentShipped   ShipmentStatus = "shipped"    // передана перевозчику
+	ShipmentInTransit ShipmentStatus = "in_transit" // в пути
+	ShipmentDelivered ShipmentStatus = "delivered"  // вручена
+	ShipmentCancelled ShipmentStatus = "cancelled"
+)
+
+var ErrInvalidShipmentTransition = errors.New("invalid shipment status transition")
+
+var shipmentTransitions = map[ShipmentStatus][]ShipmentStatus{
+	ShipmentPending:   {ShipmentShipped, ShipmentCancelled},
+	ShipmentShipped:   {ShipmentInTransit, ShipmentDelivered, ShipmentCancelled},
+	ShipmentInTransit: {ShipmentDelivered, ShipmentCancelled},
+	ShipmentDelivered: {},
+	ShipmentCancelled: {},
+}
+
+func (s ShipmentStatus) IsValid() bool {
+	_, ok := shipmentTransitions[s]
+	return ok
+}
+
+func (s ShipmentStatus) CanTransitionTo(next ShipmentStatus) bool {
+	for _, allowed := range shipmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDispatched — посылка уже покинула склад
+func (s ShipmentStatus) IsDispatched() bool {
+	return s == ShipmentShipped || s == ShipmentInTransit || s == ShipmentDelivered
+}
@@ -0,0 +1,10 @@
+package types
+
+type StockMovementReason string
+
+const (
+	StockReserve  StockMovementReason = "reserve"   // списание под заказ
+	StockRelease  StockMovementReason = "release"   // возврат на склад при отмене заказа
+	StockRestock  StockMovementReason = "restock"   // возврат товара покупателем
+	StockWriteOff StockMovementReason = "write_off" // списание брака
+)
@@ -2,6 +2,7 @@ package utils
 
 import "strconv"
 import "strings"
+import "Market_backend/internal/common/money"
 
 func ParseFloat(s string) float64 {
 	s = strings.TrimSpace(s)
@@ -21,6 +22,14 @@ func ParseInt(s string) int {
 	return i
 }
 
+func ParseMoney(s string) money.Money {
+	m, err := money.Parse(s)
+	if err != nil {
+		return money.Money{}
+	}
+	return m
+}
+
 func ParseBool(s string) bool {
 	s = strings.ToLower(strings.TrimSpace(s))
 	return s == "true" || s == "1" || s == "yes"
@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/validate"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CompanyDTO struct {
+	Name        string `json:"name" validate:"required"`
+	INN         string `json:"inn" validate:"required,numeric"`
+	KPP         string `json:"kpp" validate:"omitempty,numeric,len=9"`
+	Address     string `json:"address" validate:"required"`
+	Bank        string `json:"bank"`
+	BIK         string `json:"bik" validate:"omitempty,numeric,len=9"`
+	Account     string `json:"account" validate:"omitempty,numeric,len=20"`
+	CorrAccount string `json:"corr_account" validate:"omitempty,numeric,len=20"`
+}
+
+func (c *CompanyDTO) Validate() error {
+	if err := validate.Validate.Struct(c); err != nil {
+		return err
+	}
+
+	// 10 цифр — организация (нужен КПП), 12 — ИП
+	switch len(c.INN) {
+	case 10:
+		if c.KPP == "" {
+			return errors.New("kpp is required for organisations")
+		}
+	case 12:
+	default:
+		return errors.New("inn must contain 10 or 12 digits")
+	}
+	return nil
+}
+
+type AddMemberDTO struct {
+	Email         string            `json:"email" validate:"required,email"`
+	Role          types.CompanyRole `json:"role" validate:"required"`
+	ApprovalLimit *money.Money      `json:"approval_limit"`
+}
+
+type UpdateMemberDTO struct {
+	Role          types.CompanyRole `json:"role"`
+	ApprovalLimit *money.Money      `json:"approval_limit"`
+	NoLimit       bool              `json:"no_limit"` // снять лимит
+}
+
+type MemberDTO struct {
+	ID            uuid.UUID    `json:"id"`
+	UserID        uuid.UUID    `json:"user_id"`
+	Name          string       `json:"name"`
+	Surname       string       `json:"surname"`
+	Email         string       `json:"email"`
+	Role          string       `json:"role"`
+	ApprovalLimit *money.Money `json:"approval_limit"`
+}
+
+type CompanyResponse struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	INN         string      `json:"inn"`
+	KPP         string      `json:"kpp"`
+	Address     string      `json:"address"`
+	Bank        string      `json:"bank"`
+	BIK         string      `json:"bik"`
+	Account     string      `json:"account"`
+	CorrAccount string      `json:"corr_account"`
+	Role        string      `json:"role,omitempty"` // роль текущего пользователя
+	Members     []MemberDTO `json:"members,omitempty"`
+}
+
+type CompanyOrderDTO struct {
+	ID          uuid.UUID   `json:"id"`
+	OrderNumber int32       `json:"number"`
+	Status      string      `json:"status"`
+	Total       money.Money `json:"total"`
+	PlacedBy    string      `json:"placed_by"`
+	ApprovedAt  *time.Time  `json:"approved_at"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
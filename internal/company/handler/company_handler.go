@@ -0,0 +1,300 @@
+package handler
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/company/dto"
+	"Market_backend/internal/company/service"
+	"Market_backend/models"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CompanyHandler struct {
+	service *service.CompanyService
+}
+
+func NewCompanyHandler(service *service.CompanyService) *CompanyHandler {
+	return &CompanyHandler{service: service}
+}
+
+func (h *CompanyHandler) CreateCompany(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var body dto.CompanyDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	company, err := h.service.CreateCompany(userId, body)
+	if err != nil {
+		return companyError(c, err)
+	}
+
+	response := toCompanyResponse(company)
+	response.Role = string(types.CompanyApprover)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"company": response})
+}
+
+func (h *CompanyHandler) GetMyCompanies(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	companies, roles, err := h.service.GetMyCompanies(userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	result := make([]dto.CompanyResponse, 0, len(companies))
+	for i := range companies {
+		response := toCompanyResponse(&companies[i])
+		response.Role = string(roles[companies[i].ID])
+		result = append(result, response)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"companies": result})
+}
+
+func (h *CompanyHandler) GetCompany(c *fiber.Ctx) error {
+	userId, companyId, err := parseIds(c)
+	if err != nil {
+		return err
+	}
+
+	company, member, err := h.service.GetCompany(userId, companyId)
+	if err != nil {
+		return companyError(c, err)
+	}
+
+	response := toCompanyResponse(company)
+	response.Role = string(member.Role)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"company": response})
+}
+
+func (h *CompanyHandler) UpdateCompany(c *fiber.Ctx) error {
+	userId, companyId, err := parseIds(c)
+	if err != nil {
+		return err
+	}
+
+	var body dto.CompanyDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	company, err := h.service.UpdateCompany(userId, companyId, body)
+	if err != nil {
+		return companyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"company": toCompanyResponse(company)})
+}
+
+// ===================== Members =====================
+
+func (h *CompanyHandler) AddMember(c *fiber.Ctx) error {
+	userId, companyId, err := parseIds(c)
+	if err != nil {
+		return err
+	}
+
+	var body dto.AddMemberDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	member, err := h.service.AddMember(userId, companyId, body)
+	if err != nil {
+		return companyError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"member": toMemberDTO(member)})
+}
+
+func (h *CompanyHandler) UpdateMember(c *fiber.Ctx) error {
+	userId, companyId, err := parseIds(c)
+	if err != nil {
+		return err
+	}
+	memberId, err := uuid.Parse(c.Params("memberId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid member id"})
+	}
+
+	var body dto.UpdateMemberDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	member, err := h.service.UpdateMember(userId, companyId, memberId, body)
+	if err != nil {
+		return companyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"member": toMemberDTO(member)})
+}
+
+func (h *CompanyHandler) RemoveMember(c *fiber.Ctx) error {
+	userId, companyId, err := parseIds(c)
+	if err != nil {
+		return err
+	}
+	memberId, err := uuid.Parse(c.Params("memberId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid member id"})
+	}
+
+	if err := h.service.RemoveMember(userId, companyId, memberId); err != nil {
+		return companyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "member removed"})
+}
+
+// ===================== Orders =====================
+
+func (h *CompanyHandler) GetCompanyOrders(c *fiber.Ctx) error {
+	userId, companyId, err := parseIds(c)
+	if err != nil {
+		return err
+	}
+
+	status := types.OrderStatus(c.Query("status"))
+	if status != "" && !status.IsValid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid status"})
+	}
+
+	orders, err := h.service.GetCompanyOrders(userId, companyId, status)
+	if err != nil {
+		return companyError(c, err)
+	}
+
+	result := make([]dto.CompanyOrderDTO, 0, len(orders))
+	for _, order := range orders {
+		result = append(result, dto.CompanyOrderDTO{
+			ID:          order.ID,
+			OrderNumber: order.OrderNumber,
+			Status:      string(order.Status),
+			Total:       order.Total,
+			PlacedBy:    order.User.Name + " " + order.User.Surname,
+			ApprovedAt:  order.ApprovedAt,
+			CreatedAt:   order.CreatedAt,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"orders": result})
+}
+
+func (h *CompanyHandler) ApproveOrder(c *fiber.Ctx) error {
+	userId, companyId, err := parseIds(c)
+	if err != nil {
+		return err
+	}
+	orderId, err := uuid.Parse(c.Params("orderId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	if err := h.service.ApproveOrder(userId, companyId, orderId); err != nil {
+		return companyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "order approved"})
+}
+
+func (h *CompanyHandler) RejectOrder(c *fiber.Ctx) error {
+	userId, companyId, err := parseIds(c)
+	if err != nil {
+		return err
+	}
+	orderId, err := uuid.Parse(c.Params("orderId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	if err := h.service.RejectOrder(userId, companyId, orderId, body.Comment); err != nil {
+		return companyError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "order rejected"})
+}
+
+// parseIds достаёт пользователя из токена и id компании из пути; при ошибке ответ уже отправлен
+func parseIds(c *fiber.Ctx) (uuid.UUID, uuid.UUID, error) {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	companyId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid company id"})
+	}
+
+	return userId, companyId, nil
+}
+
+func toCompanyResponse(company *models.Company) dto.CompanyResponse {
+	response := dto.CompanyResponse{
+		ID:          company.ID,
+		Name:        company.Name,
+		INN:         company.INN,
+		KPP:         company.KPP,
+		Address:     company.Address,
+		Bank:        company.Bank,
+		BIK:         company.BIK,
+		Account:     company.Account,
+		CorrAccount: company.CorrAccount,
+	}
+	for i := range company.Members {
+		response.Members = append(response.Members, toMemberDTO(&company.Members[i]))
+	}
+	return response
+}
+
+func toMemberDTO(member *models.CompanyMember) dto.MemberDTO {
+	return dto.MemberDTO{
+		ID:            member.ID,
+		UserID:        member.UserID,
+		Name:          member.User.Name,
+		Surname:       member.User.Surname,
+		Email:         member.User.Email,
+		Role:          string(member.Role),
+		ApprovalLimit: member.ApprovalLimit,
+	}
+}
+
+func companyError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	case errors.Is(err, service.ErrNotCompanyMember),
+		errors.Is(err, service.ErrNotApprover):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrLastApprover),
+		errors.Is(err, service.ErrAlreadyMember),
+		errors.Is(err, service.ErrOrderNotAwaitingApproval),
+		errors.Is(err, service.ErrApprovalLimitExceeded),
+		errors.Is(err, service.ErrSelfApproval),
+		errors.Is(err, types.ErrInvalidOrderTransition):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CompanyRepository struct {
+	db *gorm.DB
+}
+
+func NewCompanyRepository() *CompanyRepository {
+	return &CompanyRepository{db: common.DB}
+}
+
+func (r *CompanyRepository) DB() *gorm.DB {
+	return r.db
+}
+
+func (r *CompanyRepository) CreateTx(tx *gorm.DB, company *models.Company) error {
+	return tx.Create(company).Error
+}
+
+func (r *CompanyRepository) Save(company *models.Company) error {
+	return r.db.Omit(clause.Associations).Save(company).Error
+}
+
+func (r *CompanyRepository) GetById(id uuid.UUID) (*models.Company, error) {
+	var company models.Company
+	if err := r.db.
+		Preload("Members", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Members.User").
+		First(&company, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// GetByUser — компании, в которых состоит пользователь, вместе с его участием
+func (r *CompanyRepository) GetByUser(userId uuid.UUID) ([]models.CompanyMember, error) {
+	var members []models.CompanyMember
+	err := r.db.
+		Where("user_id = ?", userId).
+		Order("created_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+func (r *CompanyRepository) GetCompaniesByIds(ids []uuid.UUID) ([]models.Company, error) {
+	var companies []models.Company
+	err := r.db.Where("id IN ?", ids).Find(&companies).Error
+	return companies, err
+}
+
+func (r *CompanyRepository) GetMember(companyId, userId uuid.UUID) (*models.CompanyMember, error) {
+	return r.GetMemberTx(r.db, companyId, userId)
+}
+
+func (r *CompanyRepository) GetMemberTx(tx *gorm.DB, companyId, userId uuid.UUID) (*models.CompanyMember, error) {
+	var member models.CompanyMember
+	if err := tx.First(&member, "company_id = ? AND user_id = ?", companyId, userId).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *CompanyRepository) GetMemberByIdTx(tx *gorm.DB, companyId, memberId uuid.UUID) (*models.CompanyMember, error) {
+	var member models.CompanyMember
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&member, "id = ? AND company_id = ?", memberId, companyId).Error; err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *CompanyRepository) CreateMember(member *models.CompanyMember) error {
+	return r.db.Create(member).Error
+}
+
+func (r *CompanyRepository) CreateMemberTx(tx *gorm.DB, member *models.CompanyMember) error {
+	return tx.Create(member).Error
+}
+
+func (r *CompanyRepository) SaveMemberTx(tx *gorm.DB, member *models.CompanyMember) error {
+	return tx.Omit(clause.Associations).Save(member).Error
+}
+
+func (r *CompanyRepository) DeleteMemberTx(tx *gorm.DB, member *models.CompanyMember) error {
+	return tx.Delete(member).Error
+}
+
+// CountApproversTx считает согласующих; строки блокируются, чтобы не удалить последнего параллельно
+func (r *CompanyRepository) CountApproversTx(tx *gorm.DB, companyId uuid.UUID) (int, error) {
+	var approvers []models.CompanyMember
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("company_id = ? AND role = ?", companyId, types.CompanyApprover).
+		Find(&approvers).Error
+	return len(approvers), err
+}
+
+// GetOrders — история заказов компании, status пустой — все статусы
+func (r *CompanyRepository) GetOrders(companyId uuid.UUID, status types.OrderStatus) ([]models.Order, error) {
+	var orders []models.Order
+	query := r.db.
+		Preload("User").
+		Where("company_id = ?", companyId)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").Find(&orders).Error
+	return orders, err
+}
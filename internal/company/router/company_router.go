@@ -0,0 +1,27 @@
+package router
+
+import (
+	"Market_backend/internal/company/handler"
+	"Market_backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterCompanyRouter(app *fiber.App, h *handler.CompanyHandler) {
+	companies := app.Group("/companies")
+
+	companies.Post("/", middleware.AuthRequired(), h.CreateCompany)
+	companies.Get("/my", middleware.AuthRequired(), h.GetMyCompanies)
+	companies.Get("/:id", middleware.AuthRequired(), h.GetCompany)
+	companies.Patch("/:id", middleware.AuthRequired(), h.UpdateCompany)
+
+	// Участники (управляет согласующий)
+	companies.Post("/:id/members", middleware.AuthRequired(), h.AddMember)
+	companies.Patch("/:id/members/:memberId", middleware.AuthRequired(), h.UpdateMember)
+	companies.Delete("/:id/members/:memberId", middleware.AuthRequired(), h.RemoveMember)
+
+	// Общая история заказов и согласование
+	companies.Get("/:id/orders", middleware.AuthRequired(), h.GetCompanyOrders)
+	companies.Post("/:id/orders/:orderId/approve", middleware.AuthRequired(), h.ApproveOrder)
+	companies.Post("/:id/orders/:orderId/reject", middleware.AuthRequired(), h.RejectOrder)
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/company/dto"
+	"Market_backend/internal/company/repository"
+	OrderRepository "Market_backend/internal/order/repository"
+	OrderService "Market_backend/internal/order/service"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotCompanyMember         = errors.New("вы не состоите в этой компании")
+	ErrNotApprover              = errors.New("действие доступно только согласующему")
+	ErrLastApprover             = errors.New("в компании должен остаться хотя бы один согласующий")
+	ErrAlreadyMember            = errors.New("пользователь уже состоит в компании")
+	ErrOrderNotAwaitingApproval = errors.New("заказ не ожидает согласования")
+	ErrApprovalLimitExceeded    = errors.New("сумма заказа превышает ваш лимит согласования")
+	ErrSelfApproval             = errors.New("нельзя согласовать собственный заказ")
+)
+
+type CompanyService struct {
+	repo         *repository.CompanyRepository
+	orderRepo    *OrderRepository.OrderRepository
+	orderService *OrderService.OrderService
+}
+
+func NewCompanyService(
+	repo *repository.CompanyRepository,
+	orderRepo *OrderRepository.OrderRepository,
+	orderS *OrderService.OrderService,
+) *CompanyService {
+	return &CompanyService{repo: repo, orderRepo: orderRepo, orderService: orderS}
+}
+
+// CreateCompany регистрирует компанию; создатель становится согласующим без лимита
+func (s *CompanyService) CreateCompany(userId uuid.UUID, companyDto dto.CompanyDTO) (*models.Company, error) {
+	if err := companyDto.Validate(); err != nil {
+		return nil, err
+	}
+
+	company := &models.Company{ID: uuid.New()}
+	applyCompany(company, companyDto)
+
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.CreateTx(tx, company); err != nil {
+			return err
+		}
+		return s.repo.CreateMemberTx(tx, &models.CompanyMember{
+			ID:        uuid.New(),
+			CompanyID: company.ID,
+			UserID:    userId,
+			Role:      types.CompanyApprover,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return company, nil
+}
+
+// GetMyCompanies возвращает компании пользователя и его роль в каждой
+func (s *CompanyService) GetMyCompanies(userId uuid.UUID) ([]models.Company, map[uuid.UUID]types.CompanyRole, error) {
+	members, err := s.repo.GetByUser(userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(members))
+	roles := make(map[uuid.UUID]types.CompanyRole, len(members))
+	for _, m := range members {
+		ids = append(ids, m.CompanyID)
+		roles[m.CompanyID] = m.Role
+	}
+	if len(ids) == 0 {
+		return []models.Company{}, roles, nil
+	}
+
+	companies, err := s.repo.GetCompaniesByIds(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	return companies, roles, nil
+}
+
+func (s *CompanyService) GetCompany(userId, companyId uuid.UUID) (*models.Company, *models.CompanyMember, error) {
+	member, err := s.member(companyId, userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	company, err := s.repo.GetById(companyId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return company, member, nil
+}
+
+func (s *CompanyService) UpdateCompany(userId, companyId uuid.UUID, companyDto dto.CompanyDTO) (*models.Company, error) {
+	if err := companyDto.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.approver(companyId, userId); err != nil {
+		return nil, err
+	}
+
+	company, err := s.repo.GetById(companyId)
+	if err != nil {
+		return nil, err
+	}
+
+	applyCompany(company, companyDto)
+	if err := s.repo.Save(company); err != nil {
+		return nil, err
+	}
+	return company, nil
+}
+
+// ===================== Members =====================
+
+func (s *CompanyService) AddMember(userId, companyId uuid.UUID, memberDto dto.AddMemberDTO) (*models.CompanyMember, error) {
+	if !memberDto.Role.IsValid() {
+		return nil, fmt.Errorf("invalid role: %s", memberDto.Role)
+	}
+	if memberDto.ApprovalLimit != nil && memberDto.ApprovalLimit.IsNegative() {
+		return nil, errors.New("approval_limit must not be negative")
+	}
+	if _, err := s.approver(companyId, userId); err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := s.repo.DB().First(&user, "email = ?", memberDto.Email).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	if _, err := s.repo.GetMember(companyId, user.ID); err == nil {
+		return nil, ErrAlreadyMember
+	}
+
+	member := &models.CompanyMember{
+		ID:            uuid.New(),
+		CompanyID:     companyId,
+		UserID:        user.ID,
+		User:          user,
+		Role:          memberDto.Role,
+		ApprovalLimit: memberDto.ApprovalLimit,
+	}
+	if err := s.repo.CreateMember(member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (s *CompanyService) UpdateMember(userId, companyId, memberId uuid.UUID, update dto.UpdateMemberDTO) (*models.CompanyMember, error) {
+	if update.Role != "" && !update.Role.IsValid() {
+		return nil, fmt.Errorf("invalid role: %s", update.Role)
+	}
+	if update.ApprovalLimit != nil && update.ApprovalLimit.IsNegative() {
+		return nil, errors.New("approval_limit must not be negative")
+	}
+	if _, err := s.approver(companyId, userId); err != nil {
+		return nil, err
+	}
+
+	var member *models.CompanyMember
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		member, err = s.repo.GetMemberByIdTx(tx, companyId, memberId)
+		if err != nil {
+			return err
+		}
+
+		if update.Role != "" && update.Role != member.Role {
+			if member.Role == types.CompanyApprover {
+				if err := s.ensureNotLastApproverTx(tx, companyId); err != nil {
+					return err
+				}
+			}
+			member.Role = update.Role
+		}
+
+		switch {
+		case update.NoLimit:
+			member.ApprovalLimit = nil
+		case update.ApprovalLimit != nil:
+			member.ApprovalLimit = update.ApprovalLimit
+		}
+
+		return s.repo.SaveMemberTx(tx, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return member, nil
+}
+
+func (s *CompanyService) RemoveMember(userId, companyId, memberId uuid.UUID) error {
+	if _, err := s.approver(companyId, userId); err != nil {
+		return err
+	}
+
+	return s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		member, err := s.repo.GetMemberByIdTx(tx, companyId, memberId)
+		if err != nil {
+			return err
+		}
+
+		if member.Role == types.CompanyApprover {
+			if err := s.ensureNotLastApproverTx(tx, companyId); err != nil {
+				return err
+			}
+		}
+
+		return s.repo.DeleteMemberTx(tx, member)
+	})
+}
+
+func (s *CompanyService) ensureNotLastApproverTx(tx *gorm.DB, companyId uuid.UUID) error {
+	count, err := s.repo.CountApproversTx(tx, companyId)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastApprover
+	}
+	return nil
+}
+
+// ===================== Orders =====================
+
+// GetCompanyOrders — общая история заказов компании, доступна всем участникам
+func (s *CompanyService) GetCompanyOrders(userId, companyId uuid.UUID, status types.OrderStatus) ([]models.Order, error) {
+	if _, err := s.member(companyId, userId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetOrders(companyId, status)
+}
+
+// ApproveOrder согласует заказ, превысивший лимит участника; после этого заказ можно оплачивать
+func (s *CompanyService) ApproveOrder(userId, companyId, orderId uuid.UUID) error {
+	approver, err := s.approver(companyId, userId)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		order, err := s.companyOrderTx(tx, companyId, orderId)
+		if err != nil {
+			return err
+		}
+		if order.UserID == userId {
+			return ErrSelfApproval
+		}
+		if approver.ApprovalLimit != nil && approver.ApprovalLimit.Less(order.Total) {
+			return ErrApprovalLimitExceeded
+		}
+
+		if err := s.orderRepo.ChangeStatusTx(tx, orderId, types.InProgress, &userId, "заказ согласован"); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&models.Order{}).Where("id = ?", orderId).Updates(map[string]any{
+			"approved_by_id":   userId,
+			"approved_at":      now,
+			"payment_deadline": OrderService.PaymentDeadline(now),
+		}).Error
+	})
+}
+
+// RejectOrder отклоняет заказ: он отменяется, резерв остатков снимается
+func (s *CompanyService) RejectOrder(userId, companyId, orderId uuid.UUID, comment string) error {
+	if _, err := s.approver(companyId, userId); err != nil {
+		return err
+	}
+
+	if _, err := s.companyOrderTx(s.repo.DB(), companyId, orderId); err != nil {
+		return err
+	}
+
+	if comment == "" {
+		comment = "заказ не согласован"
+	}
+	_, err := s.orderService.CancelOrder(orderId, &userId, comment)
+	return err
+}
+
+func (s *CompanyService) companyOrderTx(tx *gorm.DB, companyId, orderId uuid.UUID) (*models.Order, error) {
+	var order models.Order
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&order, "id = ? AND company_id = ?", orderId, companyId).Error; err != nil {
+		return nil, err
+	}
+	if order.Status != types.AwaitingApproval {
+		return nil, ErrOrderNotAwaitingApproval
+	}
+	return &order, nil
+}
+
+func (s *CompanyService) member(companyId, userId uuid.UUID) (*models.CompanyMember, error) {
+	member, err := s.repo.GetMember(companyId, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotCompanyMember
+	}
+	return member, err
+}
+
+func (s *CompanyService) approver(companyId, userId uuid.UUID) (*models.CompanyMember, error) {
+	member, err := s.member(companyId, userId)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != types.CompanyApprover {
+		return nil, ErrNotApprover
+	}
+	return member, nil
+}
+
+func applyCompany(company *models.Company, companyDto dto.CompanyDTO) {
+	company.Name = companyDto.Name
+	company.INN = companyDto.INN
+	company.KPP = companyDto.KPP
+	company.Address = companyDto.Address
+	company.Bank = companyDto.Bank
+	company.BIK = companyDto.BIK
+	company.Account = companyDto.Account
+	company.CorrAccount = companyDto.CorrAccount
+}
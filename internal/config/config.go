@@ -21,6 +21,7 @@ var (
 	SMTPPort     string
 	SMTPEmail    string
 	SMTPPassword string
+	SupportEmail string // куда приходят вопросы покупателей по заказам
 
 	S3Endpoint  string
 	S3Host      string
@@ -31,6 +32,57 @@ var (
 
 	S3Name     string
 	S3Password string
+
+	ShippingCourierBase     string
+	ShippingPickupPointBase string
+	ShippingPerKg           string
+
+	// Реквизиты продавца для счетов и УПД
+	SellerName        string
+	SellerINN         string
+	SellerKPP         string
+	SellerAddress     string
+	SellerBank        string
+	SellerBIK         string
+	SellerAccount     string
+	SellerCorrAccount string
+	SellerDirector    string
+	SellerAccountant  string
+	VATRate           string
+
+	PDFFontPath string
+
+	IdempotencyKeyTTLHours string
+
+	// Платёжный шлюз: yookassa (по умолчанию) или fake для локальной разработки
+	PaymentProvider     string
+	YKassaShopID        string
+	YKassaSecretKey     string
+	YKassaWebhookSecret string
+	YKassaTestMode      string
+
+	// Сверка зависших pending-платежей со шлюзом
+	PaymentReconcileIntervalMinutes string
+	PaymentReconcileAfterMinutes    string
+
+	PaymentDeadlineMinutes   string // сколько ждать оплату заказа до автоотмены
+	BankTransferDeadlineDays string // срок оплаты по счёту, дней
+
+	// Заказы от этой суммы оплачиваются в две стадии: блокировка при оформлении, списание при отгрузке.
+	// Пусто или 0 — все платежи одностадийные.
+	HoldPaymentMinTotal string
+
+	// Чеки 54-ФЗ: передаются шлюзу с платежами и возвратами, false — не передавать
+	FiscalReceipts string
+	TaxSystemCode  string // код системы налогообложения ЮKassa (1–6), пусто — не передавать
+
+	// Бонусные баллы для розничных покупателей, 1 балл = 1 ₽ скидки. Пусто — значения по умолчанию.
+	LoyaltyEarnPercent     string // процент суммы завершённого заказа, начисляемый баллами; 0 — не начислять
+	LoyaltyMaxSharePercent string // какую долю стоимости товаров можно оплатить баллами, %
+	LoyaltyPointsTTLDays   string // через сколько дней начисленные баллы сгорают
+
+	AppBaseURL  string // внешний адрес API, нужен fake-провайдеру для страницы оплаты и вебхуков
+	FrontendURL string
 )
 
 func Init() {
@@ -56,6 +108,7 @@ func Init() {
 	SMTPPort = os.Getenv("SMTP_PORT")
 	SMTPEmail = os.Getenv("SMTP_EMAIL")
 	SMTPPassword = os.Getenv("SMTP_PASSWORD")
+	SupportEmail = os.Getenv("SUPPORT_EMAIL")
 
 	S3Endpoint = os.Getenv("S3_ENDPOINT")
 	S3Host = os.Getenv("S3_HOST")
@@ -67,5 +120,49 @@ func Init() {
 	S3Name = os.Getenv("MINIO_ROOT_USER")
 	S3Password = os.Getenv("MINIO_ROOT_PASSWORD")
 
+	ShippingCourierBase = os.Getenv("SHIPPING_COURIER_BASE")
+	ShippingPickupPointBase = os.Getenv("SHIPPING_PICKUP_POINT_BASE")
+	ShippingPerKg = os.Getenv("SHIPPING_PER_KG")
+
+	SellerName = os.Getenv("SELLER_NAME")
+	SellerINN = os.Getenv("SELLER_INN")
+	SellerKPP = os.Getenv("SELLER_KPP")
+	SellerAddress = os.Getenv("SELLER_ADDRESS")
+	SellerBank = os.Getenv("SELLER_BANK")
+	SellerBIK = os.Getenv("SELLER_BIK")
+	SellerAccount = os.Getenv("SELLER_ACCOUNT")
+	SellerCorrAccount = os.Getenv("SELLER_CORR_ACCOUNT")
+	SellerDirector = os.Getenv("SELLER_DIRECTOR")
+	SellerAccountant = os.Getenv("SELLER_ACCOUNTANT")
+	VATRate = os.Getenv("VAT_RATE")
+
+	PDFFontPath = os.Getenv("PDF_FONT_PATH")
+
+	IdempotencyKeyTTLHours = os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS")
+
+	PaymentProvider = os.Getenv("PAYMENT_PROVIDER")
+	YKassaShopID = os.Getenv("YKASSA_SHOP_ID")
+	YKassaSecretKey = os.Getenv("YKASSA_SECRET_KEY")
+	YKassaWebhookSecret = os.Getenv("YKASSA_WEBHOOK_SECRET")
+	YKassaTestMode = os.Getenv("YKASSA_TEST_MODE")
+
+	PaymentReconcileIntervalMinutes = os.Getenv("PAYMENT_RECONCILE_INTERVAL_MINUTES")
+	PaymentReconcileAfterMinutes = os.Getenv("PAYMENT_RECONCILE_AFTER_MINUTES")
+
+	PaymentDeadlineMinutes = os.Getenv("PAYMENT_DEADLINE_MINUTES")
+	BankTransferDeadlineDays = os.Getenv("BANK_TRANSFER_DEADLINE_DAYS")
+
+	HoldPaymentMinTotal = os.Getenv("HOLD_PAYMENT_MIN_TOTAL")
+
+	FiscalReceipts = os.Getenv("FISCAL_RECEIPTS")
+	TaxSystemCode = os.Getenv("TAX_SYSTEM_CODE")
+
+	LoyaltyEarnPercent = os.Getenv("LOYALTY_EARN_PERCENT")
+	LoyaltyMaxSharePercent = os.Getenv("LOYALTY_MAX_SHARE_PERCENT")
+	LoyaltyPointsTTLDays = os.Getenv("LOYALTY_POINTS_TTL_DAYS")
+
+	AppBaseURL = os.Getenv("APP_BASE_URL")
+	FrontendURL = os.Getenv("FRONTEND_URL")
+
 	AppPort = os.Getenv("APP_PORT")
 }
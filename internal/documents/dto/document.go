@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/validate"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BuyerDTO — реквизиты покупателя-юрлица или ИП для счёта и УПД
+type BuyerDTO struct {
+	Name    string `json:"name" validate:"required"`
+	INN     string `json:"inn" validate:"required,numeric"`
+	KPP     string `json:"kpp" validate:"omitempty,numeric,len=9"`
+	Address string `json:"address" validate:"required"`
+}
+
+func (b *BuyerDTO) Validate() error {
+	if err := validate.Validate.Struct(b); err != nil {
+		return err
+	}
+
+	// 10 цифр — организация (нужен КПП), 12 — ИП
+	switch len(b.INN) {
+	case 10:
+		if b.KPP == "" {
+			return errors.New("kpp is required for organisations")
+		}
+	case 12:
+	default:
+		return errors.New("inn must contain 10 or 12 digits")
+	}
+	return nil
+}
+
+type DocumentDTO struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	Number    int         `json:"number"`
+	Year      int         `json:"year"`
+	BuyerName string      `json:"buyer_name"`
+	BuyerINN  string      `json:"buyer_inn"`
+	VATRate   float64     `json:"vat_rate"`
+	VATAmount money.Money `json:"vat_amount"`
+	Total     money.Money `json:"total"`
+	CreatedAt time.Time   `json:"created_at"`
+}
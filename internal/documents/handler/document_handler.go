@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/documents/dto"
+	"Market_backend/internal/documents/service"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DocumentHandler struct {
+	service *service.DocumentService
+}
+
+func NewDocumentHandler(service *service.DocumentService) *DocumentHandler {
+	return &DocumentHandler{service: service}
+}
+
+// IssueInvoice — покупатель запрашивает счёт на оплату со своими реквизитами.
+// Для заказа компании тело можно не передавать.
+func (h *DocumentHandler) IssueInvoice(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	var buyer *dto.BuyerDTO
+	if len(c.Body()) > 0 {
+		buyer = &dto.BuyerDTO{}
+		if err := c.BodyParser(buyer); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	doc, err := h.service.IssueInvoice(userId, orderId, buyer)
+	if err != nil {
+		return documentError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"document": toDocumentDTO(doc)})
+}
+
+// IssueUPD — админ выпускает УПД; тело с реквизитами необязательно
+func (h *DocumentHandler) IssueUPD(c *fiber.Ctx) error {
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	var buyer *dto.BuyerDTO
+	if len(c.Body()) > 0 {
+		buyer = &dto.BuyerDTO{}
+		if err := c.BodyParser(buyer); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	doc, err := h.service.IssueUPD(orderId, buyer)
+	if err != nil {
+		return documentError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"document": toDocumentDTO(doc)})
+}
+
+func (h *DocumentHandler) GetOrderDocuments(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	docs, err := h.service.GetOrderDocuments(userId, orderId, isAdmin(c))
+	if err != nil {
+		return documentError(c, err)
+	}
+
+	result := make([]dto.DocumentDTO, 0, len(docs))
+	for i := range docs {
+		result = append(result, toDocumentDTO(&docs[i]))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"documents": result})
+}
+
+// DownloadDocument отдаёт PDF потоком из MinIO
+func (h *DocumentHandler) DownloadDocument(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+	docId, err := uuid.Parse(c.Params("docId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid document id"})
+	}
+
+	doc, reader, err := h.service.OpenDocument(userId, orderId, docId, isAdmin(c))
+	if err != nil {
+		return documentError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s-%d-%d.pdf"`, doc.Type, doc.Year, doc.Number))
+	return c.SendStream(reader)
+}
+
+func isAdmin(c *fiber.Ctx) bool {
+	return c.Locals("role") == "admin"
+}
+
+func toDocumentDTO(doc *models.OrderDocument) dto.DocumentDTO {
+	return dto.DocumentDTO{
+		ID:        doc.ID,
+		Type:      string(doc.Type),
+		Number:    doc.Number,
+		Year:      doc.Year,
+		BuyerName: doc.BuyerName,
+		BuyerINN:  doc.BuyerINN,
+		VATRate:   doc.VATRate,
+		VATAmount: doc.VATAmount,
+		Total:     doc.Total,
+		CreatedAt: doc.CreatedAt,
+	}
+}
+
+func documentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	case errors.Is(err, service.ErrOrderNotInvoiceable),
+		errors.Is(err, service.ErrOrderNotShipped),
+		errors.Is(err, service.ErrBuyerRequired):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrSellerNotConfigured):
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+}
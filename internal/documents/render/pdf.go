@@ -0,0 +1,411 @@
+package render
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const fontFamily = "DejaVu"
+
+// Шрифт по умолчанию — из пакета ttf-dejavu в alpine-образе
+const defaultFontPath = "/usr/share/fonts/dejavu/DejaVuSans.ttf"
+
+type Party struct {
+	Name        string
+	INN         string
+	KPP         string
+	Address     string
+	Bank        string
+	BIK         string
+	Account     string
+	CorrAccount string
+}
+
+// Line — строка товара. Amount и VAT — суммы по строке с НДС и налог в ней
+type Line struct {
+	Name     string
+	Unit     string
+	Quantity int
+	Amount   money.Money
+	VAT      money.Money
+}
+
+type Document struct {
+	Type        types.DocumentType
+	Number      int
+	Date        time.Time
+	OrderNumber int32
+
+	Seller Party
+	Buyer  Party
+	Lines  []Line
+
+	VATRate   float64
+	VATAmount money.Money
+	Total     money.Money
+
+	Director   string
+	Accountant string
+}
+
+// Render рисует печатную форму документа и возвращает PDF
+func Render(doc Document, fontPath string) ([]byte, error) {
+	if fontPath == "" {
+		fontPath = defaultFontPath
+	}
+	if _, err := os.Stat(fontPath); err != nil {
+		return nil, fmt.Errorf("pdf font not found: %w", err)
+	}
+
+	orientation := "P"
+	if doc.Type == types.DocumentUPD {
+		orientation = "L"
+	}
+
+	// fpdf ищет шрифты относительно своего каталога, поэтому передаём его отдельно
+	fontDir, fontFile := filepath.Split(fontPath)
+	pdf := fpdf.New(orientation, "mm", "A4", fontDir)
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 10)
+	pdf.AddUTF8Font(fontFamily, "", fontFile)
+
+	// Жирное начертание лежит рядом: DejaVuSans.ttf -> DejaVuSans-Bold.ttf
+	boldFile := strings.TrimSuffix(fontFile, ".ttf") + "-Bold.ttf"
+	if _, err := os.Stat(filepath.Join(fontDir, boldFile)); err != nil {
+		boldFile = fontFile
+	}
+	pdf.AddUTF8Font(fontFamily, "B", boldFile)
+
+	pdf.AddPage()
+
+	switch doc.Type {
+	case types.DocumentInvoice:
+		renderInvoice(pdf, doc)
+	case types.DocumentUPD:
+		renderUPD(pdf, doc)
+	default:
+		return nil, fmt.Errorf("unknown document type: %s", doc.Type)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func renderInvoice(pdf *fpdf.Fpdf, doc Document) {
+	s := doc.Seller
+
+	// Банковские реквизиты получателя
+	pdf.SetFont(fontFamily, "", 9)
+	bankWidths := []float64{110, 20, 60}
+	row(pdf, bankWidths, []string{"L", "L", "L"}, []string{s.Bank + "\nБанк получателя", "БИК\nСч. №", s.BIK + "\n" + s.CorrAccount})
+	row(pdf, bankWidths, []string{"L", "L", "L"}, []string{
+		fmt.Sprintf("ИНН %s    КПП %s\n%s\nПолучатель", s.INN, s.KPP, s.Name),
+		"Сч. №",
+		s.Account,
+	})
+	pdf.Ln(6)
+
+	pdf.SetFont(fontFamily, "B", 14)
+	pdf.CellFormat(0, 8, fmt.Sprintf("%s № %d от %s", doc.Type.Title(), doc.Number, formatDate(doc.Date)), "B", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont(fontFamily, "", 9)
+	labeled(pdf, 30, "Поставщик:", partyLine(s))
+	labeled(pdf, 30, "Покупатель:", partyLine(doc.Buyer))
+	labeled(pdf, 30, "Основание:", fmt.Sprintf("Заказ № %d", doc.OrderNumber))
+	pdf.Ln(3)
+
+	widths := []float64{10, 95, 20, 15, 25, 25}
+	aligns := []string{"C", "L", "R", "C", "R", "R"}
+
+	pdf.SetFont(fontFamily, "B", 9)
+	row(pdf, widths, []string{"C", "C", "C", "C", "C", "C"}, []string{"№", "Товары (работы, услуги)", "Кол-во", "Ед.", "Цена", "Сумма"})
+
+	pdf.SetFont(fontFamily, "", 9)
+	for i, line := range doc.Lines {
+		row(pdf, widths, aligns, []string{
+			fmt.Sprint(i + 1),
+			line.Name,
+			fmt.Sprint(line.Quantity),
+			line.Unit,
+			formatMoney(line.Amount.Share(1, float64(line.Quantity))),
+			formatMoney(line.Amount),
+		})
+	}
+	pdf.Ln(2)
+
+	pdf.SetFont(fontFamily, "B", 9)
+	total(pdf, "Итого:", formatMoney(doc.Total))
+	total(pdf, vatLabel(doc.VATRate), vatValue(doc.VATRate, doc.VATAmount))
+	total(pdf, "Всего к оплате:", formatMoney(doc.Total))
+	pdf.Ln(4)
+
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.MultiCell(0, 5, fmt.Sprintf("Всего наименований %d, на сумму %s руб.", len(doc.Lines), formatMoney(doc.Total)), "", "L", false)
+	pdf.SetFont(fontFamily, "B", 9)
+	pdf.MultiCell(0, 5, AmountInWords(doc.Total), "B", "L", false)
+	pdf.Ln(12)
+
+	pdf.SetFont(fontFamily, "", 9)
+	signatures(pdf, [][2]string{
+		{"Руководитель", doc.Director},
+		{"Бухгалтер", doc.Accountant},
+	})
+}
+
+func renderUPD(pdf *fpdf.Fpdf, doc Document) {
+	s, b := doc.Seller, doc.Buyer
+
+	pdf.SetFont(fontFamily, "B", 12)
+	pdf.CellFormat(0, 7, doc.Type.Title(), "", 1, "L", false, 0, "")
+	pdf.SetFont(fontFamily, "", 10)
+	pdf.CellFormat(0, 6, fmt.Sprintf("Счёт-фактура № %d от %s    Статус: 1", doc.Number, formatDate(doc.Date)), "", 1, "L", false, 0, "")
+	pdf.SetFont(fontFamily, "", 8)
+	pdf.CellFormat(0, 4, "1 — счёт-фактура и передаточный документ (акт)", "", 1, "L", false, 0, "")
+	pdf.Ln(2)
+
+	pdf.SetFont(fontFamily, "", 9)
+	const labelWidth = 65
+	labeled(pdf, labelWidth, "Продавец:", s.Name)
+	labeled(pdf, labelWidth, "Адрес:", s.Address)
+	labeled(pdf, labelWidth, "ИНН/КПП продавца:", innKpp(s))
+	labeled(pdf, labelWidth, "Грузоотправитель и его адрес:", "он же")
+	labeled(pdf, labelWidth, "Грузополучатель и его адрес:", b.Name+", "+b.Address)
+	labeled(pdf, labelWidth, "К платёжно-расчётному документу:", "—")
+	labeled(pdf, labelWidth, "Покупатель:", b.Name)
+	labeled(pdf, labelWidth, "Адрес:", b.Address)
+	labeled(pdf, labelWidth, "ИНН/КПП покупателя:", innKpp(b))
+	labeled(pdf, labelWidth, "Валюта: наименование, код:", "Российский рубль, 643")
+	labeled(pdf, labelWidth, "Основание передачи:", fmt.Sprintf("Заказ № %d", doc.OrderNumber))
+	pdf.Ln(3)
+
+	widths := []float64{10, 105, 15, 17, 25, 28, 22, 25, 30}
+	aligns := []string{"C", "L", "C", "R", "R", "R", "C", "R", "R"}
+
+	pdf.SetFont(fontFamily, "B", 8)
+	row(pdf, widths, []string{"C", "C", "C", "C", "C", "C", "C", "C", "C"}, []string{
+		"№", "Наименование товара", "Ед. изм.", "Кол-во", "Цена без НДС",
+		"Стоимость без НДС", "Налоговая ставка", "Сумма налога", "Стоимость с налогом",
+	})
+
+	pdf.SetFont(fontFamily, "", 8)
+	var totalNet money.Money
+	for i, line := range doc.Lines {
+		net := line.Amount.Sub(line.VAT)
+		totalNet = totalNet.Add(net)
+		row(pdf, widths, aligns, []string{
+			fmt.Sprint(i + 1),
+			line.Name,
+			line.Unit,
+			fmt.Sprint(line.Quantity),
+			formatMoney(net.Share(1, float64(line.Quantity))),
+			formatMoney(net),
+			vatRateLabel(doc.VATRate),
+			vatValue(doc.VATRate, line.VAT),
+			formatMoney(line.Amount),
+		})
+	}
+
+	pdf.SetFont(fontFamily, "B", 8)
+	row(pdf, []float64{widths[0] + widths[1] + widths[2] + widths[3] + widths[4], widths[5], widths[6], widths[7], widths[8]},
+		[]string{"L", "R", "C", "R", "R"},
+		[]string{"Всего к оплате", formatMoney(totalNet), "X", vatValue(doc.VATRate, doc.VATAmount), formatMoney(doc.Total)},
+	)
+	pdf.Ln(3)
+
+	pdf.SetFont(fontFamily, "", 9)
+	pdf.MultiCell(0, 5, "Всего к оплате: "+AmountInWords(doc.Total), "", "L", false)
+	pdf.Ln(8)
+
+	signatures(pdf, [][2]string{
+		{"Руководитель организации", doc.Director},
+		{"Главный бухгалтер", doc.Accountant},
+	})
+	pdf.Ln(6)
+	signatures(pdf, [][2]string{
+		{"Товар (груз) передал", doc.Director},
+		{"Товар (груз) получил", ""},
+	})
+}
+
+// row рисует строку таблицы; высота подстраивается под самую длинную ячейку
+func row(pdf *fpdf.Fpdf, widths []float64, aligns []string, cells []string) {
+	const lineHeight = 5
+
+	lines := make([][]string, len(cells))
+	maxLines := 1
+	for i, cell := range cells {
+		for _, part := range strings.Split(cell, "\n") {
+			lines[i] = append(lines[i], wrap(pdf, part, widths[i]-2)...)
+		}
+		if len(lines[i]) > maxLines {
+			maxLines = len(lines[i])
+		}
+	}
+	height := float64(maxLines) * lineHeight
+
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	if pdf.GetY()+height > pageHeight-bottom {
+		pdf.AddPage()
+	}
+
+	x, y := pdf.GetX(), pdf.GetY()
+	for i := range cells {
+		pdf.Rect(x, y, widths[i], height, "D")
+		for j, text := range lines[i] {
+			pdf.SetXY(x, y+float64(j)*lineHeight)
+			pdf.CellFormat(widths[i], lineHeight, text, "", 0, aligns[i], false, 0, "")
+		}
+		x += widths[i]
+	}
+
+	left, _, _, _ := pdf.GetMargins()
+	pdf.SetXY(left, y+height)
+}
+
+// wrap разбивает текст по словам под ширину ячейки.
+// SplitText из fpdf не работает с UTF-8 шрифтами, поэтому считаем ширину сами.
+func wrap(pdf *fpdf.Fpdf, text string, width float64) []string {
+	var lines []string
+	current := ""
+
+	for _, word := range strings.Fields(text) {
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if pdf.GetStringWidth(candidate) <= width {
+			current = candidate
+			continue
+		}
+
+		if current != "" {
+			lines = append(lines, current)
+		}
+		// Слово длиннее ячейки режем посимвольно
+		current = ""
+		for _, r := range word {
+			if current != "" && pdf.GetStringWidth(current+string(r)) > width {
+				lines = append(lines, current)
+				current = ""
+			}
+			current += string(r)
+		}
+	}
+
+	if current != "" || len(lines) == 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+func labeled(pdf *fpdf.Fpdf, labelWidth float64, label, value string) {
+	pdf.CellFormat(labelWidth, 5, label, "", 0, "L", false, 0, "")
+	pdf.MultiCell(0, 5, value, "", "L", false)
+}
+
+func total(pdf *fpdf.Fpdf, label, value string) {
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	const valueWidth = 30
+
+	pdf.CellFormat(pageWidth-left-right-valueWidth, 5, label, "", 0, "R", false, 0, "")
+	pdf.CellFormat(valueWidth, 5, value, "", 1, "R", false, 0, "")
+}
+
+func signatures(pdf *fpdf.Fpdf, pairs [][2]string) {
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	width := (pageWidth - left - right) / float64(len(pairs))
+
+	for _, pair := range pairs {
+		pdf.CellFormat(width*0.35, 5, pair[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(width*0.3, 5, "", "B", 0, "L", false, 0, "")
+		pdf.CellFormat(width*0.35, 5, pair[1], "", 0, "L", false, 0, "")
+	}
+	pdf.Ln(5)
+}
+
+func partyLine(p Party) string {
+	parts := []string{p.Name}
+	if p.INN != "" {
+		parts = append(parts, "ИНН "+p.INN)
+	}
+	if p.KPP != "" {
+		parts = append(parts, "КПП "+p.KPP)
+	}
+	if p.Address != "" {
+		parts = append(parts, p.Address)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func innKpp(p Party) string {
+	if p.KPP == "" {
+		return p.INN
+	}
+	return p.INN + "/" + p.KPP
+}
+
+func vatLabel(rate float64) string {
+	if rate == 0 {
+		return "Без налога (НДС):"
+	}
+	return fmt.Sprintf("В том числе НДС (%s):", vatRateLabel(rate))
+}
+
+func vatRateLabel(rate float64) string {
+	if rate == 0 {
+		return "без НДС"
+	}
+	return fmt.Sprintf("%g%%", rate)
+}
+
+func vatValue(rate float64, amount money.Money) string {
+	if rate == 0 {
+		return "—"
+	}
+	return formatMoney(amount)
+}
+
+// formatMoney печатает сумму в российском формате: 1 234 567,89
+func formatMoney(amount money.Money) string {
+	kopecks := amount.Kopecks
+	sign := ""
+	if kopecks < 0 {
+		sign = "-"
+		kopecks = -kopecks
+	}
+
+	digits := fmt.Sprint(kopecks / 100)
+	var grouped strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			grouped.WriteRune(' ')
+		}
+		grouped.WriteRune(d)
+	}
+
+	return fmt.Sprintf("%s%s,%02d", sign, grouped.String(), kopecks%100)
+}
+
+var monthsGenitive = []string{
+	"января", "февраля", "марта", "апреля", "мая", "июня",
+	"июля", "августа", "сентября", "октября", "ноября", "декабря",
+}
+
+func formatDate(t time.Time) string {
+	return fmt.Sprintf("%d %s %d г.", t.Day(), monthsGenitive[t.Month()-1], t.Year())
+}
@@ -0,0 +1,104 @@
+package render
+
+import (
+	"Market_backend/internal/common/money"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+var (
+	unitsMale   = []string{"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	unitsFemale = []string{"", "одна", "две", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	teens       = []string{"десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+	tens        = []string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	hundreds    = []string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"}
+)
+
+// scales — разряды: женский род у тысяч, мужской у остальных
+var scales = []struct {
+	female         bool
+	one, few, many string
+}{
+	{false, "", "", ""},
+	{true, "тысяча", "тысячи", "тысяч"},
+	{false, "миллион", "миллиона", "миллионов"},
+	{false, "миллиард", "миллиарда", "миллиардов"},
+}
+
+// plural выбирает форму слова для числа: 1 рубль, 2 рубля, 5 рублей
+func plural(n int64, one, few, many string) string {
+	n %= 100
+	if n >= 11 && n <= 14 {
+		return many
+	}
+	switch n % 10 {
+	case 1:
+		return one
+	case 2, 3, 4:
+		return few
+	}
+	return many
+}
+
+func tripletWords(n int64, female bool) []string {
+	var words []string
+	if h := n / 100; h > 0 {
+		words = append(words, hundreds[h])
+	}
+	rest := n % 100
+	switch {
+	case rest >= 10 && rest < 20:
+		words = append(words, teens[rest-10])
+	default:
+		if t := rest / 10; t > 0 {
+			words = append(words, tens[t])
+		}
+		if u := rest % 10; u > 0 {
+			if female {
+				words = append(words, unitsFemale[u])
+			} else {
+				words = append(words, unitsMale[u])
+			}
+		}
+	}
+	return words
+}
+
+func numberWords(n int64) string {
+	if n == 0 {
+		return "ноль"
+	}
+
+	var parts []string
+	for i := len(scales) - 1; i >= 0; i-- {
+		div := int64(math.Pow(1000, float64(i)))
+		triplet := (n / div) % 1000
+		if triplet == 0 {
+			continue
+		}
+		parts = append(parts, tripletWords(triplet, scales[i].female)...)
+		if i > 0 {
+			parts = append(parts, plural(triplet, scales[i].one, scales[i].few, scales[i].many))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// AmountInWords — сумма прописью для печатных форм: «Одна тысяча двести рублей 50 копеек»
+func AmountInWords(amount money.Money) string {
+	kopecks := amount.Kopecks
+	rubles := kopecks / 100
+	kopecks %= 100
+
+	words := []rune(numberWords(rubles))
+	words[0] = unicode.ToUpper(words[0])
+
+	return fmt.Sprintf("%s %s %02d %s",
+		string(words),
+		plural(rubles, "рубль", "рубля", "рублей"),
+		kopecks,
+		plural(kopecks, "копейка", "копейки", "копеек"),
+	)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DocumentRepository struct {
+	db *gorm.DB
+}
+
+func NewDocumentRepository() *DocumentRepository {
+	return &DocumentRepository{db: common.DB}
+}
+
+func (r *DocumentRepository) DB() *gorm.DB {
+	return r.db
+}
+
+// NextNumberTx выдаёт следующий номер документа за год.
+// Строка счётчика блокируется до конца транзакции, поэтому при откате номер не теряется.
+func (r *DocumentRepository) NextNumberTx(tx *gorm.DB, docType types.DocumentType, year int) (int, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.DocumentCounter{Type: docType, Year: year}).Error; err != nil {
+		return 0, err
+	}
+
+	var counter models.DocumentCounter
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&counter, "type = ? AND year = ?", docType, year).Error; err != nil {
+		return 0, err
+	}
+
+	counter.LastNumber++
+	if err := tx.Model(&counter).
+		Where("type = ? AND year = ?", docType, year).
+		Update("last_number", counter.LastNumber).Error; err != nil {
+		return 0, err
+	}
+
+	return counter.LastNumber, nil
+}
+
+func (r *DocumentRepository) CreateTx(tx *gorm.DB, doc *models.OrderDocument) error {
+	return tx.Create(doc).Error
+}
+
+// FindByOrderTx возвращает nil без ошибки, если документ этого типа ещё не выпускался
+func (r *DocumentRepository) FindByOrderTx(tx *gorm.DB, orderId uuid.UUID, docType types.DocumentType) (*models.OrderDocument, error) {
+	var doc models.OrderDocument
+	err := tx.Where("order_id = ? AND type = ?", orderId, docType).First(&doc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *DocumentRepository) GetByOrder(orderId uuid.UUID) ([]models.OrderDocument, error) {
+	var docs []models.OrderDocument
+	err := r.db.Where("order_id = ?", orderId).Order("created_at").Find(&docs).Error
+	return docs, err
+}
+
+func (r *DocumentRepository) GetById(orderId, docId uuid.UUID) (*models.OrderDocument, error) {
+	var doc models.OrderDocument
+	if err := r.db.First(&doc, "id = ? AND order_id = ?", docId, orderId).Error; err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
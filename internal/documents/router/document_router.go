@@ -0,0 +1,18 @@
+package router
+
+import (
+	"Market_backend/internal/documents/handler"
+	"Market_backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterDocumentRouter(app *fiber.App, h *handler.DocumentHandler) {
+	documents := app.Group("/order/:id/documents")
+
+	documents.Get("/", middleware.AuthRequired(), h.GetOrderDocuments)
+	documents.Get("/:docId", middleware.AuthRequired(), h.DownloadDocument)
+
+	documents.Post("/invoice", middleware.AuthRequired(), h.IssueInvoice)
+	documents.Post("/upd", middleware.AuthRequired(), middleware.AdminOnly(), h.IssueUPD)
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	CompanyRepository "Market_backend/internal/company/repository"
+	"Market_backend/internal/config"
+	"Market_backend/internal/documents/dto"
+	"Market_backend/internal/documents/render"
+	"Market_backend/internal/documents/repository"
+	"Market_backend/internal/storage"
+	"Market_backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultVATRate = 20.0
+
+var (
+	ErrSellerNotConfigured = errors.New("реквизиты продавца не заданы")
+	ErrOrderNotInvoiceable = errors.New("по этому заказу нельзя выставить счёт")
+	ErrOrderNotShipped     = errors.New("УПД выпускается только по отгруженному заказу")
+	ErrBuyerRequired       = errors.New("нужны реквизиты покупателя")
+)
+
+type DocumentService struct {
+	repo        *repository.DocumentRepository
+	companyRepo *CompanyRepository.CompanyRepository
+	storage     *storage.MinioStorage
+	seller      render.Party
+	vatRate     float64
+	fontPath    string
+}
+
+func NewDocumentService(
+	repo *repository.DocumentRepository,
+	companyRepo *CompanyRepository.CompanyRepository,
+	storage *storage.MinioStorage,
+) *DocumentService {
+	vatRate := defaultVATRate
+	if config.VATRate != "" {
+		vatRate = utils.ParseFloat(config.VATRate)
+	}
+
+	return &DocumentService{
+		repo:        repo,
+		companyRepo: companyRepo,
+		storage:     storage,
+		seller: render.Party{
+			Name:        config.SellerName,
+			INN:         config.SellerINN,
+			KPP:         config.SellerKPP,
+			Address:     config.SellerAddress,
+			Bank:        config.SellerBank,
+			BIK:         config.SellerBIK,
+			Account:     config.SellerAccount,
+			CorrAccount: config.SellerCorrAccount,
+		},
+		vatRate:  vatRate,
+		fontPath: config.PDFFontPath,
+	}
+}
+
+// IssueInvoice выставляет счёт на оплату по заказу покупателя.
+// Для заказа компании реквизиты можно не передавать — возьмём из карточки компании.
+// Повторный вызов возвращает уже выпущенный счёт.
+func (s *DocumentService) IssueInvoice(userId, orderId uuid.UUID, buyer *dto.BuyerDTO) (*models.OrderDocument, error) {
+	if buyer != nil {
+		if err := buyer.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.issue(orderId, types.DocumentInvoice, buyer, func(order *models.Order) error {
+		if !s.canAccess(order, userId) {
+			return gorm.ErrRecordNotFound
+		}
+		switch order.Status {
+		case types.Cancelled, types.Failed, types.Refunded:
+			return ErrOrderNotInvoiceable
+		}
+		return nil
+	})
+}
+
+// IssueUPD выпускает УПД по отгруженному заказу.
+// Если реквизиты не переданы, берутся из ранее выставленного счёта или карточки компании.
+func (s *DocumentService) IssueUPD(orderId uuid.UUID, buyer *dto.BuyerDTO) (*models.OrderDocument, error) {
+	if buyer != nil {
+		if err := buyer.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.issue(orderId, types.DocumentUPD, buyer, func(order *models.Order) error {
+		switch order.Status {
+		case types.Shipped, types.Delivered, types.Completed:
+			return nil
+		}
+		return ErrOrderNotShipped
+	})
+}
+
+func (s *DocumentService) issue(
+	orderId uuid.UUID,
+	docType types.DocumentType,
+	buyer *dto.BuyerDTO,
+	check func(order *models.Order) error,
+) (*models.OrderDocument, error) {
+	if s.seller.INN == "" || s.seller.Name == "" {
+		return nil, ErrSellerNotConfigured
+	}
+
+	var doc *models.OrderDocument
+
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		// Блокировка заказа не даёт выпустить два документа одного типа параллельно
+		var order models.Order
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").
+			First(&order, "id = ?", orderId).Error; err != nil {
+			return err
+		}
+		if err := check(&order); err != nil {
+			return err
+		}
+
+		existing, err := s.repo.FindByOrderTx(tx, orderId, docType)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			doc = existing
+			return nil
+		}
+
+		if buyer == nil {
+			buyer, err = s.defaultBuyerTx(tx, &order)
+			if err != nil {
+				return err
+			}
+		}
+
+		lines, err := s.buildLinesTx(tx, &order)
+		if err != nil {
+			return err
+		}
+
+		var total, vatAmount money.Money
+		for _, line := range lines {
+			total = total.Add(line.Amount)
+			vatAmount = vatAmount.Add(line.VAT)
+		}
+
+		now := time.Now()
+		number, err := s.repo.NextNumberTx(tx, docType, now.Year())
+		if err != nil {
+			return err
+		}
+
+		pdf, err := render.Render(render.Document{
+			Type:        docType,
+			Number:      number,
+			Date:        now,
+			OrderNumber: order.OrderNumber,
+			Seller:      s.seller,
+			Buyer: render.Party{
+				Name:    buyer.Name,
+				INN:     buyer.INN,
+				KPP:     buyer.KPP,
+				Address: buyer.Address,
+			},
+			Lines:      lines,
+			VATRate:    s.vatRate,
+			VATAmount:  vatAmount,
+			Total:      total,
+			Director:   config.SellerDirector,
+			Accountant: config.SellerAccountant,
+		}, s.fontPath)
+		if err != nil {
+			return err
+		}
+
+		objectKey := fmt.Sprintf("documents/%d/%s-%d.pdf", now.Year(), docType, number)
+		if err := s.storage.UploadBytes(context.Background(), objectKey, pdf, "application/pdf"); err != nil {
+			return err
+		}
+
+		doc = &models.OrderDocument{
+			ID:           uuid.New(),
+			OrderID:      order.ID,
+			Type:         docType,
+			Year:         now.Year(),
+			Number:       number,
+			BuyerName:    buyer.Name,
+			BuyerINN:     buyer.INN,
+			BuyerKPP:     buyer.KPP,
+			BuyerAddress: buyer.Address,
+			VATRate:      s.vatRate,
+			VATAmount:    vatAmount,
+			Total:        total,
+			ObjectKey:    objectKey,
+		}
+		return s.repo.CreateTx(tx, doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// defaultBuyerTx — реквизиты покупателя, если они не переданы явно:
+// из выставленного ранее счёта, затем из карточки компании заказа
+func (s *DocumentService) defaultBuyerTx(tx *gorm.DB, order *models.Order) (*dto.BuyerDTO, error) {
+	invoice, err := s.repo.FindByOrderTx(tx, order.ID, types.DocumentInvoice)
+	if err != nil {
+		return nil, err
+	}
+	if invoice != nil {
+		return &dto.BuyerDTO{
+			Name:    invoice.BuyerName,
+			INN:     invoice.BuyerINN,
+			KPP:     invoice.BuyerKPP,
+			Address: invoice.BuyerAddress,
+		}, nil
+	}
+
+	if order.CompanyID != nil {
+		var company models.Company
+		if err := tx.First(&company, "id = ?", *order.CompanyID).Error; err != nil {
+			return nil, err
+		}
+		return &dto.BuyerDTO{
+			Name:    company.Name,
+			INN:     company.INN,
+			KPP:     company.KPP,
+			Address: company.Address,
+		}, nil
+	}
+
+	return nil, ErrBuyerRequired
+}
+
+// buildLinesTx собирает строки документа из позиций заказа и доставки.
+// Цены в заказе включают НДС, налог выделяется из суммы строки. Скидка баллами распределяется
+// по позициям пропорционально их сумме, поэтому итог документа равен итогу заказа.
+func (s *DocumentService) buildLinesTx(tx *gorm.DB, order *models.Order) ([]render.Line, error) {
+	lines := make([]render.Line, 0, len(order.Items)+1)
+	var itemsTotal money.Money
+
+	for _, item := range order.Items {
+		var name string
+		var err error
+		switch item.ProductType {
+		case types.Processor:
+			err = tx.Model(&models.Processor{}).Select("name").Where("id = ?", item.ProductID).Scan(&name).Error
+		case types.FlashDriver:
+			err = tx.Model(&models.FlashDrive{}).Select("name").Where("id = ?", item.ProductID).Scan(&name).Error
+		default:
+			err = fmt.Errorf("unknown product type: %s", item.ProductType)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		amount := item.UnitPrice.Mul(item.Quantity)
+		itemsTotal = itemsTotal.Add(amount)
+		lines = append(lines, render.Line{
+			Name:     name,
+			Unit:     "шт",
+			Quantity: item.Quantity,
+			Amount:   amount,
+		})
+	}
+
+	discount := order.PointsDiscount.Min(itemsTotal)
+	left := discount
+	for i := range lines {
+		if !discount.IsPositive() {
+			lines[i].VAT = s.vatOf(lines[i].Amount)
+			continue
+		}
+		part := discount.Share(float64(lines[i].Amount.Kopecks), float64(itemsTotal.Kopecks)).Min(left)
+		if i == len(lines)-1 {
+			part = left // остаток от округления — последней позиции
+		}
+		lines[i].Amount = lines[i].Amount.Sub(part)
+		lines[i].VAT = s.vatOf(lines[i].Amount)
+		left = left.Sub(part)
+	}
+
+	if order.ShippingCost.IsPositive() {
+		lines = append(lines, render.Line{
+			Name:     "Доставка",
+			Unit:     "усл",
+			Quantity: 1,
+			Amount:   order.ShippingCost,
+			VAT:      s.vatOf(order.ShippingCost),
+		})
+	}
+
+	return lines, nil
+}
+
+func (s *DocumentService) vatOf(amount money.Money) money.Money {
+	return amount.Share(s.vatRate, 100+s.vatRate)
+}
+
+// GetOrderDocuments — документы заказа; покупатель видит только свои заказы
+func (s *DocumentService) GetOrderDocuments(userId, orderId uuid.UUID, isAdmin bool) ([]models.OrderDocument, error) {
+	if err := s.checkAccess(userId, orderId, isAdmin); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByOrder(orderId)
+}
+
+// OpenDocument открывает PDF документа на чтение; reader закрывает вызывающий
+func (s *DocumentService) OpenDocument(userId, orderId, docId uuid.UUID, isAdmin bool) (*models.OrderDocument, io.ReadCloser, error) {
+	if err := s.checkAccess(userId, orderId, isAdmin); err != nil {
+		return nil, nil, err
+	}
+
+	doc, err := s.repo.GetById(orderId, docId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Download(context.Background(), doc.ObjectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, reader, nil
+}
+
+func (s *DocumentService) checkAccess(userId, orderId uuid.UUID, isAdmin bool) error {
+	var order models.Order
+	if err := s.repo.DB().Select("id", "user_id", "company_id").First(&order, "id = ?", orderId).Error; err != nil {
+		return err
+	}
+	if !isAdmin && !s.canAccess(&order, userId) {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// canAccess — документы заказа видит покупатель и любой участник компании, от имени которой заказ
+func (s *DocumentService) canAccess(order *models.Order, userId uuid.UUID) bool {
+	if order.UserID == userId {
+		return true
+	}
+	if order.CompanyID == nil {
+		return false
+	}
+	_, err := s.companyRepo.GetMember(*order.CompanyID, userId)
+	return err == nil
+}
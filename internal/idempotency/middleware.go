@@ -0,0 +1,185 @@
+package idempotency
+
+import (
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/idempotency/repository"
+	"Market_backend/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+
+	ScopeOrderCreate   = "order_create"
+	ScopePaymentCreate = "payment_create"
+
+	maxKeyLength     = 255
+	DefaultRetention = 24 * time.Hour
+
+	// processingLease — сколько ключ считается занятым выполняющимся запросом.
+	// С запасом на вызовы ЮKassa (таймаут 30 с и повторы).
+	processingLease = 2 * time.Minute
+
+	// completeRetryMax — наибольшая пауза между попытками сохранить ответ; меньше processingLease,
+	// чтобы ответ успел сохраниться раньше, чем повтор запроса сможет перехватить ключ
+	completeRetryMax = 15 * time.Second
+)
+
+// Idempotency делает операцию идемпотентной по заголовку Idempotency-Key.
+// Первый запрос выполняется как обычно, его ответ сохраняется; повтор с тем же ключом
+// получает сохранённый ответ без повторного выполнения. Без заголовка запрос проходит как есть.
+// Ставится после AuthRequired: ключи разделены по пользователям.
+func Idempotency(repo *repository.IdempotencyRepository, scope string, retention time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(HeaderKey)
+		if key == "" {
+			return c.Next()
+		}
+		if len(key) > maxKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Idempotency-Key is too long",
+			})
+		}
+
+		userId, err := utils.GetUserId(c)
+		if err != nil {
+			return err
+		}
+
+		record, created, err := repo.Reserve(&models.IdempotencyKey{
+			ID:          uuid.New(),
+			UserID:      userId,
+			Scope:       scope,
+			Key:         key,
+			RequestHash: requestHash(c),
+			LockedUntil: time.Now().Add(processingLease),
+			ExpiresAt:   time.Now().Add(retention),
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if !created {
+			return replay(c, record)
+		}
+
+		// Ключ освобождается при любом исходе, кроме сохранённого ответа, — в том числе при панике
+		completed := false
+		defer func() {
+			if !completed {
+				release(repo, record.ID)
+			}
+		}()
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		// Ошибку сервера не запоминаем — клиент должен иметь возможность повторить запрос
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			return nil
+		}
+
+		// Операция уже выполнена: ключ не освобождаем, а сохраняем ответ, пока не получится, —
+		// иначе повтор после истечения блокировки выполнил бы её второй раз
+		completed = true
+		body := append([]byte(nil), c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		if err := repo.Complete(record.ID, status, contentType, body); err != nil {
+			log.Printf("idempotency key %s: save response: %v", key, err)
+			go completeRetry(repo, record, status, contentType, body)
+		}
+		return nil
+	}
+}
+
+// completeRetry повторяет сохранение ответа с нарастающей паузой до успеха или до истечения срока хранения ключа
+func completeRetry(repo *repository.IdempotencyRepository, record *models.IdempotencyKey, status int, contentType string, body []byte) {
+	wait := time.Second
+	for time.Now().Before(record.ExpiresAt) {
+		time.Sleep(wait)
+		err := repo.Complete(record.ID, status, contentType, body)
+		if err == nil {
+			return
+		}
+		log.Printf("idempotency key %s: save response: %v", record.Key, err)
+		wait = min(wait*2, completeRetryMax)
+	}
+}
+
+func replay(c *fiber.Ctx, record *models.IdempotencyKey) error {
+	if record.RequestHash != requestHash(c) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Idempotency-Key was already used for a different request",
+		})
+	}
+	if !record.Completed {
+		if wait := time.Until(record.LockedUntil); wait > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())+1))
+		}
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "request with this Idempotency-Key is still being processed",
+		})
+	}
+
+	c.Set(HeaderReplayed, "true")
+	if record.ContentType != "" {
+		c.Set(fiber.HeaderContentType, record.ContentType)
+	}
+	return c.Status(record.StatusCode).Send(record.ResponseBody)
+}
+
+func release(repo *repository.IdempotencyRepository, id uuid.UUID) {
+	if err := repo.Release(id); err != nil {
+		log.Printf("idempotency key %s: release: %v", id, err)
+	}
+}
+
+// requestHash — отпечаток запроса: метод, путь с параметрами и тело
+func requestHash(c *fiber.Ctx) string {
+	h := sha256.New()
+	h.Write([]byte(c.Method()))
+	h.Write([]byte{0})
+	h.Write([]byte(c.OriginalURL()))
+	h.Write([]byte{0})
+	h.Write(c.Body())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// StartCleanup периодически удаляет ключи с истёкшим сроком хранения
+func StartCleanup(repo *repository.IdempotencyRepository, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			deleted, err := repo.DeleteExpired(time.Now())
+			if err != nil {
+				log.Printf("idempotency cleanup: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				log.Printf("idempotency cleanup: removed %d expired keys", deleted)
+			}
+		}
+	}()
+}
+
+// Retention — срок хранения ключей из IDEMPOTENCY_KEY_TTL_HOURS, по умолчанию сутки
+func Retention(hours string) time.Duration {
+	if h := utils.ParseInt(hours); h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	return DefaultRetention
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository() *IdempotencyRepository {
+	return &IdempotencyRepository{db: common.DB}
+}
+
+// Reserve пытается занять ключ. Если ключ уже занят, возвращает существующую запись и false.
+// Просроченная запись удаляется и ключ занимается заново. Незавершённую запись того же запроса
+// с истёкшей блокировкой перехватывает новый запрос: записи даётся новый id, поэтому
+// зависший обработчик уже не сможет ни сохранить ответ, ни освободить ключ.
+func (r *IdempotencyRepository) Reserve(key *models.IdempotencyKey) (*models.IdempotencyKey, bool, error) {
+	var existing *models.IdempotencyKey
+	now := time.Now()
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("user_id = ? AND scope = ? AND key = ? AND expires_at <= ?", key.UserID, key.Scope, key.Key, now).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			return nil
+		}
+
+		res = tx.Model(&models.IdempotencyKey{}).
+			Where("user_id = ? AND scope = ? AND key = ? AND request_hash = ? AND completed = false AND locked_until <= ?",
+				key.UserID, key.Scope, key.Key, key.RequestHash, now).
+			Updates(map[string]any{
+				"id":           key.ID,
+				"locked_until": key.LockedUntil,
+				"expires_at":   key.ExpiresAt,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			return nil
+		}
+
+		existing = &models.IdempotencyKey{}
+		return tx.First(existing, "user_id = ? AND scope = ? AND key = ?", key.UserID, key.Scope, key.Key).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing != nil {
+		return existing, false, nil
+	}
+	return key, true, nil
+}
+
+// Complete сохраняет ответ, который получат повторные запросы с этим ключом
+func (r *IdempotencyRepository) Complete(id uuid.UUID, statusCode int, contentType string, body []byte) error {
+	return r.db.Model(&models.IdempotencyKey{}).Where("id = ? AND completed = false", id).Updates(map[string]any{
+		"completed":     true,
+		"status_code":   statusCode,
+		"content_type":  contentType,
+		"response_body": body,
+	}).Error
+}
+
+// Release освобождает ключ, чтобы запрос можно было повторить (например, после ошибки сервера)
+func (r *IdempotencyRepository) Release(id uuid.UUID) error {
+	return r.db.Delete(&models.IdempotencyKey{}, "id = ? AND completed = false", id).Error
+}
+
+// DeleteExpired удаляет ключи с истёкшим сроком хранения
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
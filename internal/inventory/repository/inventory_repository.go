@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InventoryRepository struct {
+	db *gorm.DB
+}
+
+func NewInventoryRepository() *InventoryRepository {
+	return &InventoryRepository{db: common.DB}
+}
+
+func (r *InventoryRepository) DB() *gorm.DB {
+	return r.db
+}
+
+// AdjustStockTx атомарно меняет остаток товара на delta и не даёт уйти в минус
+func (r *InventoryRepository) AdjustStockTx(tx *gorm.DB, productType types.ProductType, productId uuid.UUID, delta int) error {
+	var model any
+	switch productType {
+	case types.Processor:
+		model = &models.Processor{}
+	case types.FlashDriver:
+		model = &models.FlashDrive{}
+	default:
+		return fmt.Errorf("unknown product type: %s", productType)
+	}
+
+	res := tx.Model(model).
+		Where("id = ? AND stock + ? >= 0", productId, delta).
+		Update("stock", gorm.Expr("stock + ?", delta))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("товара %s не хватает на складе", productId)
+	}
+	return nil
+}
+
+func (r *InventoryRepository) CreateMovementTx(tx *gorm.DB, movement *models.StockMovement) error {
+	if movement.ID == uuid.Nil {
+		movement.ID = uuid.New()
+	}
+	return tx.Create(movement).Error
+}
+
+func (r *InventoryRepository) GetMovementsByProduct(productId uuid.UUID) ([]models.StockMovement, error) {
+	var movements []models.StockMovement
+	if err := r.db.Where("product_id = ?", productId).Order("created_at DESC").Find(&movements).Error; err != nil {
+		return nil, err
+	}
+	return movements, nil
+}
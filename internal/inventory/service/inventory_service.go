@@ -0,0 +1,77 @@
+package service
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/inventory/repository"
+	"Market_backend/models"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InventoryService struct {
+	repo *repository.InventoryRepository
+}
+
+func NewInventoryService(repo *repository.InventoryRepository) *InventoryService {
+	return &InventoryService{repo: repo}
+}
+
+// ReserveOrderTx списывает остатки под позиции заказа
+func (s *InventoryService) ReserveOrderTx(tx *gorm.DB, orderId uuid.UUID, items []models.OrderItem) error {
+	for _, item := range items {
+		if err := s.moveTx(tx, item.ProductType, item.ProductID, -item.Quantity, types.StockReserve, &orderId, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseOrderTx возвращает на склад остатки, списанные под заказ
+func (s *InventoryService) ReleaseOrderTx(tx *gorm.DB, orderId uuid.UUID, items []models.OrderItem) error {
+	for _, item := range items {
+		if err := s.moveTx(tx, item.ProductType, item.ProductID, item.Quantity, types.StockRelease, &orderId, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestockTx возвращает на склад товар, полученный от покупателя
+func (s *InventoryService) RestockTx(tx *gorm.DB, productType types.ProductType, productId uuid.UUID, quantity int, orderId *uuid.UUID, comment string) error {
+	return s.moveTx(tx, productType, productId, quantity, types.StockRestock, orderId, comment)
+}
+
+// WriteOffTx фиксирует списание брака: на остаток не влияет, остаётся только запись в журнале
+func (s *InventoryService) WriteOffTx(tx *gorm.DB, productType types.ProductType, productId uuid.UUID, quantity int, orderId *uuid.UUID, comment string) error {
+	if comment == "" {
+		comment = fmt.Sprintf("списано %d шт.", quantity)
+	}
+	return s.moveTx(tx, productType, productId, 0, types.StockWriteOff, orderId, comment)
+}
+
+func (s *InventoryService) moveTx(
+	tx *gorm.DB,
+	productType types.ProductType,
+	productId uuid.UUID,
+	delta int,
+	reason types.StockMovementReason,
+	orderId *uuid.UUID,
+	comment string,
+) error {
+	if delta != 0 {
+		if err := s.repo.AdjustStockTx(tx, productType, productId, delta); err != nil {
+			return err
+		}
+	}
+
+	return s.repo.CreateMovementTx(tx, &models.StockMovement{
+		ProductID:   productId,
+		ProductType: productType,
+		Delta:       delta,
+		Reason:      reason,
+		OrderID:     orderId,
+		Comment:     comment,
+	})
+}
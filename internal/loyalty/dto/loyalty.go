@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LoyaltyTransactionDTO struct {
+	ID        uuid.UUID  `json:"id"`
+	Kind      string     `json:"kind"`
+	Points    int        `json:"points"`
+	Remaining int        `json:"remaining,omitempty"` // несписанный остаток начисления
+	OrderID   *uuid.UUID `json:"order_id,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Comment   string     `json:"comment"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// LoyaltyExpiryDTO — сколько баллов и когда сгорят первыми
+type LoyaltyExpiryDTO struct {
+	Points    int       `json:"points"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoyaltyResponse — бонусный счёт в профиле: баланс, условия программы и история
+type LoyaltyResponse struct {
+	Balance         int                     `json:"balance"`
+	EarnPercent     int                     `json:"earn_percent"`
+	MaxSharePercent int                     `json:"max_share_percent"`
+	PointsTTLDays   int                     `json:"points_ttl_days"`
+	NextExpiry      *LoyaltyExpiryDTO       `json:"next_expiry"`
+	Total           int64                   `json:"total"`
+	Transactions    []LoyaltyTransactionDTO `json:"transactions"`
+}
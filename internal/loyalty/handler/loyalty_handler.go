@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/loyalty/dto"
+	"Market_backend/internal/loyalty/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LoyaltyHandler struct {
+	service *service.LoyaltyService
+}
+
+func NewLoyaltyHandler(service *service.LoyaltyService) *LoyaltyHandler {
+	return &LoyaltyHandler{service: service}
+}
+
+// GetHistory — баланс баллов и история начислений и списаний текущего пользователя
+func (h *LoyaltyHandler) GetHistory(c *fiber.Ctx) error {
+	userID, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be between 1 and 100"})
+	}
+
+	balance, next, transactions, total, err := h.service.GetHistory(userID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.LoyaltyResponse{
+		Balance:      balance,
+		Total:        total,
+		Transactions: make([]dto.LoyaltyTransactionDTO, 0, len(transactions)),
+	}
+	response.EarnPercent, response.MaxSharePercent, response.PointsTTLDays = h.service.Rules()
+	if next != nil {
+		response.NextExpiry = &dto.LoyaltyExpiryDTO{Points: next.Remaining, ExpiresAt: *next.ExpiresAt}
+	}
+	for _, t := range transactions {
+		response.Transactions = append(response.Transactions, dto.LoyaltyTransactionDTO{
+			ID:        t.ID,
+			Kind:      string(t.Kind),
+			Points:    t.Points,
+			Remaining: t.Remaining,
+			OrderID:   t.OrderID,
+			ExpiresAt: t.ExpiresAt,
+			Comment:   t.Comment,
+			CreatedAt: t.CreatedAt,
+		})
+	}
+	return c.JSON(response)
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var lotKinds = []types.LoyaltyKind{types.LoyaltyEarn, types.LoyaltyRestore}
+
+type LoyaltyRepository struct {
+	db *gorm.DB
+}
+
+func NewLoyaltyRepository() *LoyaltyRepository {
+	return &LoyaltyRepository{db: common.DB}
+}
+
+func (r *LoyaltyRepository) DB() *gorm.DB {
+	return r.db
+}
+
+func (r *LoyaltyRepository) CreateTx(tx *gorm.DB, transaction *models.LoyaltyTransaction) error {
+	return tx.Create(transaction).Error
+}
+
+// UpdateRemainingTx сохраняет несписанный остаток начисления
+func (r *LoyaltyRepository) UpdateRemainingTx(tx *gorm.DB, lot *models.LoyaltyTransaction) error {
+	return tx.Model(&models.LoyaltyTransaction{}).Where("id = ?", lot.ID).Update("remaining", lot.Remaining).Error
+}
+
+// GetOpenLotsTx — несгоревшие начисления покупателя с остатком, ближайшие к сгоранию первыми.
+// Строки блокируются: параллельные списания одного покупателя выполняются по очереди.
+func (r *LoyaltyRepository) GetOpenLotsTx(tx *gorm.DB, userID uuid.UUID, now time.Time) ([]models.LoyaltyTransaction, error) {
+	var lots []models.LoyaltyTransaction
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND kind IN ? AND remaining > 0 AND (expires_at IS NULL OR expires_at > ?)", userID, lotKinds, now).
+		Order("expires_at ASC NULLS LAST, created_at ASC").
+		Find(&lots).Error
+	return lots, err
+}
+
+// GetBalance — сколько баллов покупатель может потратить сейчас
+func (r *LoyaltyRepository) GetBalance(userID uuid.UUID, now time.Time) (int, error) {
+	var balance int
+	err := r.db.Model(&models.LoyaltyTransaction{}).
+		Select("COALESCE(SUM(remaining), 0)").
+		Where("user_id = ? AND kind IN ? AND remaining > 0 AND (expires_at IS NULL OR expires_at > ?)", userID, lotKinds, now).
+		Scan(&balance).Error
+	return balance, err
+}
+
+// GetNextExpiring — начисление с остатком, которое сгорит первым
+func (r *LoyaltyRepository) GetNextExpiring(userID uuid.UUID, now time.Time) (*models.LoyaltyTransaction, error) {
+	var lot models.LoyaltyTransaction
+	err := r.db.
+		Where("user_id = ? AND kind IN ? AND remaining > 0 AND expires_at > ?", userID, lotKinds, now).
+		Order("expires_at ASC").
+		First(&lot).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lot, nil
+}
+
+// SumOrderPointsTx — сумма баллов по заказу для движений вида kind
+func (r *LoyaltyRepository) SumOrderPointsTx(tx *gorm.DB, orderID uuid.UUID, kind types.LoyaltyKind) (int, error) {
+	var points int
+	err := tx.Model(&models.LoyaltyTransaction{}).
+		Select("COALESCE(SUM(points), 0)").
+		Where("order_id = ? AND kind = ?", orderID, kind).
+		Scan(&points).Error
+	return points, err
+}
+
+// GetExpiredLots — начисления, срок которых истёк, а остаток ещё не списан
+func (r *LoyaltyRepository) GetExpiredLots(now time.Time, limit int) ([]models.LoyaltyTransaction, error) {
+	var lots []models.LoyaltyTransaction
+	err := r.db.
+		Where("kind IN ? AND remaining > 0 AND expires_at <= ?", lotKinds, now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&lots).Error
+	return lots, err
+}
+
+func (r *LoyaltyRepository) GetByIdTx(tx *gorm.DB, id uuid.UUID) (*models.LoyaltyTransaction, error) {
+	var transaction models.LoyaltyTransaction
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&transaction, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// GetHistory — движения баллов покупателя, новые первыми
+func (r *LoyaltyRepository) GetHistory(userID uuid.UUID, limit, offset int) ([]models.LoyaltyTransaction, int64, error) {
+	query := r.db.Model(&models.LoyaltyTransaction{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transactions []models.LoyaltyTransaction
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&transactions).Error
+	return transactions, total, err
+}
@@ -0,0 +1,13 @@
+package router
+
+import (
+	"Market_backend/internal/loyalty/handler"
+	"Market_backend/internal/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterLoyaltyRouter(app *fiber.App, h *handler.LoyaltyHandler) {
+	// Бонусный счёт в профиле пользователя
+	app.Get("/users/me/loyalty", middleware.AuthRequired(), h.GetHistory)
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/config"
+	"Market_backend/internal/loyalty/repository"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotEnoughPoints     = errors.New("недостаточно бонусных баллов")
+	ErrPointsShareExceeded = errors.New("баллами можно оплатить не больше установленной доли стоимости товаров")
+	ErrPointsRetailOnly    = errors.New("баллы не применяются к заказам компаний")
+)
+
+// Значения по умолчанию, если LOYALTY_* не заданы
+const (
+	DefaultEarnPercent     = 5
+	DefaultMaxSharePercent = 30
+	DefaultPointsTTLDays   = 365
+)
+
+// pointValue — стоимость одного балла
+var pointValue = money.New(100)
+
+// setting — числовая настройка программы; пусто — значение по умолчанию
+func setting(value string, def int) int {
+	if value == "" {
+		return def
+	}
+	return utils.ParseInt(value)
+}
+
+func earnPercent() int     { return setting(config.LoyaltyEarnPercent, DefaultEarnPercent) }
+func maxSharePercent() int { return setting(config.LoyaltyMaxSharePercent, DefaultMaxSharePercent) }
+func pointsTTLDays() int   { return setting(config.LoyaltyPointsTTLDays, DefaultPointsTTLDays) }
+
+// proportional — доля points, соответствующая amount из total, с округлением
+func proportional(points int, amount, total money.Money) int {
+	if !total.IsPositive() {
+		return 0
+	}
+	return int((int64(points)*amount.Kopecks + total.Kopecks/2) / total.Kopecks)
+}
+
+type LoyaltyService struct {
+	repo *repository.LoyaltyRepository
+}
+
+func NewLoyaltyService(repo *repository.LoyaltyRepository) *LoyaltyService {
+	return &LoyaltyService{repo: repo}
+}
+
+// Rules — действующие условия программы для профиля покупателя
+func (s *LoyaltyService) Rules() (earn, maxShare, ttlDays int) {
+	return earnPercent(), maxSharePercent(), pointsTTLDays()
+}
+
+// DiscountTx проверяет, можно ли оплатить points баллами заказ со стоимостью товаров base, и возвращает скидку
+func (s *LoyaltyService) DiscountTx(tx *gorm.DB, userID uuid.UUID, points int, base money.Money) (money.Money, error) {
+	if points <= 0 {
+		return money.Money{}, nil
+	}
+
+	discount := pointValue.Mul(points)
+	if base.Share(float64(maxSharePercent()), 100).Less(discount) {
+		return money.Money{}, ErrPointsShareExceeded
+	}
+
+	lots, err := s.repo.GetOpenLotsTx(tx, userID, time.Now())
+	if err != nil {
+		return money.Money{}, err
+	}
+	available := 0
+	for _, lot := range lots {
+		available += lot.Remaining
+	}
+	if available < points {
+		return money.Money{}, ErrNotEnoughPoints
+	}
+	return discount, nil
+}
+
+// SpendTx списывает баллы, которыми оплачена часть только что созданного заказа
+func (s *LoyaltyService) SpendTx(tx *gorm.DB, orderID uuid.UUID) error {
+	var order models.Order
+	if err := tx.Select("id", "user_id", "order_number", "points_spent").
+		First(&order, "id = ?", orderID).Error; err != nil {
+		return err
+	}
+	if order.PointsSpent <= 0 {
+		return nil
+	}
+
+	consumed, err := s.consumeTx(tx, order.UserID, order.PointsSpent)
+	if err != nil {
+		return err
+	}
+	if consumed < order.PointsSpent {
+		return ErrNotEnoughPoints
+	}
+
+	return s.repo.CreateTx(tx, &models.LoyaltyTransaction{
+		ID:        uuid.New(),
+		UserID:    order.UserID,
+		OrderID:   &order.ID,
+		Kind:      types.LoyaltySpend,
+		Points:    -order.PointsSpent,
+		Comment:   fmt.Sprintf("оплата заказа №%d", order.OrderNumber),
+		CreatedAt: time.Now(),
+	})
+}
+
+// EarnTx начисляет баллы за завершённый розничный заказ — процент от фактически оплаченной суммы.
+// Повторный вызов для того же заказа ничего не начисляет.
+func (s *LoyaltyService) EarnTx(tx *gorm.DB, order *models.Order) error {
+	percent := earnPercent()
+	if order.CompanyID != nil || percent <= 0 {
+		return nil
+	}
+
+	earned, err := s.repo.SumOrderPointsTx(tx, order.ID, types.LoyaltyEarn)
+	if err != nil || earned > 0 {
+		return err
+	}
+
+	paid := order.Total.Sub(order.RefundedAmount)
+	points := int(paid.Kopecks * int64(percent) / 100 / pointValue.Kopecks)
+	if points <= 0 {
+		return nil
+	}
+
+	return s.createLotTx(tx, order.UserID, &order.ID, types.LoyaltyEarn, points,
+		fmt.Sprintf("начисление за заказ №%d", order.OrderNumber))
+}
+
+// OnRefundTx пересчитывает баллы после возврата денег по заказу: начисленные за него баллы списываются,
+// потраченные на него — возвращаются, в доле возвращённой суммы от итога заказа.
+// Если начисленные баллы уже потрачены, списывается сколько есть.
+func (s *LoyaltyService) OnRefundTx(tx *gorm.DB, orderID uuid.UUID, amount money.Money) error {
+	var order models.Order
+	if err := tx.Select("id", "user_id", "order_number", "total", "points_spent").
+		First(&order, "id = ?", orderID).Error; err != nil {
+		return err
+	}
+
+	earned, err := s.repo.SumOrderPointsTx(tx, order.ID, types.LoyaltyEarn)
+	if err != nil {
+		return err
+	}
+	reversed, err := s.repo.SumOrderPointsTx(tx, order.ID, types.LoyaltyReverse)
+	if err != nil {
+		return err
+	}
+	if left := earned + reversed; left > 0 {
+		want := min(proportional(earned, amount, order.Total), left)
+		consumed, err := s.consumeTx(tx, order.UserID, want)
+		if err != nil {
+			return err
+		}
+		if consumed > 0 {
+			if err := s.repo.CreateTx(tx, &models.LoyaltyTransaction{
+				ID:        uuid.New(),
+				UserID:    order.UserID,
+				OrderID:   &order.ID,
+				Kind:      types.LoyaltyReverse,
+				Points:    -consumed,
+				Comment:   fmt.Sprintf("возврат по заказу №%d", order.OrderNumber),
+				CreatedAt: time.Now(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.restoreTx(tx, &order, proportional(order.PointsSpent, amount, order.Total))
+}
+
+// RestoreSpentTx возвращает все ещё не возвращённые баллы, потраченные на заказ, — при его отмене
+func (s *LoyaltyService) RestoreSpentTx(tx *gorm.DB, orderID uuid.UUID) error {
+	var order models.Order
+	if err := tx.Select("id", "user_id", "order_number", "points_spent").
+		First(&order, "id = ?", orderID).Error; err != nil {
+		return err
+	}
+	return s.restoreTx(tx, &order, order.PointsSpent)
+}
+
+// restoreTx возвращает до points потраченных на заказ баллов новым начислением со свежим сроком
+func (s *LoyaltyService) restoreTx(tx *gorm.DB, order *models.Order, points int) error {
+	if order.PointsSpent <= 0 || points <= 0 {
+		return nil
+	}
+	restored, err := s.repo.SumOrderPointsTx(tx, order.ID, types.LoyaltyRestore)
+	if err != nil {
+		return err
+	}
+	points = min(points, order.PointsSpent-restored)
+	if points <= 0 {
+		return nil
+	}
+	return s.createLotTx(tx, order.UserID, &order.ID, types.LoyaltyRestore, points,
+		fmt.Sprintf("возврат баллов за заказ №%d", order.OrderNumber))
+}
+
+func (s *LoyaltyService) createLotTx(tx *gorm.DB, userID uuid.UUID, orderID *uuid.UUID, kind types.LoyaltyKind, points int, comment string) error {
+	now := time.Now()
+	lot := &models.LoyaltyTransaction{
+		ID:        uuid.New(),
+		UserID:    userID,
+		OrderID:   orderID,
+		Kind:      kind,
+		Points:    points,
+		Remaining: points,
+		Comment:   comment,
+		CreatedAt: now,
+	}
+	if days := pointsTTLDays(); days > 0 {
+		expiresAt := now.AddDate(0, 0, days)
+		lot.ExpiresAt = &expiresAt
+	}
+	return s.repo.CreateTx(tx, lot)
+}
+
+// consumeTx уменьшает остатки начислений покупателя на points, начиная с ближайших к сгоранию.
+// Возвращает, сколько удалось списать.
+func (s *LoyaltyService) consumeTx(tx *gorm.DB, userID uuid.UUID, points int) (int, error) {
+	if points <= 0 {
+		return 0, nil
+	}
+
+	lots, err := s.repo.GetOpenLotsTx(tx, userID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	consumed := 0
+	for i := range lots {
+		if consumed == points {
+			break
+		}
+		take := min(lots[i].Remaining, points-consumed)
+		lots[i].Remaining -= take
+		if err := s.repo.UpdateRemainingTx(tx, &lots[i]); err != nil {
+			return 0, err
+		}
+		consumed += take
+	}
+	return consumed, nil
+}
+
+// ExpirePoints списывает сгоревшие остатки начислений. Возвращает число обработанных начислений.
+func (s *LoyaltyService) ExpirePoints() (int, error) {
+	lots, err := s.repo.GetExpiredLots(time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, lot := range lots {
+		err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+			locked, err := s.repo.GetByIdTx(tx, lot.ID)
+			if err != nil || locked.Remaining <= 0 {
+				return err
+			}
+
+			points := locked.Remaining
+			locked.Remaining = 0
+			if err := s.repo.UpdateRemainingTx(tx, locked); err != nil {
+				return err
+			}
+			return s.repo.CreateTx(tx, &models.LoyaltyTransaction{
+				ID:        uuid.New(),
+				UserID:    locked.UserID,
+				OrderID:   locked.OrderID,
+				Kind:      types.LoyaltyExpire,
+				Points:    -points,
+				Comment:   fmt.Sprintf("сгорели баллы, начисленные %s", locked.CreatedAt.Format("02.01.2006")),
+				CreatedAt: time.Now(),
+			})
+		})
+		if err != nil {
+			log.Printf("loyalty expiry: lot %s: %v", lot.ID, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// StartExpiryWatcher периодически списывает сгоревшие баллы
+func (s *LoyaltyService) StartExpiryWatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			expired, err := s.ExpirePoints()
+			if err != nil {
+				log.Printf("loyalty expiry: %v", err)
+				continue
+			}
+			if expired > 0 {
+				log.Printf("loyalty expiry: expired %d point lots", expired)
+			}
+		}
+	}()
+}
+
+// GetHistory — баланс баллов, ближайшее сгорание и движения по счёту покупателя
+func (s *LoyaltyService) GetHistory(userID uuid.UUID, page, limit int) (int, *models.LoyaltyTransaction, []models.LoyaltyTransaction, int64, error) {
+	now := time.Now()
+	balance, err := s.repo.GetBalance(userID, now)
+	if err != nil {
+		return 0, nil, nil, 0, err
+	}
+
+	next, err := s.repo.GetNextExpiring(userID, now)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil, nil, 0, err
+	}
+
+	transactions, total, err := s.repo.GetHistory(userID, limit, (page-1)*limit)
+	if err != nil {
+		return 0, nil, nil, 0, err
+	}
+	return balance, next, transactions, total, nil
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"mime/multipart"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PostOrderMessageDTO struct {
+	Text  string
+	Files []*multipart.FileHeader
+}
+
+type AttachmentDTO struct {
+	ID          uuid.UUID `json:"id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+}
+
+type OrderMessageDTO struct {
+	ID          uuid.UUID       `json:"id"`
+	AuthorName  string          `json:"author_name"`
+	FromStaff   bool            `json:"from_staff"`
+	Text        string          `json:"text"`
+	Attachments []AttachmentDTO `json:"attachments"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type OrderThreadDTO struct {
+	Messages []OrderMessageDTO `json:"messages"`
+	Unread   int64             `json:"unread"`
+}
+
+// UnreadThread — заказ, в переписке по которому есть непрочитанные сообщения
+type UnreadThread struct {
+	OrderID       uuid.UUID `json:"order_id"`
+	OrderNumber   int32     `json:"order_number"`
+	Unread        int64     `json:"unread"`
+	LastMessageAt time.Time `json:"last_message_at"`
+}
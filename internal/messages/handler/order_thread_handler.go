@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/messages/dto"
+	"Market_backend/internal/messages/service"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OrderThreadHandler struct {
+	service *service.OrderThreadService
+}
+
+func NewOrderThreadHandler(service *service.OrderThreadService) *OrderThreadHandler {
+	return &OrderThreadHandler{service: service}
+}
+
+func (h *OrderThreadHandler) GetThread(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	messages, unread, err := h.service.GetThread(userId, orderId, isAdmin(c))
+	if err != nil {
+		return threadError(c, err)
+	}
+
+	result := dto.OrderThreadDTO{
+		Messages: make([]dto.OrderMessageDTO, 0, len(messages)),
+		Unread:   unread,
+	}
+	for i := range messages {
+		result.Messages = append(result.Messages, toOrderMessageDTO(&messages[i]))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// PostMessage принимает multipart-форму: text и до 5 файлов files
+func (h *OrderThreadHandler) PostMessage(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	var msgDto dto.PostOrderMessageDTO
+	if strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid form"})
+		}
+		if vals := form.Value["text"]; len(vals) > 0 {
+			msgDto.Text = vals[0]
+		}
+		msgDto.Files = form.File["files"]
+	} else {
+		var body struct {
+			Text string `json:"text"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		msgDto.Text = body.Text
+	}
+
+	msg, err := h.service.PostMessage(userId, orderId, isAdmin(c), msgDto)
+	if err != nil {
+		return threadError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(toOrderMessageDTO(msg))
+}
+
+func (h *OrderThreadHandler) MarkRead(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	if err := h.service.MarkRead(userId, orderId, isAdmin(c)); err != nil {
+		return threadError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "thread marked as read"})
+}
+
+// DownloadAttachment отдаёт вложение потоком из MinIO
+func (h *OrderThreadHandler) DownloadAttachment(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+	attachmentId, err := uuid.Parse(c.Params("attachmentId"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid attachment id"})
+	}
+
+	attachment, reader, err := h.service.OpenAttachment(userId, orderId, attachmentId, isAdmin(c))
+	if err != nil {
+		return threadError(c, err)
+	}
+
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, attachment.FileName))
+	return c.SendStream(reader)
+}
+
+func (h *OrderThreadHandler) GetUnreadThreads(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	threads, err := h.service.GetUnreadThreads(userId, isAdmin(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	if threads == nil {
+		threads = []dto.UnreadThread{}
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"threads": threads})
+}
+
+func isAdmin(c *fiber.Ctx) bool {
+	return c.Locals("role") == "admin"
+}
+
+func toOrderMessageDTO(msg *models.OrderMessage) dto.OrderMessageDTO {
+	attachments := make([]dto.AttachmentDTO, 0, len(msg.Attachments))
+	for _, a := range msg.Attachments {
+		attachments = append(attachments, dto.AttachmentDTO{
+			ID:          a.ID,
+			FileName:    a.FileName,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+		})
+	}
+
+	return dto.OrderMessageDTO{
+		ID:          msg.ID,
+		AuthorName:  strings.TrimSpace(msg.Author.Name + " " + msg.Author.Surname),
+		FromStaff:   msg.FromStaff,
+		Text:        msg.Text,
+		Attachments: attachments,
+		CreatedAt:   msg.CreatedAt,
+	}
+}
+
+func threadError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	case errors.Is(err, service.ErrEmptyMessage),
+		errors.Is(err, service.ErrMessageTooLong),
+		errors.Is(err, service.ErrTooManyAttachments):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+}
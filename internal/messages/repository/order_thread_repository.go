@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/internal/messages/dto"
+	"Market_backend/models"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrderThreadRepository struct {
+	db *gorm.DB
+}
+
+func NewOrderThreadRepository() *OrderThreadRepository {
+	return &OrderThreadRepository{db: common.DB}
+}
+
+func (r *OrderThreadRepository) DB() *gorm.DB {
+	return r.db
+}
+
+// CreateMessage сохраняет сообщение вместе с вложениями
+func (r *OrderThreadRepository) CreateMessage(msg *models.OrderMessage) error {
+	return r.db.Create(msg).Error
+}
+
+func (r *OrderThreadRepository) GetMessages(orderId uuid.UUID) ([]models.OrderMessage, error) {
+	var messages []models.OrderMessage
+	err := r.db.
+		Preload("Author").
+		Preload("Attachments").
+		Where("order_id = ?", orderId).
+		Order("created_at ASC").
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *OrderThreadRepository) GetAttachment(orderId, attachmentId uuid.UUID) (*models.OrderMessageAttachment, error) {
+	var attachment models.OrderMessageAttachment
+	if err := r.db.
+		Joins("JOIN order_messages ON order_messages.id = order_message_attachments.message_id").
+		Where("order_message_attachments.id = ? AND order_messages.order_id = ?", attachmentId, orderId).
+		First(&attachment).Error; err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// MarkRead сдвигает отметку прочтения вперёд; более ранняя отметка её не откатывает
+func (r *OrderThreadRepository) MarkRead(orderId, userId uuid.UUID, at time.Time) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "order_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Set{{
+			Column: clause.Column{Name: "last_read_at"},
+			Value:  gorm.Expr("GREATEST(order_thread_reads.last_read_at, EXCLUDED.last_read_at)"),
+		}},
+	}).Create(&models.OrderThreadRead{OrderID: orderId, UserID: userId, LastReadAt: at}).Error
+}
+
+// CountUnread — сообщения другой стороны после отметки прочтения пользователя
+func (r *OrderThreadRepository) CountUnread(orderId, userId uuid.UUID, fromStaff bool) (int64, error) {
+	var read models.OrderThreadRead
+	err := r.db.First(&read, "order_id = ? AND user_id = ?", orderId, userId).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	var count int64
+	err = r.db.Model(&models.OrderMessage{}).
+		Where("order_id = ? AND from_staff = ? AND created_at > ?", orderId, fromStaff, read.LastReadAt).
+		Count(&count).Error
+	return count, err
+}
+
+// GetUnreadThreads — заказы с непрочитанными сообщениями другой стороны.
+// customerId != nil ограничивает выборку заказами покупателя.
+func (r *OrderThreadRepository) GetUnreadThreads(userId uuid.UUID, fromStaff bool, customerId *uuid.UUID) ([]dto.UnreadThread, error) {
+	query := r.db.
+		Table("order_messages AS m").
+		Select("m.order_id, o.order_number, COUNT(*) AS unread, MAX(m.created_at) AS last_message_at").
+		Joins("JOIN orders o ON o.id = m.order_id").
+		Joins("LEFT JOIN order_thread_reads r ON r.order_id = m.order_id AND r.user_id = ?", userId).
+		Where("m.from_staff = ? AND (r.last_read_at IS NULL OR m.created_at > r.last_read_at)", fromStaff)
+	if customerId != nil {
+		query = query.Where("o.user_id = ?", *customerId)
+	}
+
+	var threads []dto.UnreadThread
+	err := query.
+		Group("m.order_id, o.order_number").
+		Order("last_message_at DESC").
+		Scan(&threads).Error
+	return threads, err
+}
@@ -15,3 +15,16 @@ func RegisterMessageRoutes(app *fiber.App, h *handler.MessageHandler) {
 	// Получение всех сообщений (GET /message/all) с middleware если нужно
 	message.Get("/all", middleware.AuthRequired(), middleware.AdminOnly(), h.GetMessages)
 }
+
+// RegisterOrderThreadRoutes — переписка по конкретному заказу
+func RegisterOrderThreadRoutes(app *fiber.App, h *handler.OrderThreadHandler) {
+	thread := app.Group("/order/:id/messages")
+
+	thread.Get("/", middleware.AuthRequired(), h.GetThread)
+	thread.Post("/", middleware.AuthRequired(), h.PostMessage)
+	thread.Post("/read", middleware.AuthRequired(), h.MarkRead)
+	thread.Get("/attachments/:attachmentId", middleware.AuthRequired(), h.DownloadAttachment)
+
+	// Заказы с непрочитанными сообщениями: сотруднику — от покупателей, покупателю — от магазина
+	app.Get("/message/threads/unread", middleware.AuthRequired(), h.GetUnreadThreads)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"Market_backend/internal/config"
+	mail "Market_backend/internal/mail/service"
+	"Market_backend/internal/messages/dto"
+	"Market_backend/internal/messages/repository"
+	"Market_backend/internal/storage"
+	"Market_backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxAttachments      = 5
+	MaxAttachmentSize   = 10 << 20 // 10 МБ
+	maxOrderMessageText = 4000
+)
+
+var (
+	ErrEmptyMessage       = errors.New("сообщение не может быть пустым")
+	ErrMessageTooLong     = fmt.Errorf("сообщение длиннее %d символов", maxOrderMessageText)
+	ErrTooManyAttachments = fmt.Errorf("не более %d вложений", MaxAttachments)
+	ErrAttachmentTooLarge = errors.New("вложение больше 10 МБ")
+)
+
+// OrderThreadService — переписка по заказу между покупателем и сотрудниками магазина
+type OrderThreadService struct {
+	repo       *repository.OrderThreadRepository
+	storage    *storage.MinioStorage
+	mailSender *mail.MailService
+}
+
+func NewOrderThreadService(repo *repository.OrderThreadRepository, storage *storage.MinioStorage) *OrderThreadService {
+	return &OrderThreadService{
+		repo:       repo,
+		storage:    storage,
+		mailSender: mail.NewMailService(),
+	}
+}
+
+// GetThread — сообщения по заказу и число непрочитанных от другой стороны
+func (s *OrderThreadService) GetThread(userId, orderId uuid.UUID, isAdmin bool) ([]models.OrderMessage, int64, error) {
+	if _, err := s.order(userId, orderId, isAdmin); err != nil {
+		return nil, 0, err
+	}
+
+	messages, err := s.repo.GetMessages(orderId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Сотрудник ждёт сообщений покупателя, покупатель — сотрудников
+	unread, err := s.repo.CountUnread(orderId, userId, !isAdmin)
+	if err != nil {
+		return nil, 0, err
+	}
+	return messages, unread, nil
+}
+
+// PostMessage добавляет сообщение с вложениями и уведомляет другую сторону по почте
+func (s *OrderThreadService) PostMessage(userId, orderId uuid.UUID, isAdmin bool, msgDto dto.PostOrderMessageDTO) (*models.OrderMessage, error) {
+	text := strings.TrimSpace(msgDto.Text)
+	if text == "" && len(msgDto.Files) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if len([]rune(text)) > maxOrderMessageText {
+		return nil, ErrMessageTooLong
+	}
+	if len(msgDto.Files) > MaxAttachments {
+		return nil, ErrTooManyAttachments
+	}
+	for _, file := range msgDto.Files {
+		if file.Size > MaxAttachmentSize {
+			return nil, ErrAttachmentTooLarge
+		}
+	}
+
+	order, err := s.order(userId, orderId, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &models.OrderMessage{
+		ID:        uuid.New(),
+		OrderID:   orderId,
+		AuthorID:  userId,
+		FromStaff: isAdmin,
+		Text:      text,
+		CreatedAt: time.Now(),
+	}
+
+	for _, file := range msgDto.Files {
+		attachment, err := s.uploadAttachment(msg, file)
+		if err != nil {
+			s.removeAttachments(msg.Attachments)
+			return nil, err
+		}
+		msg.Attachments = append(msg.Attachments, *attachment)
+	}
+
+	if err := s.repo.CreateMessage(msg); err != nil {
+		s.removeAttachments(msg.Attachments)
+		return nil, err
+	}
+
+	// Своё сообщение автор уже прочитал
+	if err := s.repo.MarkRead(orderId, userId, msg.CreatedAt); err != nil {
+		log.Printf("order %d thread: mark read: %v", order.OrderNumber, err)
+	}
+
+	if err := s.repo.DB().First(&msg.Author, "id = ?", userId).Error; err != nil {
+		return nil, err
+	}
+	s.notify(order, msg)
+
+	return msg, nil
+}
+
+func (s *OrderThreadService) MarkRead(userId, orderId uuid.UUID, isAdmin bool) error {
+	if _, err := s.order(userId, orderId, isAdmin); err != nil {
+		return err
+	}
+	return s.repo.MarkRead(orderId, userId, time.Now())
+}
+
+// OpenAttachment открывает вложение на чтение; reader закрывает вызывающий
+func (s *OrderThreadService) OpenAttachment(userId, orderId, attachmentId uuid.UUID, isAdmin bool) (*models.OrderMessageAttachment, io.ReadCloser, error) {
+	if _, err := s.order(userId, orderId, isAdmin); err != nil {
+		return nil, nil, err
+	}
+
+	attachment, err := s.repo.GetAttachment(orderId, attachmentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := s.storage.Download(context.Background(), attachment.ObjectKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, reader, nil
+}
+
+// GetUnreadThreads — для сотрудника все заказы с новыми сообщениями покупателей,
+// для покупателя — его заказы с новыми ответами магазина
+func (s *OrderThreadService) GetUnreadThreads(userId uuid.UUID, isAdmin bool) ([]dto.UnreadThread, error) {
+	if isAdmin {
+		return s.repo.GetUnreadThreads(userId, false, nil)
+	}
+	return s.repo.GetUnreadThreads(userId, true, &userId)
+}
+
+// order — заказ, если пользователь может видеть его переписку: покупатель свой, сотрудник любой
+func (s *OrderThreadService) order(userId, orderId uuid.UUID, isAdmin bool) (*models.Order, error) {
+	var order models.Order
+	query := s.repo.DB().Preload("User")
+	if !isAdmin {
+		query = query.Where("user_id = ?", userId)
+	}
+	if err := query.First(&order, "id = ?", orderId).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (s *OrderThreadService) uploadAttachment(msg *models.OrderMessage, fileHeader *multipart.FileHeader) (*models.OrderMessageAttachment, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, MaxAttachmentSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAttachmentSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	contentType := fileHeader.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	attachment := &models.OrderMessageAttachment{
+		ID:          uuid.New(),
+		MessageID:   msg.ID,
+		FileName:    filepath.Base(fileHeader.Filename),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+	}
+	attachment.ObjectKey = fmt.Sprintf("order-messages/%s/%s/%s", msg.OrderID, attachment.ID, attachment.FileName)
+
+	if err := s.storage.UploadBytes(context.Background(), attachment.ObjectKey, data, contentType); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+func (s *OrderThreadService) removeAttachments(attachments []models.OrderMessageAttachment) {
+	for _, attachment := range attachments {
+		if err := s.storage.Delete(context.Background(), attachment.ObjectKey); err != nil {
+			log.Printf("remove attachment %s: %v", attachment.ObjectKey, err)
+		}
+	}
+}
+
+// notify — ответ магазина уходит покупателю, сообщение покупателя — на почту поддержки
+func (s *OrderThreadService) notify(order *models.Order, msg *models.OrderMessage) {
+	to := order.User.Email
+	subject := fmt.Sprintf("Новый ответ по заказу №%d", order.OrderNumber)
+	if !msg.FromStaff {
+		to = config.SupportEmail
+		if to == "" {
+			to = config.SMTPEmail
+		}
+		subject = fmt.Sprintf("Вопрос по заказу №%d", order.OrderNumber)
+	}
+	if to == "" {
+		return
+	}
+
+	text := html.EscapeString(msg.Text)
+	if len(msg.Attachments) > 0 {
+		text += fmt.Sprintf("<br><i>Вложений: %d</i>", len(msg.Attachments))
+	}
+
+	body := fmt.Sprintf(`
+<h1>Market</h1>
+<p>%s пишет по заказу №%d:</p>
+<p>%s</p>
+`, html.EscapeString(msg.Author.Name), order.OrderNumber, strings.ReplaceAll(text, "\n", "<br>"))
+
+	if err := s.mailSender.SendEmail(to, subject, body); err != nil {
+		log.Printf("order %d thread email: %v", order.OrderNumber, err)
+	}
+}
@@ -0,0 +1,123 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/validate"
+	ShippingDto "Market_backend/internal/shipping/dto"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+type AdminOrderItemDTO struct {
+	ProductID   uuid.UUID         `json:"product_id"`
+	ProductType types.ProductType `json:"product_type"`
+	Quantity    int               `json:"quantity"`
+	UnitPrice   *money.Money      `json:"unit_price"` // ручная цена; nil — текущая цена каталога
+}
+
+// GuestCustomerDTO — новый покупатель без аккаунта; аккаунт заводится по email
+type GuestCustomerDTO struct {
+	Email    string `json:"email" validate:"required,email"`
+	Name     string `json:"name" validate:"required"`
+	Surname  string `json:"surname"`
+	LastName string `json:"last_name"`
+	Number   string `json:"number"`
+}
+
+// AdminCreateOrderDTO — заказ, оформленный менеджером за покупателя (например, по телефону).
+// Покупатель задаётся либо UserID, либо Guest.
+type AdminCreateOrderDTO struct {
+	UserID *uuid.UUID        `json:"user_id"`
+	Guest  *GuestCustomerDTO `json:"guest"`
+
+	Items []AdminOrderItemDTO `json:"items"`
+
+	DeliveryMethod types.DeliveryMethod    `json:"delivery_method"`
+	AddressID      *uuid.UUID              `json:"address_id"` // адрес покупателя из адресной книги
+	Address        *ShippingDto.AddressDTO `json:"address"`    // или новый адрес, он сохранится покупателю
+	ShippingCost   *money.Money            `json:"shipping_cost"`
+
+	PaymentMethod types.PaymentMethod `json:"payment_method"`
+	Comment       string              `json:"comment"`
+}
+
+func (d *AdminCreateOrderDTO) Validate() error {
+	if (d.UserID == nil) == (d.Guest == nil) {
+		return errors.New("either user_id or guest is required")
+	}
+	if d.Guest != nil {
+		if err := validate.Validate.Struct(d.Guest); err != nil {
+			return err
+		}
+	}
+
+	if len(d.Items) == 0 {
+		return errors.New("items are required")
+	}
+	for _, item := range d.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("invalid quantity for product %s", item.ProductID)
+		}
+		if item.UnitPrice != nil && item.UnitPrice.IsNegative() {
+			return fmt.Errorf("invalid unit_price for product %s", item.ProductID)
+		}
+	}
+
+	if d.AddressID != nil && d.Address != nil {
+		return errors.New("use either address_id or address")
+	}
+	if d.ShippingCost != nil && d.ShippingCost.IsNegative() {
+		return errors.New("shipping_cost must not be negative")
+	}
+	if !d.PaymentMethod.IsValid() {
+		return fmt.Errorf("unknown payment method: %s", d.PaymentMethod)
+	}
+	return nil
+}
+
+type AdminCreateOrderResponse struct {
+	OrderID     uuid.UUID   `json:"order_id"`
+	OrderNumber int32       `json:"order_number"`
+	UserID      uuid.UUID   `json:"user_id"`
+	Total       money.Money `json:"total"`
+	PaymentURL  string      `json:"payment_url,omitempty"`
+	// Заказ создан, но ссылку на оплату получить не удалось — её можно выслать позже
+	PaymentError string `json:"payment_error,omitempty"`
+}
+
+// EditOrderDTO — новый состав неоплаченного заказа. Позиции, которых нет в списке, удаляются.
+// Без unit_price у существующей позиции сохраняется её цена, у новой берётся цена каталога.
+type EditOrderDTO struct {
+	Items        []AdminOrderItemDTO `json:"items"`
+	ShippingCost *money.Money        `json:"shipping_cost"`
+	Comment      string              `json:"comment"`
+}
+
+func (d *EditOrderDTO) Validate() error {
+	if len(d.Items) == 0 {
+		return errors.New("items are required, cancel the order to remove all of them")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(d.Items))
+	for _, item := range d.Items {
+		if seen[item.ProductID] {
+			return fmt.Errorf("duplicate product %s", item.ProductID)
+		}
+		seen[item.ProductID] = true
+
+		if item.Quantity <= 0 {
+			return fmt.Errorf("invalid quantity for product %s", item.ProductID)
+		}
+		if item.UnitPrice != nil && item.UnitPrice.IsNegative() {
+			return fmt.Errorf("invalid unit_price for product %s", item.ProductID)
+		}
+	}
+
+	if d.ShippingCost != nil && d.ShippingCost.IsNegative() {
+		return errors.New("shipping_cost must not be negative")
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	"github.com/google/uuid"
 )
@@ -9,5 +10,5 @@ type CreateOrderItem struct {
 	ProductID   uuid.UUID         `gorm:"type:uuid;primaryKey"`
 	ProductType types.ProductType `gorm:"type:product_type;not null"`
 	Quantity    int
-	UnitPrice   float64
+	UnitPrice   money.Money
 }
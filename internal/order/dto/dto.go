@@ -1,15 +1,31 @@
 package dto
 
 import (
+	"Market_backend/internal/common/money"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type OrderItemDTO struct {
-	Name     string  `json:"name"`
-	Quantity int     `json:"quantity"`
-	Price    float64 `json:"price"` // UnitPrice
+	Name     string      `json:"name"`
+	Quantity int         `json:"quantity"`
+	Price    money.Money `json:"price"` // UnitPrice
+}
+
+type ShipmentItemDTO struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+type ShipmentDTO struct {
+	ID             uuid.UUID         `json:"id"`
+	Carrier        string            `json:"carrier"`
+	TrackingNumber string            `json:"tracking_number"`
+	Status         string            `json:"status"`
+	ShippedAt      *time.Time        `json:"shipped_at"`
+	DeliveredAt    *time.Time        `json:"delivered_at"`
+	Items          []ShipmentItemDTO `json:"items"`
 }
 
 type OrderDTO struct {
@@ -17,9 +33,21 @@ type OrderDTO struct {
 	CreatedAt   time.Time      `json:"created_at"`
 	Status      string         `json:"status"`
 	OrderNumber int32          `json:"number"`
-	Total       float64        `json:"total"`
+	Total       money.Money    `json:"total"`
 	Items       []OrderItemDTO `json:"items"`
 	Name        string         `json:"name"`
+
+	RefundedAmount  money.Money `json:"refunded_amount"`
+	PaymentDeadline *time.Time  `json:"payment_deadline"` // nil — срок не задан (например, заказ ждёт согласования)
+
+	DeliveryMethod  string      `json:"delivery_method"`
+	ShippingAddress string      `json:"shipping_address"`
+	ShippingCost    money.Money `json:"shipping_cost"`
+
+	PointsSpent    int         `json:"points_spent"`
+	PointsDiscount money.Money `json:"points_discount"`
+
+	Shipments []ShipmentDTO `json:"shipments"`
 }
 
 type OrderAdminDTO struct {
@@ -27,7 +55,7 @@ type OrderAdminDTO struct {
 	CreatedAt   time.Time      `json:"created_at"`
 	Status      string         `json:"status"`
 	OrderNumber int32          `json:"order_number"`
-	Total       float64        `json:"total"`
+	Total       money.Money    `json:"total"`
 	Items       []OrderItemDTO `json:"items"`
 	Name        string         `json:"name"`
 	Surname     string         `json:"surname"`
@@ -35,18 +63,28 @@ type OrderAdminDTO struct {
 	Number      string         `json:"number"`
 	Email       string         `json:"email"`
 	LenItems    int            `json:"len_items"`
+
+	RefundedAmount  money.Money `json:"refunded_amount"`
+	PaymentDeadline *time.Time  `json:"payment_deadline"` // nil — срок не задан (например, заказ ждёт согласования)
+
+	DeliveryMethod  string      `json:"delivery_method"`
+	ShippingAddress string      `json:"shipping_address"`
+	ShippingCost    money.Money `json:"shipping_cost"`
+
+	PointsSpent    int         `json:"points_spent"`
+	PointsDiscount money.Money `json:"points_discount"`
 }
 
 type AllOrdersResponse struct {
-	TotalOrders int        `json:"total_orders"`
-	TotalItems  int        `json:"total_items"`
-	TotalSum    float64    `json:"total_sum"`
-	Orders      []OrderDTO `json:"orders"`
+	TotalOrders int         `json:"total_orders"`
+	TotalItems  int         `json:"total_items"`
+	TotalSum    money.Money `json:"total_sum"`
+	Orders      []OrderDTO  `json:"orders"`
 }
 
 type AllOrdersAdminResponse struct {
 	TotalOrders int             `json:"total_orders"`
 	TotalItems  int             `json:"total_items"`
-	TotalSum    float64         `json:"total_sum"`
+	TotalSum    money.Money     `json:"total_sum"`
 	Orders      []OrderAdminDTO `json:"orders"`
 }
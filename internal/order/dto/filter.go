@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"time"
+)
+
+// OrderFilter — фильтры, сортировка и пагинация админского поиска заказов
+type OrderFilter struct {
+	Statuses    []types.OrderStatus
+	DateFrom    *time.Time
+	DateTo      *time.Time // не включительно
+	Email       string
+	OrderNumber *int32
+	TotalMin    *money.Money
+	TotalMax    *money.Money
+	SKU         string
+
+	Sort string // created_at, total, number, status
+	Desc bool
+
+	Page  int
+	Limit int // 0 — без пагинации (экспорт)
+}
+
+type OrderSearchResponse struct {
+	Orders []OrderAdminDTO `json:"orders"`
+	Total  int64           `json:"total"`
+	Page   int             `json:"page"`
+	Limit  int             `json:"limit"`
+}
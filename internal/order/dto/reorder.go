@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+
+	"github.com/google/uuid"
+)
+
+// Причины, по которым позиция прошлого заказа не попала в корзину целиком
+const (
+	ReorderUnavailable = "unavailable"  // товар снят с продажи
+	ReorderOutOfStock  = "out_of_stock" // товара нет в наличии
+	ReorderPartial     = "partial"      // добавлено меньше, чем было в заказе
+)
+
+type ReorderLineDTO struct {
+	ProductID   uuid.UUID         `json:"product_id"`
+	ProductType types.ProductType `json:"product_type"`
+	Name        string            `json:"name"`
+	Requested   int               `json:"requested"`
+	Added       int               `json:"added"`
+	Price       money.Money       `json:"price,omitempty"` // текущая цена за штуку
+	Reason      string            `json:"reason,omitempty"`
+}
+
+type ReorderResponse struct {
+	CartID  uuid.UUID        `json:"cart_id"`
+	Added   []ReorderLineDTO `json:"added"`
+	Skipped []ReorderLineDTO `json:"skipped"`
+}
@@ -1,14 +1,26 @@
 package handler
 
 import (
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	"Market_backend/internal/common/utils"
+	LoyaltyService "Market_backend/internal/loyalty/service"
 	"Market_backend/internal/order/dto"
 	"Market_backend/internal/order/service"
+	PaymentService "Market_backend/internal/payment/service"
+	ShippingHandler "Market_backend/internal/shipping/handler"
+	ShippingService "Market_backend/internal/shipping/service"
+	"Market_backend/models"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type OrderHandler struct {
@@ -42,8 +54,47 @@ func (h *OrderHandler) CreateOrder(c *fiber.Ctx) error {
 		})
 	}
 
-	orderId, err := h.service.CreateOrder(userId, cartId)
+	delivery, err := ShippingHandler.ParseDeliveryRequest(c)
 	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// company_id — заказ от имени компании пользователя
+	var companyId *uuid.UUID
+	if companyIdStr := c.Query("company_id"); companyIdStr != "" {
+		id, err := uuid.Parse(companyIdStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid company_id",
+			})
+		}
+		companyId = &id
+	}
+
+	// points — бонусные баллы в счёт оплаты товаров
+	points := c.QueryInt("points", 0)
+	if points < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "points must not be negative",
+		})
+	}
+
+	orderId, err := h.service.CreateOrder(userId, cartId, delivery, companyId, points)
+	if err != nil {
+		if errors.Is(err, service.ErrNotCompanyBuyer) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, LoyaltyService.ErrNotEnoughPoints) ||
+			errors.Is(err, LoyaltyService.ErrPointsShareExceeded) ||
+			errors.Is(err, LoyaltyService.ErrPointsRetailOnly) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -109,6 +160,7 @@ func (h *OrderHandler) GetOrders(c *fiber.Ctx) error {
 
 	for _, order := range orders {
 		var itemsDTO []dto.OrderItemDTO
+		itemNames := make(map[uuid.UUID]string, len(order.Items))
 
 		for _, item := range order.Items {
 
@@ -136,11 +188,12 @@ func (h *OrderHandler) GetOrders(c *fiber.Ctx) error {
 				name = "Unknown product"
 			}
 
-			totalPrice := item.UnitPrice * float64(item.Quantity)
+			itemNames[item.ID] = name
+			totalPrice := item.UnitPrice.Mul(item.Quantity)
 
 			response.TotalItems += item.Quantity
 			if order.Status != types.Cancelled {
-				response.TotalSum += totalPrice
+				response.TotalSum = response.TotalSum.Add(totalPrice)
 			}
 
 			itemsDTO = append(itemsDTO, dto.OrderItemDTO{
@@ -150,13 +203,42 @@ func (h *OrderHandler) GetOrders(c *fiber.Ctx) error {
 			})
 		}
 
+		// Трек-номера отгрузок для покупателя
+		shipmentsDTO := make([]dto.ShipmentDTO, 0, len(order.Shipments))
+		for _, sh := range order.Shipments {
+			shItems := make([]dto.ShipmentItemDTO, 0, len(sh.Items))
+			for _, si := range sh.Items {
+				shItems = append(shItems, dto.ShipmentItemDTO{
+					Name:     itemNames[si.OrderItemID],
+					Quantity: si.Quantity,
+				})
+			}
+			shipmentsDTO = append(shipmentsDTO, dto.ShipmentDTO{
+				ID:             sh.ID,
+				Carrier:        sh.Carrier,
+				TrackingNumber: sh.TrackingNumber,
+				Status:         string(sh.Status),
+				ShippedAt:      sh.ShippedAt,
+				DeliveredAt:    sh.DeliveredAt,
+				Items:          shItems,
+			})
+		}
+
 		ordersDTO = append(ordersDTO, dto.OrderDTO{
-			ID:          order.ID,
-			CreatedAt:   order.CreatedAt,
-			Status:      string(order.Status),
-			OrderNumber: order.OrderNumber,
-			Total:       order.Total,
-			Items:       itemsDTO,
+			ID:              order.ID,
+			CreatedAt:       order.CreatedAt,
+			Status:          string(order.Status),
+			OrderNumber:     order.OrderNumber,
+			Total:           order.Total,
+			Items:           itemsDTO,
+			DeliveryMethod:  string(order.DeliveryMethod),
+			ShippingAddress: order.ShippingAddress,
+			ShippingCost:    order.ShippingCost,
+			PointsSpent:     order.PointsSpent,
+			PointsDiscount:  order.PointsDiscount,
+			Shipments:       shipmentsDTO,
+			RefundedAmount:  order.RefundedAmount,
+			PaymentDeadline: order.PaymentDeadline,
 		})
 	}
 
@@ -173,70 +255,205 @@ func (h *OrderHandler) GetAllOrders(c *fiber.Ctx) error {
 		})
 	}
 
+	// Названия товаров одним запросом на тип вместо запроса на каждую позицию
+	names, err := h.service.GetProductNames(orders)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	response := dto.AllOrdersAdminResponse{}
 
 	var ordersDTO []dto.OrderAdminDTO
 
-	for _, order := range orders {
-		var itemsDTO []dto.OrderItemDTO
-		var orderItemsCount int
-		var totalSum float64
+	for i := range orders {
+		orderDTO := toOrderAdminDTO(&orders[i], names)
+		ordersDTO = append(ordersDTO, orderDTO)
 
-		for _, item := range order.Items {
-			var name string
-			switch item.ProductType {
-			case types.Processor:
-				proc, err := h.service.ProcService.GetProcessorById(item.ProductID)
-				if err != nil {
-					name = "Unknown processor"
-				} else {
-					name = proc.Name
-				}
-			case types.FlashDriver:
-				fd, err := h.service.FlashService.GetFlashDriveById(item.ProductID)
-				if err != nil {
-					name = "Unknown flash drive"
-				} else {
-					name = fd.Name
-				}
-			default:
-				name = "Unknown product"
-			}
+		if orders[i].Status == types.Completed {
+			response.TotalOrders += 1
+			response.TotalItems += orderDTO.LenItems
+			response.TotalSum = response.TotalSum.Add(orderDTO.Total)
+		}
+	}
+	response.Orders = ordersDTO
 
-			totalPrice := item.UnitPrice * float64(item.Quantity)
-			orderItemsCount += item.Quantity
-			totalSum += totalPrice
+	return c.Status(fiber.StatusOK).JSON(response)
+}
 
-			itemsDTO = append(itemsDTO, dto.OrderItemDTO{
-				Name:     name,
-				Quantity: item.Quantity,
-				Price:    item.UnitPrice,
+// SearchOrders — поиск заказов для админки с фильтрами, сортировкой и пагинацией
+func (h *OrderHandler) SearchOrders(c *fiber.Ctx) error {
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	orders, total, names, err := h.service.SearchOrders(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	response := dto.OrderSearchResponse{
+		Orders: make([]dto.OrderAdminDTO, 0, len(orders)),
+		Total:  total,
+		Page:   filter.Page,
+		Limit:  filter.Limit,
+	}
+	for i := range orders {
+		response.Orders = append(response.Orders, toOrderAdminDTO(&orders[i], names))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// ExportOrders выгружает отфильтрованные заказы в CSV или XLSX (?format=csv|xlsx)
+func (h *OrderHandler) ExportOrders(c *fiber.Ctx) error {
+	filter, err := parseOrderFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	format := c.Query("format", service.ExportCSV)
+	data, err := h.service.ExportOrders(filter, format)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownExportFormat) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
 			})
 		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
-		ordersDTO = append(ordersDTO, dto.OrderAdminDTO{
-			ID:          order.ID,
-			Name:        order.User.Name, // нужно чтобы User был preloaded
-			Surname:     order.User.Surname,
-			LastName:    order.User.LastName,
-			Number:      order.User.Number,
-			Email:       order.User.Email,
-			Status:      string(order.Status),
-			OrderNumber: order.OrderNumber,
-			Total:       totalSum,
-			CreatedAt:   order.CreatedAt,
-			Items:       itemsDTO,
-			LenItems:    orderItemsCount,
-		})
-		if order.Status == types.Completed {
-			response.TotalOrders += 1
-			response.TotalItems += orderItemsCount
-			response.TotalSum += totalSum
+	contentType := "text/csv; charset=utf-8"
+	if format == service.ExportXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="orders-%s.%s"`, time.Now().Format("2006-01-02"), format))
+	return c.Send(data)
+}
+
+func toOrderAdminDTO(order *models.Order, names map[uuid.UUID]string) dto.OrderAdminDTO {
+	var itemsDTO []dto.OrderItemDTO
+	var orderItemsCount int
+	var totalSum money.Money
+
+	for _, item := range order.Items {
+		name, ok := names[item.ProductID]
+		if !ok {
+			name = "Unknown product"
 		}
+
+		orderItemsCount += item.Quantity
+		totalSum = totalSum.Add(item.UnitPrice.Mul(item.Quantity))
+
+		itemsDTO = append(itemsDTO, dto.OrderItemDTO{
+			Name:     name,
+			Quantity: item.Quantity,
+			Price:    item.UnitPrice,
+		})
 	}
-	response.Orders = ordersDTO
 
-	return c.Status(fiber.StatusOK).JSON(response)
+	return dto.OrderAdminDTO{
+		ID:          order.ID,
+		Name:        order.User.Name, // нужно чтобы User был preloaded
+		Surname:     order.User.Surname,
+		LastName:    order.User.LastName,
+		Number:      order.User.Number,
+		Email:       order.User.Email,
+		Status:      string(order.Status),
+		OrderNumber: order.OrderNumber,
+		Total:       totalSum,
+		CreatedAt:   order.CreatedAt,
+		Items:       itemsDTO,
+		LenItems:    orderItemsCount,
+
+		DeliveryMethod:  string(order.DeliveryMethod),
+		ShippingAddress: order.ShippingAddress,
+		ShippingCost:    order.ShippingCost,
+		PointsSpent:     order.PointsSpent,
+		PointsDiscount:  order.PointsDiscount,
+		RefundedAmount:  order.RefundedAmount,
+		PaymentDeadline: order.PaymentDeadline,
+	}
+}
+
+// parseOrderFilter разбирает query-параметры поиска:
+// status (через запятую), date_from/date_to (YYYY-MM-DD, date_to включительно), email,
+// number, total_min/total_max, sku, sort (created_at|total|number|status), order (asc|desc), page, limit
+func parseOrderFilter(c *fiber.Ctx) (dto.OrderFilter, error) {
+	filter := dto.OrderFilter{
+		Sort:  c.Query("sort", "created_at"),
+		Desc:  c.Query("order", "desc") != "asc",
+		Email: strings.TrimSpace(c.Query("email")),
+		SKU:   strings.TrimSpace(c.Query("sku")),
+		Page:  c.QueryInt("page", 1),
+		Limit: c.QueryInt("limit", 20),
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		return filter, errors.New("limit must be between 1 and 100")
+	}
+
+	if raw := c.Query("status"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			status := types.OrderStatus(strings.TrimSpace(part))
+			if !status.IsValid() {
+				return filter, fmt.Errorf("invalid status: %s", status)
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if raw := c.Query("date_from"); raw != "" {
+		from, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			return filter, errors.New("invalid date_from, expected YYYY-MM-DD")
+		}
+		filter.DateFrom = &from
+	}
+	if raw := c.Query("date_to"); raw != "" {
+		to, err := time.ParseInLocation("2006-01-02", raw, time.Local)
+		if err != nil {
+			return filter, errors.New("invalid date_to, expected YYYY-MM-DD")
+		}
+		to = to.AddDate(0, 0, 1)
+		filter.DateTo = &to
+	}
+
+	if raw := c.Query("number"); raw != "" {
+		number, err := strconv.ParseInt(raw, 10, 32)
+		if err != nil {
+			return filter, errors.New("invalid number")
+		}
+		n := int32(number)
+		filter.OrderNumber = &n
+	}
+
+	for key, target := range map[string]**money.Money{"total_min": &filter.TotalMin, "total_max": &filter.TotalMax} {
+		if raw := c.Query(key); raw != "" {
+			value, err := money.Parse(raw)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", key)
+			}
+			*target = &value
+		}
+	}
+
+	return filter, nil
 }
 
 func (h *OrderHandler) UpdateOrderStatusHandler(c *fiber.Ctx) error {
@@ -257,24 +474,32 @@ func (h *OrderHandler) UpdateOrderStatusHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	var newStatus types.OrderStatus
-	switch newStatusStr {
-	case "in_progress":
-		newStatus = types.InProgress
-	case "paid":
-		newStatus = types.Paid
-	case "completed":
-		newStatus = types.Completed
-	case "cancelled":
-		newStatus = types.Cancelled
-	default:
+	newStatus := types.OrderStatus(newStatusStr)
+	if !newStatus.IsValid() {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "invalid status",
 		})
 	}
 
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Меняем статус
-	if err := h.service.UpdateOrderStatus(orderID, newStatus); err != nil {
+	if err := h.service.UpdateOrderStatus(orderID, newStatus, adminId, c.Query("comment")); err != nil {
+		if errors.Is(err, service.ErrManualStatus) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if errors.Is(err, types.ErrInvalidOrderTransition) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": err.Error(),
 		})
@@ -284,3 +509,278 @@ func (h *OrderHandler) UpdateOrderStatusHandler(c *fiber.Ctx) error {
 		"message": "status updated",
 	})
 }
+
+func (h *OrderHandler) CancelOrder(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid order id",
+		})
+	}
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	refunded, err := h.service.CancelOrderByCustomer(userId, orderId, body.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "order not found",
+			})
+		case errors.Is(err, service.ErrOrderNotCancellable):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "order cancelled",
+		"refunded": refunded,
+	})
+}
+
+// ReorderOrder — повторить прошлый заказ: позиции переносятся в корзину по текущим ценам
+func (h *OrderHandler) ReorderOrder(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid order id",
+		})
+	}
+
+	result, err := h.service.Reorder(userId, orderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "order not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// AdminCreateOrder — заказ, оформленный менеджером за покупателя или нового гостя
+func (h *OrderHandler) AdminCreateOrder(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var body dto.AdminCreateOrderDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	result, err := h.service.AdminCreateOrder(adminId, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, ShippingService.ErrAddressRequired):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// SendPaymentLink — выслать покупателю новую ссылку на оплату заказа
+func (h *OrderHandler) SendPaymentLink(c *fiber.Ctx) error {
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid order id",
+		})
+	}
+
+	var body struct {
+		PaymentMethod types.PaymentMethod `json:"payment_method"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if !body.PaymentMethod.IsValid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "unknown payment method",
+		})
+	}
+
+	url, err := h.service.SendPaymentLink(orderId, body.PaymentMethod)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "order not found",
+			})
+		case errors.Is(err, service.ErrOrderNotPayable), errors.Is(err, PaymentService.ErrPaymentDeadlinePassed),
+			errors.Is(err, PaymentService.ErrOrderNotAwaitingPayment), errors.Is(err, PaymentService.ErrCashOnDeliveryUnavailable):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"payment_url": url,
+	})
+}
+
+// EditOrder — изменение состава и цен неоплаченного заказа
+func (h *OrderHandler) EditOrder(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid order id",
+		})
+	}
+
+	var body dto.EditOrderDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	order, err := h.service.EditOrder(adminId, orderId, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, service.ErrOrderNotEditable):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	names, err := h.service.GetProductNames([]models.Order{*order})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(toOrderAdminDTO(order, names))
+}
+
+// SetPaymentDeadline — продление срока оплаты неоплаченного заказа
+func (h *OrderHandler) SetPaymentDeadline(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	orderId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid order id",
+		})
+	}
+
+	var body struct {
+		PaymentDeadline time.Time `json:"payment_deadline"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	order, err := h.service.SetPaymentDeadline(adminId, orderId, body.PaymentDeadline)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "order not found",
+			})
+		case errors.Is(err, service.ErrInvalidPaymentDeadline):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, service.ErrOrderNotPayable):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id":               order.ID,
+		"status":           order.Status,
+		"payment_deadline": order.PaymentDeadline,
+	})
+}
@@ -3,11 +3,16 @@ package repository
 import (
 	"Market_backend/internal/cart/dto"
 	"Market_backend/internal/common"
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
+	orderDto "Market_backend/internal/order/dto"
 	"Market_backend/models"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrderRepository struct {
@@ -22,7 +27,7 @@ func (r *OrderRepository) DB() *gorm.DB {
 	return r.db
 }
 
-func (r *OrderRepository) CreateOrder(userId uuid.UUID, status types.OrderStatus, total float64) (uuid.UUID, error) {
+func (r *OrderRepository) CreateOrder(userId uuid.UUID, status types.OrderStatus, total money.Money) (uuid.UUID, error) {
 	var orderId uuid.UUID = uuid.New()
 	if err := r.db.Create(&models.Order{ID: orderId, UserID: userId, Status: status, Total: total}).Error; err != nil {
 		return orderId, err
@@ -30,22 +35,143 @@ func (r *OrderRepository) CreateOrder(userId uuid.UUID, status types.OrderStatus
 	return orderId, nil
 }
 
-func (r *OrderRepository) CreateOrderTx(tx *gorm.DB, userId uuid.UUID, status types.OrderStatus, total float64) (uuid.UUID, error) {
-	var orderId uuid.UUID = uuid.New()
-	if err := tx.Create(&models.Order{ID: orderId, UserID: userId, Status: status, Total: total}).Error; err != nil {
-		return orderId, err
+func (r *OrderRepository) CreateOrderTx(tx *gorm.DB, order *models.Order) (uuid.UUID, error) {
+	if order.ID == uuid.Nil {
+		order.ID = uuid.New()
 	}
-	return orderId, nil
+	if err := tx.Create(order).Error; err != nil {
+		return order.ID, err
+	}
+	return order.ID, nil
 }
 
 func (r *OrderRepository) GetOrderById(orderId, userId uuid.UUID) (*models.Order, error) {
 	var order models.Order
-	if err := r.db.Preload("Items").Where("id = ? AND user_id = ?", orderId, userId).First(&order).Error; err != nil {
+	if err := r.db.
+		Preload("Items").
+		Preload("History", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Where("id = ? AND user_id = ?", orderId, userId).
+		First(&order).Error; err != nil {
 		return nil, err
 	}
 	return &order, nil
 }
 
+// Допустимые поля сортировки админского поиска
+var orderSortColumns = map[string]string{
+	"created_at": "orders.created_at",
+	"total":      "orders.total",
+	"number":     "orders.order_number",
+	"status":     "orders.status",
+}
+
+// SearchOrders — поиск заказов для админки. Возвращает страницу и общее число найденных.
+func (r *OrderRepository) SearchOrders(filter orderDto.OrderFilter) ([]models.Order, int64, error) {
+	query := r.db.Model(&models.Order{})
+
+	if len(filter.Statuses) > 0 {
+		query = query.Where("orders.status IN ?", filter.Statuses)
+	}
+	if filter.DateFrom != nil {
+		query = query.Where("orders.created_at >= ?", *filter.DateFrom)
+	}
+	if filter.DateTo != nil {
+		query = query.Where("orders.created_at < ?", *filter.DateTo)
+	}
+	if filter.Email != "" {
+		query = query.
+			Joins("JOIN users ON users.id = orders.user_id").
+			Where(`users.email ILIKE ? ESCAPE '\'`, "%"+escapeLike(filter.Email)+"%")
+	}
+	if filter.OrderNumber != nil {
+		query = query.Where("orders.order_number = ?", *filter.OrderNumber)
+	}
+	if filter.TotalMin != nil {
+		query = query.Where("orders.total >= ?", *filter.TotalMin)
+	}
+	if filter.TotalMax != nil {
+		query = query.Where("orders.total <= ?", *filter.TotalMax)
+	}
+	if filter.SKU != "" {
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM order_items oi
+			LEFT JOIN processors p ON p.id = oi.product_id AND oi.product_type = ?
+			LEFT JOIN flash_drives fd ON fd.id = oi.product_id AND oi.product_type = ?
+			WHERE oi.order_id = orders.id AND (p.sku = ? OR fd.sku = ?)
+		)`, types.Processor, types.FlashDriver, filter.SKU, filter.SKU)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := orderSortColumns[filter.Sort]
+	if !ok {
+		column = orderSortColumns["created_at"]
+	}
+	query = query.Order(clause.OrderByColumn{Column: clause.Column{Name: column, Raw: true}, Desc: filter.Desc})
+
+	if filter.Limit > 0 {
+		page := filter.Page
+		if page < 1 {
+			page = 1
+		}
+		query = query.Offset((page - 1) * filter.Limit).Limit(filter.Limit)
+	}
+
+	var orders []models.Order
+	err := query.
+		Preload("Items").
+		Preload("User").
+		Find(&orders).Error
+
+	return orders, total, err
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы строка искалась буквально
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// GetProductNames одним запросом на тип товара достаёт названия позиций (id товара -> название)
+func (r *OrderRepository) GetProductNames(items []models.OrderItem) (map[uuid.UUID]string, error) {
+	ids := map[types.ProductType][]uuid.UUID{}
+	for _, item := range items {
+		ids[item.ProductType] = append(ids[item.ProductType], item.ProductID)
+	}
+
+	type row struct {
+		ID   uuid.UUID
+		Name string
+	}
+	names := make(map[uuid.UUID]string, len(items))
+
+	for productType, productIds := range ids {
+		var model any
+		switch productType {
+		case types.Processor:
+			model = &models.Processor{}
+		case types.FlashDriver:
+			model = &models.FlashDrive{}
+		default:
+			continue
+		}
+
+		var rows []row
+		if err := r.db.Model(model).Select("id", "name").Where("id IN ?", productIds).Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+		for _, rw := range rows {
+			names[rw.ID] = rw.Name
+		}
+	}
+
+	return names, nil
+}
+
 func (r *OrderRepository) GetAllOrders(userId uuid.UUID) ([]models.Order, error) {
 	var orders []models.Order
 	if err := r.db.Find(&orders, "user_id = ?", userId).Error; err != nil {
@@ -87,9 +213,106 @@ func (r *OrderRepository) CreateOrderItemsTx(tx *gorm.DB, orderId uuid.UUID, cre
 	return nil
 }
 
-func (r *OrderRepository) ChangeStatus(orderId uuid.UUID, status types.OrderStatus) error {
-	if err := r.db.Model(&models.Order{}).Where("id = ?", orderId).Update("status", status).Error; err != nil {
+// ChangeStatus переводит заказ в новый статус по таблице переходов и пишет запись в историю.
+// changedBy == nil означает, что статус изменила система.
+func (r *OrderRepository) ChangeStatus(orderId uuid.UUID, status types.OrderStatus, changedBy *uuid.UUID, comment string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.ChangeStatusTx(tx, orderId, status, changedBy, comment)
+	})
+}
+
+func (r *OrderRepository) ChangeStatusTx(tx *gorm.DB, orderId uuid.UUID, status types.OrderStatus, changedBy *uuid.UUID, comment string) error {
+	var order models.Order
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status").
+		First(&order, "id = ?", orderId).Error; err != nil {
 		return err
 	}
-	return nil
+
+	// Повторная установка того же статуса (например, повторный вебхук) — не ошибка
+	if order.Status == status {
+		return nil
+	}
+
+	if !order.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %s -> %s", types.ErrInvalidOrderTransition, order.Status, status)
+	}
+
+	if err := tx.Model(&models.Order{}).Where("id = ?", orderId).Update("status", status).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.OrderStatusHistory{
+		ID:          uuid.New(),
+		OrderID:     orderId,
+		FromStatus:  order.Status,
+		ToStatus:    status,
+		ChangedByID: changedBy,
+		Comment:     comment,
+	}).Error
+}
+
+// ReturnToApprovalTx возвращает неоплаченный заказ компании на согласование: прежнее согласование
+// и срок оплаты сбрасываются. Обычной таблицей переходов такой переход не разрешён.
+func (r *OrderRepository) ReturnToApprovalTx(tx *gorm.DB, orderId uuid.UUID, changedBy *uuid.UUID, comment string) error {
+	res := tx.Model(&models.Order{}).
+		Where("id = ? AND status = ? AND company_id IS NOT NULL", orderId, types.InProgress).
+		Updates(map[string]any{
+			"status":           types.AwaitingApproval,
+			"approved_by_id":   nil,
+			"approved_at":      nil,
+			"payment_deadline": nil,
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: %s -> %s", types.ErrInvalidOrderTransition, types.InProgress, types.AwaitingApproval)
+	}
+
+	return tx.Create(&models.OrderStatusHistory{
+		ID:          uuid.New(),
+		OrderID:     orderId,
+		FromStatus:  types.InProgress,
+		ToStatus:    types.AwaitingApproval,
+		ChangedByID: changedBy,
+		Comment:     comment,
+	}).Error
+}
+
+// AddNoteTx пишет событие в историю заказа, не меняя его статус
+func (r *OrderRepository) AddNoteTx(tx *gorm.DB, orderId uuid.UUID, changedBy *uuid.UUID, comment string) error {
+	var order models.Order
+	if err := tx.Select("id", "status").First(&order, "id = ?", orderId).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.OrderStatusHistory{
+		ID:          uuid.New(),
+		OrderID:     orderId,
+		FromStatus:  order.Status,
+		ToStatus:    order.Status,
+		ChangedByID: changedBy,
+		Comment:     comment,
+	}).Error
+}
+
+func (r *OrderRepository) GetOrderItemsTx(tx *gorm.DB, orderId uuid.UUID) ([]models.OrderItem, error) {
+	var items []models.OrderItem
+	if err := tx.Where("order_id = ?", orderId).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *OrderRepository) SetStockReservedTx(tx *gorm.DB, orderId uuid.UUID, reserved bool) error {
+	return tx.Model(&models.Order{}).Where("id = ?", orderId).Update("stock_reserved", reserved).Error
+}
+
+func (r *OrderRepository) GetStatusHistory(orderId uuid.UUID) ([]models.OrderStatusHistory, error) {
+	var history []models.OrderStatusHistory
+	if err := r.db.Where("order_id = ?", orderId).Order("created_at ASC").Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return history, nil
 }
@@ -7,14 +7,24 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func RegisterOrderRouter(app *fiber.App, h *handler.OrderHandler) {
+// idempotent — middleware Idempotency-Key для создания заказа
+func RegisterOrderRouter(app *fiber.App, h *handler.OrderHandler, idempotent fiber.Handler) {
 	order := app.Group("/order")
 
-	order.Post("/create", middleware.AuthRequired(), h.CreateOrder)
+	order.Post("/create", middleware.AuthRequired(), idempotent, h.CreateOrder)
 	order.Get("/", middleware.AuthRequired(), h.GetOrderById)
 
+	order.Post("/:id/cancel", middleware.AuthRequired(), h.CancelOrder)
+	order.Post("/:id/reorder", middleware.AuthRequired(), h.ReorderOrder)
+
 	order.Get("/get-all-orders", middleware.AuthRequired(), h.GetOrders)
 	order.Get("/get-for-all-users", middleware.AuthRequired(), middleware.AdminOnly(), h.GetAllOrders)
+	order.Get("/admin/search", middleware.AuthRequired(), middleware.AdminOnly(), h.SearchOrders)
+	order.Get("/admin/export", middleware.AuthRequired(), middleware.AdminOnly(), h.ExportOrders)
+	order.Post("/admin/create", middleware.AuthRequired(), middleware.AdminOnly(), h.AdminCreateOrder)
+	order.Post("/admin/:id/payment-link", middleware.AuthRequired(), middleware.AdminOnly(), h.SendPaymentLink)
+	order.Patch("/admin/:id", middleware.AuthRequired(), middleware.AdminOnly(), h.EditOrder)
+	order.Put("/admin/:id/payment-deadline", middleware.AuthRequired(), middleware.AdminOnly(), h.SetPaymentDeadline)
 
 	order.Post("/update-status", middleware.AuthRequired(), middleware.AdminOnly(), h.UpdateOrderStatusHandler)
 
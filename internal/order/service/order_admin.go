@@ -0,0 +1,275 @@
+package service
+
+import (
+	CartDto "Market_backend/internal/cart/dto"
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/order/dto"
+	PaymentService "Market_backend/internal/payment/service"
+	ShippingDto "Market_backend/internal/shipping/dto"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrOrderNotPayable = errors.New("заказ нельзя оплатить в текущем статусе")
+
+// AdminCreateOrder оформляет заказ за покупателя: позиции из каталога с возможной ручной ценой,
+// доставка и способ оплаты выбираются менеджером. Для гостя заводится аккаунт по email.
+// После создания покупателю уходит письмо со ссылкой на оплату.
+func (s *OrderService) AdminCreateOrder(adminId uuid.UUID, orderDto dto.AdminCreateOrderDTO) (*dto.AdminCreateOrderResponse, error) {
+	if err := orderDto.Validate(); err != nil {
+		return nil, err
+	}
+
+	var order *models.Order
+	var customer *models.User
+
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		customer, err = s.resolveCustomerTx(tx, orderDto)
+		if err != nil {
+			return err
+		}
+
+		items, itemsTotal, err := s.adminOrderItemsTx(tx, orderDto.Items)
+		if err != nil {
+			return err
+		}
+
+		delivery := ShippingDto.DeliveryRequest{Method: orderDto.DeliveryMethod, AddressID: orderDto.AddressID}
+		if orderDto.Address != nil {
+			address, err := s.shippingService.CreateAddressTx(tx, customer.ID, *orderDto.Address)
+			if err != nil {
+				return err
+			}
+			delivery.AddressID = &address.ID
+		}
+
+		parcelItems := make([]ShippingDto.ParcelItem, 0, len(items))
+		for _, item := range items {
+			parcelItems = append(parcelItems, ShippingDto.ParcelItem{
+				ProductID:   item.ProductId,
+				ProductType: item.ProductType,
+				Quantity:    item.Quantity,
+			})
+		}
+		shippingCost, address, err := s.shippingService.QuoteTx(tx, customer.ID, delivery, parcelItems)
+		if err != nil {
+			return err
+		}
+		if orderDto.ShippingCost != nil {
+			shippingCost = *orderDto.ShippingCost
+		}
+
+		deadline := PaymentDeadline(time.Now())
+		order = &models.Order{
+			UserID:         customer.ID,
+			Status:         types.InProgress,
+			Total:          itemsTotal.Add(shippingCost),
+			DeliveryMethod: delivery.Method,
+			ShippingCost:   shippingCost,
+			CreatedByID:    &adminId,
+
+			PaymentDeadline: &deadline,
+		}
+		if address != nil {
+			order.AddressID = &address.ID
+			order.ShippingAddress = address.String()
+		}
+
+		orderId, err := s.repo.CreateOrderTx(tx, order)
+		if err != nil {
+			return err
+		}
+		if err = s.repo.CreateOrderItemsTx(tx, orderId, items); err != nil {
+			return err
+		}
+
+		orderItems, err := s.repo.GetOrderItemsTx(tx, orderId)
+		if err != nil {
+			return err
+		}
+		if err = s.inventoryService.ReserveOrderTx(tx, orderId, orderItems); err != nil {
+			return err
+		}
+		if err = s.repo.SetStockReservedTx(tx, orderId, true); err != nil {
+			return err
+		}
+
+		comment := "заказ оформлен менеджером"
+		if orderDto.Comment != "" {
+			comment += ": " + orderDto.Comment
+		}
+		return s.repo.AddNoteTx(tx, orderId, &adminId, comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// номер заказа выдаёт база
+	if err := s.repo.DB().Select("order_number").First(order, "id = ?", order.ID).Error; err != nil {
+		return nil, err
+	}
+
+	result := &dto.AdminCreateOrderResponse{
+		OrderID:     order.ID,
+		OrderNumber: order.OrderNumber,
+		UserID:      customer.ID,
+		Total:       order.Total,
+	}
+
+	// Заказ уже создан: сбой платёжной системы не откатывает его, ссылку можно выслать повторно
+	url, err := s.sendPaymentLink(order, customer, orderDto.PaymentMethod)
+	if err != nil {
+		log.Printf("payment link for order %d: %v", order.OrderNumber, err)
+		result.PaymentError = err.Error()
+	}
+	result.PaymentURL = url
+
+	return result, nil
+}
+
+// SendPaymentLink создаёт новый платёж по неоплаченному заказу и отправляет ссылку покупателю
+func (s *OrderService) SendPaymentLink(orderId uuid.UUID, method types.PaymentMethod) (string, error) {
+	if !method.IsValid() {
+		return "", fmt.Errorf("unknown payment method: %s", method)
+	}
+
+	var order models.Order
+	if err := s.repo.DB().Preload("User").First(&order, "id = ?", orderId).Error; err != nil {
+		return "", err
+	}
+	if order.Status != types.InProgress {
+		return "", ErrOrderNotPayable
+	}
+
+	return s.sendPaymentLink(&order, &order.User, method)
+}
+
+func (s *OrderService) sendPaymentLink(order *models.Order, customer *models.User, method types.PaymentMethod) (string, error) {
+	_, url, err := s.paymentService.CreatePayment(order, method, PaymentService.CreatePaymentOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	// офлайн-оплата: ссылки нет, в письме — как оплатить
+	payLine := fmt.Sprintf(`<a href="%s">Перейти к оплате</a>`, url)
+	switch method {
+	case types.PaymentMethodBankTransfer:
+		payLine = "Оплата по счёту"
+		if order.PaymentDeadline != nil {
+			payLine += " до " + order.PaymentDeadline.Format("02.01.2006")
+		}
+		payLine += ". В назначении платежа укажите номер заказа."
+	case types.PaymentMethodCashOnDelivery:
+		payLine = "Оплата при получении заказа."
+	}
+
+	body := fmt.Sprintf(`
+<h1>Market</h1>
+<p>Здравствуйте, %s!</p>
+<p>Для вас оформлен заказ №%d на сумму %s ₽.</p>
+<p>%s</p>
+`, customer.Name, order.OrderNumber, order.Total.Decimal(), payLine)
+
+	if err := s.mailSender.SendEmail(customer.Email, fmt.Sprintf("Оплата заказа №%d", order.OrderNumber), body); err != nil {
+		log.Printf("payment link email for order %d: %v", order.OrderNumber, err)
+	}
+
+	return url, nil
+}
+
+// resolveCustomerTx — покупатель заказа: существующий пользователь или гость.
+// Гость с уже зарегистрированным email получает заказ в свой аккаунт.
+func (s *OrderService) resolveCustomerTx(tx *gorm.DB, orderDto dto.AdminCreateOrderDTO) (*models.User, error) {
+	var user models.User
+
+	if orderDto.UserID != nil {
+		if err := tx.First(&user, "id = ?", *orderDto.UserID).Error; err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	guest := orderDto.Guest
+	err := tx.First(&user, "email = ?", guest.Email).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	// Аккаунт без пароля: войти покупатель сможет после восстановления пароля
+	user = models.User{
+		ID:       uuid.New(),
+		Email:    guest.Email,
+		Name:     guest.Name,
+		Surname:  guest.Surname,
+		LastName: guest.LastName,
+		Number:   guest.Number,
+		Role:     types.User,
+		CartID:   uuid.New(),
+	}
+	if err := tx.Create(&user).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&models.Cart{ID: user.CartID, UserID: user.ID}).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// adminOrderItemsTx собирает позиции заказа по текущему каталогу; ручная цена заменяет цену каталога
+func (s *OrderService) adminOrderItemsTx(tx *gorm.DB, itemsDto []dto.AdminOrderItemDTO) ([]CartDto.GetCartItemsResponse, money.Money, error) {
+	items := make([]CartDto.GetCartItemsResponse, 0, len(itemsDto))
+	var total money.Money
+
+	for _, itemDto := range itemsDto {
+		var retail, wholesale money.Money
+		var minQty int
+		var name string
+
+		switch itemDto.ProductType {
+		case types.Processor:
+			var proc models.Processor
+			if err := tx.First(&proc, "id = ?", itemDto.ProductID).Error; err != nil {
+				return nil, money.Money{}, fmt.Errorf("processor %s: %w", itemDto.ProductID, err)
+			}
+			retail, wholesale, minQty, name = proc.RetailPrice, proc.WholesalePrice, proc.WholesaleMinQty, proc.Name
+		case types.FlashDriver:
+			var flash models.FlashDrive
+			if err := tx.First(&flash, "id = ?", itemDto.ProductID).Error; err != nil {
+				return nil, money.Money{}, fmt.Errorf("flash drive %s: %w", itemDto.ProductID, err)
+			}
+			retail, wholesale, minQty, name = flash.RetailPrice, flash.WholesalePrice, flash.WholesaleMinQty, flash.Name
+		default:
+			return nil, money.Money{}, fmt.Errorf("unknown product type: %s", itemDto.ProductType)
+		}
+
+		price := retail
+		if itemDto.Quantity >= minQty {
+			price = wholesale
+		}
+		if itemDto.UnitPrice != nil {
+			price = *itemDto.UnitPrice
+		}
+
+		items = append(items, CartDto.GetCartItemsResponse{
+			ProductId:   itemDto.ProductID,
+			ProductType: itemDto.ProductType,
+			Quantity:    itemDto.Quantity,
+			Price:       price,
+			Name:        name,
+		})
+		total = total.Add(price.Mul(itemDto.Quantity))
+	}
+
+	return items, total, nil
+}
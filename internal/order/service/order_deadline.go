@@ -0,0 +1,186 @@
+package service
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/config"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultPaymentDeadline = time.Hour
+
+	// expireBatch — сколько просроченных заказов отменяется за один проход
+	expireBatch = 100
+)
+
+var ErrInvalidPaymentDeadline = errors.New("срок оплаты должен быть в будущем")
+
+// PaymentDeadline — срок оплаты заказа, выставленного к оплате в момент from.
+// Длительность задаётся PAYMENT_DEADLINE_MINUTES, по умолчанию час.
+func PaymentDeadline(from time.Time) time.Time {
+	if m := utils.ParseInt(config.PaymentDeadlineMinutes); m > 0 {
+		return from.Add(time.Duration(m) * time.Minute)
+	}
+	return from.Add(DefaultPaymentDeadline)
+}
+
+// CancelExpiredOrders отменяет заказы, не оплаченные до срока: ожидающие платежи аннулируются у нас,
+// резерв остатков снимается, покупатель получает письмо. Возвращает число отменённых заказов.
+func (s *OrderService) CancelExpiredOrders() (int, error) {
+	var ids []uuid.UUID
+	if err := s.repo.DB().
+		Model(&models.Order{}).
+		Where("status = ? AND payment_deadline < ?", types.InProgress, time.Now()).
+		Where("NOT EXISTS (SELECT 1 FROM payments WHERE payments.order_id = orders.id AND payments.status = ?)",
+			types.PaymentStatusWaitingForCapture).
+		Order("payment_deadline ASC").
+		Limit(expireBatch).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	cancelled := 0
+	for _, id := range ids {
+		expired, err := s.expire(id)
+		if err != nil {
+			log.Printf("payment deadline: order %s: %v", id, err)
+			continue
+		}
+		if expired {
+			cancelled++
+		}
+	}
+
+	return cancelled, nil
+}
+
+// expire отменяет заказ, если он всё ещё не оплачен и срок истёк.
+// Если шлюз сообщает, что оплата прошла, заказ становится оплаченным и не отменяется.
+// Неоплаченный платёж ЮKassa отменить не даёт — он истекает у шлюза сам. Если покупатель
+// успеет оплатить по старой ссылке, деньги вернутся при обработке уведомления.
+func (s *OrderService) expire(orderId uuid.UUID) (bool, error) {
+	// Шлюз спрашиваем до транзакции: запрос не должен держать блокировку заказа
+	paid, err := s.paymentService.SyncPendingPayments(orderId)
+	if err != nil || paid {
+		return false, err
+	}
+
+	var order models.Order
+	expired := false
+
+	err = s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&order, "id = ?", orderId).Error; err != nil {
+			return err
+		}
+		if order.Status != types.InProgress || order.PaymentDeadline == nil || time.Now().Before(*order.PaymentDeadline) {
+			return nil
+		}
+
+		// деньги заблокированы — заказ ждёт отгрузки, а не оплаты
+		held, err := s.paymentService.HasHoldTx(tx, order.ID)
+		if err != nil || held {
+			return err
+		}
+
+		if _, err := s.paymentService.InvalidatePendingTx(tx, order.ID); err != nil {
+			return err
+		}
+
+		if _, err := s.cancelTx(tx, &order, nil, "заказ не оплачен в срок"); err != nil {
+			return err
+		}
+		expired = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if expired {
+		s.paymentService.SettleOrder(order.ID)
+		s.sendExpiredEmail(&order)
+	}
+	return expired, nil
+}
+
+// StartPaymentDeadlineWatcher периодически отменяет заказы с истёкшим сроком оплаты
+func (s *OrderService) StartPaymentDeadlineWatcher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			cancelled, err := s.CancelExpiredOrders()
+			if err != nil {
+				log.Printf("payment deadline: %v", err)
+				continue
+			}
+			if cancelled > 0 {
+				log.Printf("payment deadline: cancelled %d unpaid orders", cancelled)
+			}
+		}
+	}()
+}
+
+// SetPaymentDeadline меняет срок оплаты неоплаченного заказа (например, по просьбе покупателя)
+func (s *OrderService) SetPaymentDeadline(adminId, orderId uuid.UUID, deadline time.Time) (*models.Order, error) {
+	if !deadline.After(time.Now()) {
+		return nil, ErrInvalidPaymentDeadline
+	}
+
+	var order models.Order
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&order, "id = ?", orderId).Error; err != nil {
+			return err
+		}
+		if order.Status != types.InProgress && order.Status != types.AwaitingApproval {
+			return ErrOrderNotPayable
+		}
+
+		if err := tx.Model(&models.Order{}).
+			Where("id = ?", orderId).
+			Update("payment_deadline", deadline).Error; err != nil {
+			return err
+		}
+		order.PaymentDeadline = &deadline
+
+		return s.repo.AddNoteTx(tx, orderId, &adminId, "срок оплаты до "+deadline.Format("02.01.2006 15:04"))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (s *OrderService) sendExpiredEmail(order *models.Order) {
+	var user models.User
+	if err := s.repo.DB().First(&user, "id = ?", order.UserID).Error; err != nil {
+		log.Printf("expired order email: user %s not found: %v", order.UserID, err)
+		return
+	}
+
+	body := fmt.Sprintf(`
+<h1>Market</h1>
+<p>Здравствуйте, %s!</p>
+<p>Заказ №%d на сумму %s ₽ не был оплачен вовремя и отменён.</p>
+<p>Если товары ещё нужны, оформите заказ заново — его можно повторить из истории заказов.</p>
+`, user.Name, order.OrderNumber, order.Total.Decimal())
+
+	if err := s.mailSender.SendEmail(user.Email, fmt.Sprintf("Заказ №%d отменён: истёк срок оплаты", order.OrderNumber), body); err != nil {
+		log.Printf("expired order email for order %d: %v", order.OrderNumber, err)
+	}
+}
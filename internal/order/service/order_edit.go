@@ -0,0 +1,206 @@
+package service
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/order/dto"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrOrderNotEditable = errors.New("изменять можно только неоплаченный заказ")
+
+// EditOrder меняет состав неоплаченного заказа: пересчитывает сумму, корректирует резерв остатков,
+// пишет изменение в историю и аннулирует ожидающие платежи — покупатель оплатит новую сумму.
+// Заказ компании, сумма которого теперь выше лимита участника, снова уходит на согласование.
+func (s *OrderService) EditOrder(adminId, orderId uuid.UUID, editDto dto.EditOrderDTO) (*models.Order, error) {
+	if err := editDto.Validate(); err != nil {
+		return nil, err
+	}
+
+	var approval *models.Order
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Preload("Items").
+			First(&order, "id = ?", orderId).Error; err != nil {
+			return err
+		}
+		if order.Status != types.InProgress && order.Status != types.AwaitingApproval {
+			return ErrOrderNotEditable
+		}
+
+		lines, _, err := s.adminOrderItemsTx(tx, editDto.Items)
+		if err != nil {
+			return err
+		}
+
+		current := make(map[uuid.UUID]models.OrderItem, len(order.Items))
+		for _, item := range order.Items {
+			current[item.ProductID] = item
+		}
+
+		var reserve, release []models.OrderItem
+		var changes []string
+		var itemsTotal money.Money
+		kept := make(map[uuid.UUID]bool, len(lines))
+
+		for i, line := range lines {
+			kept[line.ProductId] = true
+
+			existing, ok := current[line.ProductId]
+			if !ok {
+				if err := tx.Create(&models.OrderItem{
+					ID:          uuid.New(),
+					OrderID:     orderId,
+					ProductID:   line.ProductId,
+					ProductType: line.ProductType,
+					Quantity:    line.Quantity,
+					UnitPrice:   line.Price,
+				}).Error; err != nil {
+					return err
+				}
+				reserve = append(reserve, models.OrderItem{ProductID: line.ProductId, ProductType: line.ProductType, Quantity: line.Quantity})
+				changes = append(changes, fmt.Sprintf("+ %s × %d по %s", line.Name, line.Quantity, line.Price.Decimal()))
+				itemsTotal = itemsTotal.Add(line.Price.Mul(line.Quantity))
+				continue
+			}
+
+			price := existing.UnitPrice
+			if editDto.Items[i].UnitPrice != nil {
+				price = line.Price
+			}
+			itemsTotal = itemsTotal.Add(price.Mul(line.Quantity))
+
+			if existing.Quantity == line.Quantity && existing.UnitPrice.Equal(price) {
+				continue
+			}
+
+			if err := tx.Model(&models.OrderItem{}).Where("id = ?", existing.ID).Updates(map[string]any{
+				"quantity":   line.Quantity,
+				"unit_price": price,
+			}).Error; err != nil {
+				return err
+			}
+
+			delta := line.Quantity - existing.Quantity
+			switch {
+			case delta > 0:
+				reserve = append(reserve, models.OrderItem{ProductID: existing.ProductID, ProductType: existing.ProductType, Quantity: delta})
+			case delta < 0:
+				release = append(release, models.OrderItem{ProductID: existing.ProductID, ProductType: existing.ProductType, Quantity: -delta})
+			}
+			changes = append(changes, fmt.Sprintf("%s: %d × %s → %d × %s", line.Name, existing.Quantity, existing.UnitPrice.Decimal(), line.Quantity, price.Decimal()))
+		}
+
+		names, err := s.repo.GetProductNames(order.Items)
+		if err != nil {
+			return err
+		}
+		for _, item := range order.Items {
+			if kept[item.ProductID] {
+				continue
+			}
+			if err := tx.Delete(&models.OrderItem{}, "id = ?", item.ID).Error; err != nil {
+				return err
+			}
+			release = append(release, item)
+			changes = append(changes, fmt.Sprintf("− %s × %d", names[item.ProductID], item.Quantity))
+		}
+
+		// Сначала возвращаем остатки, затем резервируем — замена позиций не упирается в склад
+		if order.StockReserved {
+			if err := s.inventoryService.ReleaseOrderTx(tx, orderId, release); err != nil {
+				return err
+			}
+			if err := s.inventoryService.ReserveOrderTx(tx, orderId, reserve); err != nil {
+				return err
+			}
+		}
+
+		shippingCost := order.ShippingCost
+		if editDto.ShippingCost != nil && !editDto.ShippingCost.Equal(shippingCost) {
+			changes = append(changes, fmt.Sprintf("доставка %s → %s", shippingCost.Decimal(), editDto.ShippingCost.Decimal()))
+			shippingCost = *editDto.ShippingCost
+		}
+		// скидка баллами не может превышать новую стоимость товаров
+		total := itemsTotal.Sub(order.PointsDiscount.Min(itemsTotal)).Add(shippingCost)
+
+		if len(changes) == 0 {
+			return nil
+		}
+		if !total.Equal(order.Total) {
+			changes = append(changes, fmt.Sprintf("сумма %s → %s", order.Total.Decimal(), total.Decimal()))
+		}
+
+		if err := tx.Model(&models.Order{}).Where("id = ?", orderId).Updates(map[string]any{
+			"total":         total,
+			"shipping_cost": shippingCost,
+		}).Error; err != nil {
+			return err
+		}
+
+		if _, err := s.paymentService.InvalidatePendingTx(tx, orderId); err != nil {
+			return err
+		}
+		if _, err := s.paymentService.VoidOrderHoldTx(tx, orderId, &adminId, "заказ изменён: блокировка оплаты снята"); err != nil {
+			return err
+		}
+
+		comment := "заказ изменён: " + strings.Join(changes, "; ")
+		if editDto.Comment != "" {
+			comment += ". " + editDto.Comment
+		}
+		if err := s.repo.AddNoteTx(tx, orderId, &adminId, comment); err != nil {
+			return err
+		}
+
+		// Согласование выдавалось на прежнюю сумму: та же проверка лимита, что и при оформлении
+		if order.CompanyID == nil || order.Status != types.InProgress {
+			return nil
+		}
+		exceeded := true // участник вышел из компании — согласование нужно в любом случае
+		member, err := s.companyRepo.GetMemberTx(tx, *order.CompanyID, order.UserID)
+		if err == nil {
+			exceeded = exceedsApprovalLimit(member, total)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if !exceeded {
+			return nil
+		}
+		if err := s.repo.ReturnToApprovalTx(tx, orderId, &adminId, "сумма заказа превышает лимит участника, нужно повторное согласование"); err != nil {
+			return err
+		}
+		order.Total = total
+		approval = &order
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.paymentService.SettleOrder(orderId)
+	if approval != nil {
+		s.sendApprovalRequestEmails(*approval.CompanyID, orderId, approval.Total)
+	}
+
+	var order models.Order
+	if err := s.repo.DB().
+		Preload("User").
+		Preload("Items").
+		Preload("History", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		First(&order, "id = ?", orderId).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"Market_backend/internal/order/dto"
+	"Market_backend/models"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+var ErrUnknownExportFormat = errors.New("unknown export format")
+
+var exportHeader = []string{
+	"Номер", "Дата", "Статус", "Покупатель", "Email", "Телефон",
+	"Товары", "Кол-во", "Доставка", "Адрес", "Стоимость доставки", "Сумма",
+}
+
+// ExportOrders выгружает все заказы под фильтр (без пагинации) в CSV или XLSX
+func (s *OrderService) ExportOrders(filter dto.OrderFilter, format string) ([]byte, error) {
+	filter.Limit = 0
+
+	orders, _, names, err := s.SearchOrders(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([][]any, 0, len(orders))
+	for i := range orders {
+		rows = append(rows, exportRow(&orders[i], names))
+	}
+
+	switch format {
+	case ExportCSV:
+		return writeCSV(rows)
+	case ExportXLSX:
+		return writeXLSX(rows)
+	}
+	return nil, ErrUnknownExportFormat
+}
+
+func exportRow(order *models.Order, names map[uuid.UUID]string) []any {
+	items := make([]string, 0, len(order.Items))
+	quantity := 0
+	for _, item := range order.Items {
+		items = append(items, fmt.Sprintf("%s × %d", names[item.ProductID], item.Quantity))
+		quantity += item.Quantity
+	}
+
+	customer := strings.TrimSpace(strings.Join([]string{order.User.Surname, order.User.Name, order.User.LastName}, " "))
+
+	return []any{
+		order.OrderNumber,
+		order.CreatedAt.Format("2006-01-02 15:04"),
+		string(order.Status),
+		exportText(customer),
+		exportText(order.User.Email),
+		exportText(order.User.Number),
+		exportText(strings.Join(items, "; ")),
+		quantity,
+		string(order.DeliveryMethod),
+		exportText(order.ShippingAddress),
+		order.ShippingCost.Float64(),
+		order.Total.Float64(),
+	}
+}
+
+// exportText экранирует пользовательский текст для ячейки: значение, начинающееся с =, +, -, @
+// (или табуляции и перевода строки), Excel иначе выполнит как формулу
+func exportText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeCSV пишет CSV с разделителем «;» и BOM — так его корректно открывает Excel с русской локалью
+func writeCSV(rows [][]any) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\uFEFF")
+
+	w := csv.NewWriter(&buf)
+	w.Comma = ';'
+
+	if err := w.Write(exportHeader); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			if f, ok := v.(float64); ok {
+				record[i] = strings.Replace(fmt.Sprintf("%.2f", f), ".", ",", 1)
+			} else {
+				record[i] = fmt.Sprint(v)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeXLSX(rows [][]any) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet = "Заказы"
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	header := make([]any, len(exportHeader))
+	for i, h := range exportHeader {
+		header[i] = h
+	}
+	if err := f.SetSheetRow(sheet, "A1", &header); err != nil {
+		return nil, err
+	}
+
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return nil, err
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
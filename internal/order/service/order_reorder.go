@@ -0,0 +1,133 @@
+package service
+
+import (
+	CartDto "Market_backend/internal/cart/dto"
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/order/dto"
+	"Market_backend/models"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// reorderProduct — то, что нужно о товаре для повтора заказа
+type reorderProduct struct {
+	name            string
+	stock           int
+	retailPrice     money.Money
+	wholesalePrice  money.Money
+	wholesaleMinQty int
+}
+
+// Reorder переносит позиции прошлого заказа в корзину пользователя по текущим ценам.
+// Снятые с продажи товары и товары без остатка пропускаются, при нехватке
+// остатка добавляется сколько есть с учётом того, что уже лежит в корзине.
+func (s *OrderService) Reorder(userId, orderId uuid.UUID) (*dto.ReorderResponse, error) {
+	order, err := s.repo.GetOrderById(orderId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := s.repo.DB().Select("id", "cart_id").First(&user, "id = ?", userId).Error; err != nil {
+		return nil, err
+	}
+
+	result := &dto.ReorderResponse{
+		CartID:  user.CartID,
+		Added:   []dto.ReorderLineDTO{},
+		Skipped: []dto.ReorderLineDTO{},
+	}
+
+	for _, item := range order.Items {
+		line := dto.ReorderLineDTO{
+			ProductID:   item.ProductID,
+			ProductType: item.ProductType,
+			Requested:   item.Quantity,
+		}
+
+		product, err := s.reorderProduct(item.ProductType, item.ProductID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			line.Reason = dto.ReorderUnavailable
+			result.Skipped = append(result.Skipped, line)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		line.Name = product.name
+
+		var inCart int
+		if err := s.repo.DB().
+			Model(&models.CartItem{}).
+			Select("COALESCE(SUM(quantity), 0)").
+			Where("cart_id = ? AND product_id = ? AND product_type = ?", user.CartID, item.ProductID, item.ProductType).
+			Scan(&inCart).Error; err != nil {
+			return nil, err
+		}
+
+		available := product.stock - inCart
+		if available <= 0 {
+			line.Reason = dto.ReorderOutOfStock
+			result.Skipped = append(result.Skipped, line)
+			continue
+		}
+
+		quantity := item.Quantity
+		if quantity > available {
+			quantity = available
+			line.Reason = dto.ReorderPartial
+		}
+
+		if _, err := s.cartService.AddNewItem(CartDto.CartItemDto{
+			CartID:      user.CartID,
+			ProductId:   item.ProductID,
+			ProductType: item.ProductType,
+			Quantity:    quantity,
+		}); err != nil {
+			return nil, err
+		}
+
+		line.Added = quantity
+		line.Price = product.retailPrice
+		if quantity >= product.wholesaleMinQty {
+			line.Price = product.wholesalePrice
+		}
+		result.Added = append(result.Added, line)
+	}
+
+	return result, nil
+}
+
+func (s *OrderService) reorderProduct(productType types.ProductType, productId uuid.UUID) (*reorderProduct, error) {
+	switch productType {
+	case types.Processor:
+		proc, err := s.ProcService.GetProcessorById(productId)
+		if err != nil {
+			return nil, err
+		}
+		return &reorderProduct{
+			name:            proc.Name,
+			stock:           proc.Stock,
+			retailPrice:     proc.RetailPrice,
+			wholesalePrice:  proc.WholesalePrice,
+			wholesaleMinQty: proc.WholesaleMinQty,
+		}, nil
+	case types.FlashDriver:
+		flash, err := s.FlashService.GetFlashDriveById(productId)
+		if err != nil {
+			return nil, err
+		}
+		return &reorderProduct{
+			name:            flash.Name,
+			stock:           flash.Stock,
+			retailPrice:     flash.RetailPrice,
+			wholesalePrice:  flash.WholesalePrice,
+			wholesaleMinQty: flash.WholesaleMinQty,
+		}, nil
+	}
+	// неизвестный тип товара считаем снятым с продажи
+	return nil, gorm.ErrRecordNotFound
+}
@@ -3,19 +3,45 @@ package service
 import (
 	CartRepository "Market_backend/internal/cart/repository"
 	CartService "Market_backend/internal/cart/service"
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
+	CompanyRepository "Market_backend/internal/company/repository"
+	InventoryService "Market_backend/internal/inventory/service"
+	LoyaltyService "Market_backend/internal/loyalty/service"
+	"Market_backend/internal/mail/service"
+	"Market_backend/internal/order/dto"
 	"Market_backend/internal/order/repository"
+	PaymentService "Market_backend/internal/payment/service"
 	"Market_backend/internal/product/service"
+	ShippingDto "Market_backend/internal/shipping/dto"
+	ShippingService "Market_backend/internal/shipping/service"
 	"Market_backend/models"
+	"errors"
 	"fmt"
+	"log"
+	"time"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderNotCancellable = errors.New("заказ уже отправлен или закрыт, отмена невозможна")
+	ErrNotCompanyBuyer     = errors.New("вы не можете оформлять заказы от имени этой компании")
+	ErrManualStatus        = errors.New("статус нельзя установить вручную")
 )
 
 type OrderService struct {
-	repo        *repository.OrderRepository
-	cartRepo    *CartRepository.CartRepository
-	cartService *CartService.CartService
+	repo             *repository.OrderRepository
+	cartRepo         *CartRepository.CartRepository
+	cartService      *CartService.CartService
+	inventoryService *InventoryService.InventoryService
+	paymentService   *PaymentService.PaymentService
+	shippingService  *ShippingService.ShippingService
+	companyRepo      *CompanyRepository.CompanyRepository
+	loyaltyService   *LoyaltyService.LoyaltyService
+	mailSender       *mail.MailService
 
 	ProcService  *service.ProcessorService
 	FlashService *service.FlashDriveService
@@ -27,12 +53,33 @@ func NewOrderService(
 	cartService *CartService.CartService,
 	procS *service.ProcessorService,
 	flashS *service.FlashDriveService,
+	inventoryS *InventoryService.InventoryService,
+	paymentS *PaymentService.PaymentService,
+	shippingS *ShippingService.ShippingService,
+	companyRepo *CompanyRepository.CompanyRepository,
+	loyaltyS *LoyaltyService.LoyaltyService,
 ) *OrderService {
-	return &OrderService{repo: repo, cartRepo: cartRepo, cartService: cartService, ProcService: procS, FlashService: flashS}
+	return &OrderService{
+		repo:             repo,
+		cartRepo:         cartRepo,
+		cartService:      cartService,
+		inventoryService: inventoryS,
+		paymentService:   paymentS,
+		shippingService:  shippingS,
+		companyRepo:      companyRepo,
+		loyaltyService:   loyaltyS,
+		mailSender:       mail.NewMailService(),
+		ProcService:      procS,
+		FlashService:     flashS,
+	}
 }
 
-func (s *OrderService) CreateOrder(userId, cartId uuid.UUID) (uuid.UUID, error) {
+// CreateOrder оформляет заказ из корзины. companyId != nil — заказ от имени компании:
+// если сумма выше лимита участника, заказ ждёт согласования и до него не оплачивается.
+// points — бонусные баллы, которыми покупатель оплачивает часть стоимости товаров.
+func (s *OrderService) CreateOrder(userId, cartId uuid.UUID, delivery ShippingDto.DeliveryRequest, companyId *uuid.UUID, points int) (uuid.UUID, error) {
 	var orderId uuid.UUID
+	var order *models.Order
 
 	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
 		totalBalance, err := s.cartService.ValidateCartTx(tx, userId, cartId)
@@ -46,7 +93,58 @@ func (s *OrderService) CreateOrder(userId, cartId uuid.UUID) (uuid.UUID, error)
 			return err
 		}
 
-		orderId, err = s.repo.CreateOrderTx(tx, userId, types.InProgress, totalBalance)
+		// Стоимость доставки добавляется к сумме заказа
+		parcelItems := make([]ShippingDto.ParcelItem, 0, len(items))
+		for _, item := range items {
+			parcelItems = append(parcelItems, ShippingDto.ParcelItem{
+				ProductID:   item.ProductId,
+				ProductType: item.ProductType,
+				Quantity:    item.Quantity,
+			})
+		}
+		shippingCost, address, err := s.shippingService.QuoteTx(tx, userId, delivery, parcelItems)
+		if err != nil {
+			return err
+		}
+
+		if points > 0 && companyId != nil {
+			return LoyaltyService.ErrPointsRetailOnly
+		}
+		discount, err := s.loyaltyService.DiscountTx(tx, userId, points, totalBalance)
+		if err != nil {
+			return err
+		}
+
+		deadline := PaymentDeadline(time.Now())
+		order = &models.Order{
+			UserID:          userId,
+			Status:          types.InProgress,
+			Total:           totalBalance.Add(shippingCost).Sub(discount),
+			DeliveryMethod:  delivery.Method,
+			ShippingCost:    shippingCost,
+			PointsSpent:     points,
+			PointsDiscount:  discount,
+			PaymentDeadline: &deadline,
+		}
+		if address != nil {
+			order.AddressID = &address.ID
+			order.ShippingAddress = address.String()
+		}
+
+		if companyId != nil {
+			member, err := s.companyRepo.GetMemberTx(tx, *companyId, userId)
+			if err != nil || !member.Role.CanPlaceOrders() {
+				return ErrNotCompanyBuyer
+			}
+			order.CompanyID = companyId
+			if exceedsApprovalLimit(member, order.Total) {
+				order.Status = types.AwaitingApproval
+				// срок оплаты отсчитывается после согласования
+				order.PaymentDeadline = nil
+			}
+		}
+
+		orderId, err = s.repo.CreateOrderTx(tx, order)
 		if err != nil {
 			return err
 		}
@@ -54,6 +152,21 @@ func (s *OrderService) CreateOrder(userId, cartId uuid.UUID) (uuid.UUID, error)
 		if err = s.repo.CreateOrderItemsTx(tx, orderId, items); err != nil {
 			return err
 		}
+		if err = s.loyaltyService.SpendTx(tx, orderId); err != nil {
+			return err
+		}
+
+		// Резервируем остатки под заказ
+		orderItems, err := s.repo.GetOrderItemsTx(tx, orderId)
+		if err != nil {
+			return err
+		}
+		if err = s.inventoryService.ReserveOrderTx(tx, orderId, orderItems); err != nil {
+			return err
+		}
+		if err = s.repo.SetStockReservedTx(tx, orderId, true); err != nil {
+			return err
+		}
 
 		if err = s.cartRepo.ClearCartTx(tx, userId, cartId); err != nil {
 			return err
@@ -65,15 +178,150 @@ func (s *OrderService) CreateOrder(userId, cartId uuid.UUID) (uuid.UUID, error)
 		return uuid.Nil, err
 	}
 
+	if order.Status == types.AwaitingApproval {
+		s.sendApprovalRequestEmails(*order.CompanyID, orderId, order.Total)
+	}
+
 	return orderId, nil
 }
 
-func (s *OrderService) ChangeOrderStatus(orderId uuid.UUID, status types.OrderStatus) error {
-	return s.repo.ChangeStatus(orderId, status)
+func (s *OrderService) ChangeOrderStatus(orderId uuid.UUID, status types.OrderStatus, changedBy *uuid.UUID, comment string) error {
+	return s.repo.ChangeStatus(orderId, status, changedBy, comment)
+}
+
+// CancelOrder отменяет заказ от имени администратора или системы
+func (s *OrderService) CancelOrder(orderId uuid.UUID, changedBy *uuid.UUID, comment string) (bool, error) {
+	return s.cancel(orderId, nil, changedBy, comment)
 }
 
-func (s *OrderService) CancelOrder(orderId uuid.UUID) error {
-	return s.repo.ChangeStatus(orderId, types.Cancelled)
+// CancelOrderByCustomer отменяет собственный неотправленный заказ покупателя
+func (s *OrderService) CancelOrderByCustomer(userId, orderId uuid.UUID, reason string) (bool, error) {
+	return s.cancel(orderId, &userId, &userId, reason)
+}
+
+// cancel переводит заказ в cancelled, возвращает остатки на склад
+// и делает возврат по успешному платежу. ownerId != nil — проверяем владельца заказа.
+func (s *OrderService) cancel(orderId uuid.UUID, ownerId, changedBy *uuid.UUID, comment string) (bool, error) {
+	var order models.Order
+	var refunded bool
+
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+		if ownerId != nil {
+			query = query.Where("user_id = ?", *ownerId)
+		}
+		if err := query.First(&order, "id = ?", orderId).Error; err != nil {
+			return err
+		}
+
+		var err error
+		refunded, err = s.cancelTx(tx, &order, changedBy, comment)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	s.paymentService.SettleOrder(order.ID)
+	s.sendCancellationEmail(order.UserID, order.OrderNumber, refunded)
+
+	return refunded, nil
+}
+
+// cancelTx отменяет заблокированный заказ: снимает резерв остатков и возвращает деньги.
+// Возврат и снятие блокировки у шлюза вызывающий отправляет после фиксации (SettleOrder).
+func (s *OrderService) cancelTx(tx *gorm.DB, order *models.Order, changedBy *uuid.UUID, comment string) (bool, error) {
+	if !order.Status.CanTransitionTo(types.Cancelled) {
+		return false, ErrOrderNotCancellable
+	}
+
+	if err := s.repo.ChangeStatusTx(tx, order.ID, types.Cancelled, changedBy, comment); err != nil {
+		return false, err
+	}
+
+	if order.StockReserved {
+		items, err := s.repo.GetOrderItemsTx(tx, order.ID)
+		if err != nil {
+			return false, err
+		}
+		if err := s.inventoryService.ReleaseOrderTx(tx, order.ID, items); err != nil {
+			return false, err
+		}
+		if err := s.repo.SetStockReservedTx(tx, order.ID, false); err != nil {
+			return false, err
+		}
+	}
+
+	// Часть заказа с блокировкой могла быть оплачена подарочной картой или балансом — её тоже возвращаем
+	if err := s.loyaltyService.RestoreSpentTx(tx, order.ID); err != nil {
+		return false, err
+	}
+
+	voided, err := s.paymentService.VoidOrderHoldTx(tx, order.ID, changedBy, "заказ отменён: блокировка оплаты снята")
+	if err != nil {
+		return false, err
+	}
+	refunded, err := s.paymentService.RefundOrderPaymentTx(tx, order.ID, money.Money{}, "Отмена заказа")
+	return voided || refunded.IsPositive(), err
+}
+
+func (s *OrderService) sendCancellationEmail(userId uuid.UUID, orderNumber int32, refunded bool) {
+	var user models.User
+	if err := s.repo.DB().First(&user, "id = ?", userId).Error; err != nil {
+		log.Printf("cancellation email: user %s not found: %v", userId, err)
+		return
+	}
+
+	refundText := ""
+	if refunded {
+		refundText = "<p>Деньги будут возвращены на карту в течение нескольких дней.</p>"
+	}
+
+	body := fmt.Sprintf(`
+<h1>Market</h1>
+<p>Здравствуйте, %s!</p>
+<p>Ваш заказ №%d отменён.</p>
+%s
+`, user.Name, orderNumber, refundText)
+
+	if err := s.mailSender.SendEmail(user.Email, fmt.Sprintf("Заказ №%d отменён", orderNumber), body); err != nil {
+		log.Printf("cancellation email for order %d: %v", orderNumber, err)
+	}
+}
+
+// exceedsApprovalLimit — заказ участника на сумму total требует согласования
+func exceedsApprovalLimit(member *models.CompanyMember, total money.Money) bool {
+	return member.ApprovalLimit != nil && member.ApprovalLimit.Less(total)
+}
+
+func (s *OrderService) sendApprovalRequestEmails(companyId, orderId uuid.UUID, total money.Money) {
+	var order models.Order
+	if err := s.repo.DB().Preload("User").First(&order, "id = ?", orderId).Error; err != nil {
+		log.Printf("approval email: order %s not found: %v", orderId, err)
+		return
+	}
+
+	var approvers []models.CompanyMember
+	if err := s.repo.DB().
+		Preload("User").
+		Where("company_id = ? AND role = ? AND user_id <> ?", companyId, types.CompanyApprover, order.UserID).
+		Find(&approvers).Error; err != nil {
+		log.Printf("approval email: approvers of company %s: %v", companyId, err)
+		return
+	}
+
+	for _, approver := range approvers {
+		body := fmt.Sprintf(`
+<h1>Market</h1>
+<p>Здравствуйте, %s!</p>
+<p>%s %s оформил заказ №%d на сумму %s ₽, который превышает его лимит.</p>
+<p>Заказ ждёт вашего согласования.</p>
+`, approver.User.Name, order.User.Name, order.User.Surname, order.OrderNumber, total.Decimal())
+
+		if err := s.mailSender.SendEmail(approver.User.Email, fmt.Sprintf("Заказ №%d ждёт согласования", order.OrderNumber), body); err != nil {
+			log.Printf("approval email for order %d: %v", order.OrderNumber, err)
+		}
+	}
 }
 
 func (s *OrderService) GetOrderById(userId, orderId uuid.UUID) (*models.Order, error) {
@@ -89,12 +337,20 @@ func (s *OrderService) GetAllUserOrders(userId uuid.UUID) ([]models.Order, error
 
 	err := s.repo.DB().
 		Where("user_id = ? AND status IN ?", userId, []string{
+			string(types.AwaitingApproval),
 			string(types.InProgress),
 			string(types.Completed),
 			string(types.Paid),
+			string(types.Shipped),
+			string(types.Delivered),
 			string(types.Cancelled),
+			string(types.Refunded),
 		}).
 		Preload("Items").
+		Preload("Shipments", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Shipments.Items").
 		Order("created_at DESC").
 		Find(&orders).Error
 
@@ -112,39 +368,86 @@ func (s *OrderService) GetAllOrders() ([]models.Order, error) {
 	return orders, err
 }
 
-func (s *OrderService) UpdateOrderStatus(orderId uuid.UUID, newStatus types.OrderStatus) error {
-	var order models.Order
+// UpdateOrderStatus — ручная смена статуса администратором. Оплаченным и возвращённым заказ
+// делают только платежи и возвраты: без них статус разошёлся бы с деньгами.
+func (s *OrderService) UpdateOrderStatus(orderId uuid.UUID, newStatus types.OrderStatus, changedBy uuid.UUID, comment string) error {
+	if newStatus == types.Paid || newStatus == types.Refunded {
+		return fmt.Errorf("%w: статус %s ставится только оплатой или возвратом", ErrManualStatus, newStatus)
+	}
 
-	// Находим заказ
-	if err := s.repo.DB().
-		Preload("Items").
-		First(&order, "id = ?", orderId).Error; err != nil {
-		return fmt.Errorf("order not found: %w", err)
-	}
-
-	// Обновляем статус
-	order.Status = newStatus
-
-	// Если нужно вычитать остатки со склада при оплате
-	if newStatus == types.Completed {
-		for _, item := range order.Items {
-			switch item.ProductType {
-			case types.Processor:
-				if err := s.ProcService.DB().UpdateStock(item.ProductID, -item.Quantity); err != nil {
-					return fmt.Errorf("cannot update processor stock: %w", err)
-				}
-			case types.FlashDriver:
-				if err := s.FlashService.DB().UpdateStock(item.ProductID, -item.Quantity); err != nil {
-					return fmt.Errorf("cannot update flash drive stock: %w", err)
-				}
+	// Отмена требует возврата остатков и денег
+	if newStatus == types.Cancelled {
+		_, err := s.CancelOrder(orderId, &changedBy, comment)
+		return err
+	}
+
+	return s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+
+		// Находим заказ
+		if err := tx.
+			Preload("Items").
+			First(&order, "id = ?", orderId).Error; err != nil {
+			return fmt.Errorf("order not found: %w", err)
+		}
+
+		// Неоплаченный заказ отгружается только при оплате при получении
+		if order.Status == types.InProgress && newStatus == types.Shipped {
+			cod, err := s.paymentService.HasCashOnDeliveryTx(tx, orderId)
+			if err != nil {
+				return err
 			}
+			if !cod {
+				return fmt.Errorf("%w: заказ не оплачен", types.ErrInvalidOrderTransition)
+			}
+		}
+
+		// Проверяем переход по таблице статусов и пишем историю
+		if err := s.repo.ChangeStatusTx(tx, orderId, newStatus, &changedBy, comment); err != nil {
+			return err
 		}
+
+		// Заказы, созданные до резервирования, списываем со склада при завершении
+		if newStatus == types.Completed && !order.StockReserved {
+			if err := s.inventoryService.ReserveOrderTx(tx, orderId, order.Items); err != nil {
+				return fmt.Errorf("cannot update stock: %w", err)
+			}
+			if err := s.repo.SetStockReservedTx(tx, orderId, true); err != nil {
+				return err
+			}
+		}
+
+		// Бонусные баллы начисляются за завершённый заказ
+		if newStatus == types.Completed {
+			if err := s.loyaltyService.EarnTx(tx, &order); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// SearchOrders — админский поиск заказов с названиями товаров для позиций
+func (s *OrderService) SearchOrders(filter dto.OrderFilter) ([]models.Order, int64, map[uuid.UUID]string, error) {
+	orders, total, err := s.repo.SearchOrders(filter)
+	if err != nil {
+		return nil, 0, nil, err
 	}
 
-	// Сохраняем изменения
-	if err := s.repo.DB().Save(&order).Error; err != nil {
-		return fmt.Errorf("cannot save order: %w", err)
+	names, err := s.GetProductNames(orders)
+	if err != nil {
+		return nil, 0, nil, err
 	}
 
-	return nil
+	return orders, total, names, nil
+}
+
+// GetProductNames — названия товаров всех позиций заказов (id товара -> название)
+func (s *OrderService) GetProductNames(orders []models.Order) (map[uuid.UUID]string, error) {
+	var items []models.OrderItem
+	for _, order := range orders {
+		items = append(items, order.Items...)
+	}
+	return s.repo.GetProductNames(items)
 }
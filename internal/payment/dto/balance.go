@@ -0,0 +1,107 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IssueGiftCardDTO — выпуск проданной подарочной карты администратором
+type IssueGiftCardDTO struct {
+	Amount    money.Money `json:"amount"`
+	ExpiresAt string      `json:"expires_at"` // YYYY-MM-DD, пусто — бессрочная
+	Comment   string      `json:"comment"`
+}
+
+func (d *IssueGiftCardDTO) Validate() error {
+	if !d.Amount.IsPositive() {
+		return errors.New("amount must be positive")
+	}
+	expiresAt, err := d.Expiry()
+	if err != nil {
+		return errors.New("expires_at must be YYYY-MM-DD")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+// Expiry — конец дня expires_at; nil — карта бессрочная
+func (d *IssueGiftCardDTO) Expiry() (*time.Time, error) {
+	if d.ExpiresAt == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation(documentDateLayout, d.ExpiresAt, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	end := date.AddDate(0, 0, 1).Add(-time.Second)
+	return &end, nil
+}
+
+// AdjustStoreCreditDTO — ручное начисление (Amount > 0) или списание (Amount < 0) баланса покупателя
+type AdjustStoreCreditDTO struct {
+	Amount  money.Money `json:"amount"`
+	Comment string      `json:"comment"`
+}
+
+func (d *AdjustStoreCreditDTO) Validate() error {
+	if d.Amount.IsZero() {
+		return errors.New("amount must not be zero")
+	}
+	if strings.TrimSpace(d.Comment) == "" {
+		return errors.New("comment is required")
+	}
+	return nil
+}
+
+type GiftCardDTO struct {
+	ID            uuid.UUID   `json:"id"`
+	Code          string      `json:"code"`
+	InitialAmount money.Money `json:"initial_amount"`
+	Balance       money.Money `json:"balance"`
+	ExpiresAt     *time.Time  `json:"expires_at"`
+	Disabled      bool        `json:"disabled"`
+	Comment       string      `json:"comment,omitempty"`
+	CreatedAt     time.Time   `json:"created_at"`
+}
+
+type GiftCardsResponse struct {
+	Total int64         `json:"total"`
+	Cards []GiftCardDTO `json:"cards"`
+}
+
+// GiftCardBalanceDTO — проверка подарочной карты покупателем
+type GiftCardBalanceDTO struct {
+	Code      string      `json:"code"`
+	Balance   money.Money `json:"balance"`
+	ExpiresAt *time.Time  `json:"expires_at"`
+	Available bool        `json:"available"` // картой можно оплатить заказ
+}
+
+type BalanceTransactionDTO struct {
+	ID           uuid.UUID           `json:"id"`
+	Amount       money.Money         `json:"amount"`
+	BalanceAfter money.Money         `json:"balance_after"`
+	Reason       types.BalanceReason `json:"reason"`
+	OrderID      *uuid.UUID          `json:"order_id,omitempty"`
+	Comment      string              `json:"comment,omitempty"`
+	CreatedAt    time.Time           `json:"created_at"`
+}
+
+type BalanceTransactionsResponse struct {
+	Total        int64                   `json:"total"`
+	Transactions []BalanceTransactionDTO `json:"transactions"`
+}
+
+// StoreCreditDTO — баланс покупателя и последние движения по нему
+type StoreCreditDTO struct {
+	Balance      money.Money             `json:"balance"`
+	Total        int64                   `json:"total"`
+	Transactions []BalanceTransactionDTO `json:"transactions"`
+}
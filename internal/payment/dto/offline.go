@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"errors"
+	"strings"
+	"time"
+)
+
+const documentDateLayout = "2006-01-02"
+
+// ConfirmOfflinePaymentDTO — подтверждение оплаты по счёту или при получении: реквизиты платёжного документа
+type ConfirmOfflinePaymentDTO struct {
+	DocumentNumber string      `json:"document_number"`
+	DocumentDate   string      `json:"document_date"` // YYYY-MM-DD
+	Amount         money.Money `json:"amount"`        // 0 — сумма платежа; иначе должна с ней совпасть
+}
+
+func (d *ConfirmOfflinePaymentDTO) Validate() error {
+	if strings.TrimSpace(d.DocumentNumber) == "" {
+		return errors.New("document_number is required")
+	}
+	date, err := d.Date()
+	if err != nil {
+		return errors.New("document_date must be YYYY-MM-DD")
+	}
+	if date.After(time.Now()) {
+		return errors.New("document_date must not be in the future")
+	}
+	if d.Amount.IsNegative() {
+		return errors.New("amount must not be negative")
+	}
+	return nil
+}
+
+func (d *ConfirmOfflinePaymentDTO) Date() (time.Time, error) {
+	return time.ParseInLocation(documentDateLayout, d.DocumentDate, time.Local)
+}
@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type PaymentEventDTO struct {
+	ID          uuid.UUID  `json:"id"`
+	Provider    string     `json:"provider"`
+	Event       string     `json:"event"`
+	ObjectID    string     `json:"object_id"`
+	Status      string     `json:"status"`
+	Payload     string     `json:"payload"`
+	Error       string     `json:"error,omitempty"`
+	Deliveries  int        `json:"deliveries"`
+	ReceivedAt  time.Time  `json:"received_at"`
+	ProcessedAt *time.Time `json:"processed_at"`
+}
+
+type PaymentEventsResponse struct {
+	Total  int64             `json:"total"`
+	Events []PaymentEventDTO `json:"events"`
+}
@@ -0,0 +1,65 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type RefundItemDTO struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+// CreateRefundDTO — возврат по заказу: за позиции (Items), на произвольную сумму (Amount)
+// или, если не задано ни то ни другое, весь оплаченный остаток. StoreCredit — зачислить на баланс покупателя
+// вместо возврата в источники оплаты.
+type CreateRefundDTO struct {
+	OrderID     uuid.UUID       `json:"order_id"`
+	Items       []RefundItemDTO `json:"items"`
+	Amount      money.Money     `json:"amount"`
+	Reason      string          `json:"reason"`
+	StoreCredit bool            `json:"store_credit"`
+}
+
+func (d *CreateRefundDTO) Validate() error {
+	if d.OrderID == uuid.Nil {
+		return errors.New("order_id is required")
+	}
+	if len(d.Items) > 0 && d.Amount.IsPositive() {
+		return errors.New("use either items or amount")
+	}
+	if d.Amount.IsNegative() {
+		return errors.New("amount must not be negative")
+	}
+	for _, item := range d.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("invalid quantity for order item %s", item.OrderItemID)
+		}
+	}
+	return nil
+}
+
+type RefundItemResponse struct {
+	OrderItemID uuid.UUID   `json:"order_item_id"`
+	Quantity    int         `json:"quantity"`
+	Amount      money.Money `json:"amount"`
+}
+
+type RefundDTO struct {
+	ID               uuid.UUID            `json:"id"`
+	OrderID          uuid.UUID            `json:"order_id"`
+	PaymentID        uuid.UUID            `json:"payment_id"`
+	ProviderRefundID string               `json:"provider_refund_id"`
+	Status           string               `json:"status"`
+	Amount           money.Money          `json:"amount"`
+	Currency         string               `json:"currency"`
+	Reason           string               `json:"reason"`
+	ReceiptStatus    string               `json:"receipt_status"`
+	ToStoreCredit    bool                 `json:"to_store_credit"`
+	Items            []RefundItemResponse `json:"items"`
+	CreatedAt        time.Time            `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedMethodDTO — сохранённая карта в профиле: только маскированные данные, без токена шлюза
+type SavedMethodDTO struct {
+	ID          uuid.UUID           `json:"id"`
+	Type        types.PaymentMethod `json:"type"`
+	Title       string              `json:"title"`
+	CardType    string              `json:"card_type"`
+	Last4       string              `json:"last4"`
+	ExpiryMonth string              `json:"expiry_month"`
+	ExpiryYear  string              `json:"expiry_year"`
+	CreatedAt   time.Time           `json:"created_at"`
+	LastUsedAt  *time.Time          `json:"last_used_at,omitempty"`
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/payment/dto"
+	"Market_backend/internal/payment/service"
+	"Market_backend/models"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// pagination — page и limit из запроса; limit от 1 до 100
+func pagination(c *fiber.Ctx) (int, int, error) {
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 100 {
+		return 0, 0, errors.New("limit must be between 1 and 100")
+	}
+	return page, limit, nil
+}
+
+// IssueGiftCard — выпуск проданной подарочной карты (админ)
+func (h *PaymentHandler) IssueGiftCard(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var body dto.IssueGiftCardDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	card, err := h.paymentService.IssueGiftCard(adminId, body)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusCreated).JSON(toGiftCardDTO(card))
+}
+
+// GetGiftCards — выпущенные подарочные карты (админ)
+func (h *PaymentHandler) GetGiftCards(c *fiber.Ctx) error {
+	page, limit, err := pagination(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	cards, total, err := h.paymentService.GetGiftCards(page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.GiftCardsResponse{Total: total, Cards: make([]dto.GiftCardDTO, 0, len(cards))}
+	for i := range cards {
+		response.Cards = append(response.Cards, toGiftCardDTO(&cards[i]))
+	}
+	return c.JSON(response)
+}
+
+// GetGiftCardTransactions — журнал движений по подарочной карте (админ)
+func (h *PaymentHandler) GetGiftCardTransactions(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
+	page, limit, err := pagination(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	transactions, total, err := h.paymentService.GetGiftCardTransactions(id, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(dto.BalanceTransactionsResponse{Total: total, Transactions: toBalanceTransactionDTOs(transactions)})
+}
+
+// CheckGiftCard — остаток подарочной карты по коду
+func (h *PaymentHandler) CheckGiftCard(c *fiber.Ctx) error {
+	card, available, err := h.paymentService.CheckGiftCard(c.Params("code"))
+	if err != nil {
+		if errors.Is(err, service.ErrGiftCardNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(dto.GiftCardBalanceDTO{
+		Code:      card.Code,
+		Balance:   card.Balance,
+		ExpiresAt: card.ExpiresAt,
+		Available: available,
+	})
+}
+
+// GetStoreCredit — баланс текущего пользователя и движения по нему
+func (h *PaymentHandler) GetStoreCredit(c *fiber.Ctx) error {
+	userID, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+	page, limit, err := pagination(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	balance, transactions, total, err := h.paymentService.GetStoreCredit(userID, page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(dto.StoreCreditDTO{Balance: balance, Total: total, Transactions: toBalanceTransactionDTOs(transactions)})
+}
+
+// AdjustStoreCredit — начисление или списание баланса покупателя (админ)
+func (h *PaymentHandler) AdjustStoreCredit(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	userID, err := uuid.Parse(c.Params("user_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid user id"})
+	}
+
+	var body dto.AdjustStoreCreditDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	balance, err := h.paymentService.AdjustStoreCredit(adminId, userID, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user not found"})
+		case errors.Is(err, service.ErrInsufficientStoreCredit):
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"balance": balance})
+}
+
+func toGiftCardDTO(card *models.GiftCard) dto.GiftCardDTO {
+	return dto.GiftCardDTO{
+		ID:            card.ID,
+		Code:          card.Code,
+		InitialAmount: card.InitialAmount,
+		Balance:       card.Balance,
+		ExpiresAt:     card.ExpiresAt,
+		Disabled:      card.Disabled,
+		Comment:       card.Comment,
+		CreatedAt:     card.CreatedAt,
+	}
+}
+
+func toBalanceTransactionDTOs(transactions []models.BalanceTransaction) []dto.BalanceTransactionDTO {
+	result := make([]dto.BalanceTransactionDTO, 0, len(transactions))
+	for _, t := range transactions {
+		result = append(result, dto.BalanceTransactionDTO{
+			ID:           t.ID,
+			Amount:       t.Amount,
+			BalanceAfter: t.BalanceAfter,
+			Reason:       t.Reason,
+			OrderID:      t.OrderID,
+			Comment:      t.Comment,
+			CreatedAt:    t.CreatedAt,
+		})
+	}
+	return result
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"Market_backend/internal/payment/provider"
+	"errors"
+	"fmt"
+	"html"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FakePaymentHandler — страница подтверждения fake-провайдера вместо платёжной формы банка
+type FakePaymentHandler struct {
+	provider *provider.FakeProvider
+}
+
+func NewFakePaymentHandler(provider *provider.FakeProvider) *FakePaymentHandler {
+	return &FakePaymentHandler{provider: provider}
+}
+
+const fakePaymentPage = `<!DOCTYPE html>
+<html lang="ru">
+<head><meta charset="utf-8"><title>Тестовая оплата</title></head>
+<body style="font-family: sans-serif; max-width: 480px; margin: 40px auto">
+<h2>Тестовая оплата</h2>
+<p>Платёж: %s</p>
+<p>Сумма: <b>%s %s</b></p>
+<p>Статус: %s</p>
+<form method="post" action="/payments/fake/%s/confirm" style="display:inline"><button type="submit">Оплатить</button></form>
+<form method="post" action="/payments/fake/%s/decline" style="display:inline"><button type="submit">Отказаться</button></form>
+</body>
+</html>`
+
+func (h *FakePaymentHandler) Page(c *fiber.Ctx) error {
+	id := c.Params("id")
+	payment, err := h.provider.GetPayment(c.Context(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).SendString("payment not found")
+	}
+
+	escaped := html.EscapeString(id)
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.SendString(fmt.Sprintf(fakePaymentPage,
+		escaped, payment.Amount.Decimal(), payment.Currency, payment.Status, escaped, escaped))
+}
+
+func (h *FakePaymentHandler) Confirm(c *fiber.Ctx) error {
+	_, err := h.provider.Confirm(c.Params("id"))
+	return h.finish(c, err)
+}
+
+func (h *FakePaymentHandler) Decline(c *fiber.Ctx) error {
+	_, err := h.provider.Decline(c.Params("id"))
+	return h.finish(c, err)
+}
+
+// finish возвращает покупателя на сайт, как это делает настоящий шлюз
+func (h *FakePaymentHandler) finish(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, provider.ErrPaymentNotFound):
+		return c.Status(fiber.StatusNotFound).SendString("payment not found")
+	case errors.Is(err, provider.ErrInvalidPaymentState):
+		return c.Status(fiber.StatusConflict).SendString(err.Error())
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	if url := h.provider.ReturnURL(c.Params("id")); url != "" {
+		return c.Redirect(url, fiber.StatusSeeOther)
+	}
+	return c.Redirect("/payments/fake/"+c.Params("id"), fiber.StatusSeeOther)
+}
@@ -1,18 +1,19 @@
 package handler
 
 import (
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	"Market_backend/internal/common/utils"
 	"Market_backend/internal/order/repository"
+	"Market_backend/internal/payment/dto"
+	"Market_backend/internal/payment/provider"
 	"Market_backend/internal/payment/service"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
+	"Market_backend/models"
+	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
-	"os"
+	"gorm.io/gorm"
 )
 
 type PaymentHandler struct {
@@ -55,61 +56,363 @@ func (h *PaymentHandler) CreatePayment(c *fiber.Ctx) error {
 		paymentMethod = types.PaymentMethodCard
 	case "sbp":
 		paymentMethod = types.PaymentMethodSBP
+	case "bank_transfer":
+		paymentMethod = types.PaymentMethodBankTransfer
+	case "cash":
+		paymentMethod = types.PaymentMethodCashOnDelivery
 	default:
 		return c.Status(400).JSON(fiber.Map{"error": "Unknown payment method"})
 	}
-	payment, confirmationURL, err := h.paymentService.CreatePayment(order, paymentMethod)
+
+	// save=true — сохранить карту для следующих оплат; saved_method_id — оплатить сохранённой картой;
+	// gift_card и store_credit=true — оплатить часть заказа подарочной картой и балансом
+	opts := service.CreatePaymentOptions{
+		SaveMethod:     c.QueryBool("save"),
+		GiftCardCode:   c.Query("gift_card"),
+		UseStoreCredit: c.QueryBool("store_credit"),
+	}
+	if raw := c.Query("saved_method_id"); raw != "" {
+		savedID, err := uuid.Parse(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid saved_method_id"})
+		}
+		opts.SavedMethodID = &savedID
+	}
+
+	payment, confirmationURL, err := h.paymentService.CreatePayment(order, paymentMethod, opts)
 
 	if err != nil {
+		if errors.Is(err, service.ErrOrderAwaitingApproval) ||
+			errors.Is(err, service.ErrPaymentDeadlinePassed) ||
+			errors.Is(err, service.ErrOrderAlreadyHeld) ||
+			errors.Is(err, service.ErrOrderNotAwaitingPayment) ||
+			errors.Is(err, service.ErrCashOnDeliveryUnavailable) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, service.ErrSavedMethodUnsupported) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, service.ErrSavedMethodNotFound) || errors.Is(err, service.ErrGiftCardNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, service.ErrGiftCardUnavailable) || errors.Is(err, service.ErrInsufficientStoreCredit) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
 
+	// для офлайн-оплаты, оплаты сохранённой картой и полной оплаты балансом ссылки нет: результат виден по status
 	return c.JSON(fiber.Map{
 		"payment_id":       payment.ID,
+		"method":           payment.Method,
+		"status":           payment.Status,
 		"confirmation_url": confirmationURL,
 	})
 }
 
-type YooKassaWebhook struct {
-	Event  string `json:"event"`
-	Object struct {
-		ID     string `json:"id"`
-		Status string `json:"status"`
-	} `json:"object"`
-}
+// GetSavedMethods — сохранённые карты текущего пользователя
+func (h *PaymentHandler) GetSavedMethods(c *fiber.Ctx) error {
+	userID, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
 
-func (h *PaymentHandler) Webhook(c *fiber.Ctx) error {
-	secret := os.Getenv("YKASSA_WEBHOOK_SECRET")
-	body := c.Body()
-	signature := c.Get("X-Request-Signature-SHA256")
+	methods, err := h.paymentService.GetSavedMethods(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
 
-	// Проверка подписи
-	if !verifyYooKassaSignature(secret, body, signature) {
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid signature")
+	result := make([]dto.SavedMethodDTO, 0, len(methods))
+	for _, m := range methods {
+		result = append(result, dto.SavedMethodDTO{
+			ID:          m.ID,
+			Type:        m.Type,
+			Title:       m.Title,
+			CardType:    m.CardType,
+			Last4:       m.Last4,
+			ExpiryMonth: m.ExpiryMonth,
+			ExpiryYear:  m.ExpiryYear,
+			CreatedAt:   m.CreatedAt,
+			LastUsedAt:  m.LastUsedAt,
+		})
 	}
+	return c.JSON(result)
+}
 
-	// Парсинг JSON
-	var webhook YooKassaWebhook
-	if err := json.Unmarshal(body, &webhook); err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("Invalid JSON")
+// DeleteSavedMethod — удаление сохранённой карты из профиля
+func (h *PaymentHandler) DeleteSavedMethod(c *fiber.Ctx) error {
+	userID, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	paymentID := webhook.Object.ID
-	status := types.PaymentStatus(webhook.Object.Status) // "succeeded", "canceled", "pending"
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid id"})
+	}
 
-	// Обновление статуса платежа через сервис
-	if err := h.paymentService.UpdatePaymentStatus(paymentID, status); err != nil {
-		fmt.Printf("Failed to update payment status: %v\n", err)
+	if err := h.paymentService.DeleteSavedMethod(userID, id); err != nil {
+		if errors.Is(err, service.ErrSavedMethodNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *PaymentHandler) Webhook(c *fiber.Ctx) error {
+	header := func(key string) string { return c.Get(key) }
+	err := h.paymentService.HandleWebhook(header, c.Body())
+	if errors.Is(err, provider.ErrInvalidSignature) {
+		return c.Status(fiber.StatusBadRequest).SendString("Invalid signature")
+	}
+	if err != nil {
+		fmt.Printf("Failed to handle payment webhook: %v\n", err)
 		return c.Status(fiber.StatusInternalServerError).SendString("Failed to update payment")
 	}
 
 	return c.SendStatus(fiber.StatusOK)
 }
 
-// verifyYooKassaSignature проверяет подпись webhook
-func verifyYooKassaSignature(secret string, body []byte, signature string) bool {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(body)
-	expected := base64.StdEncoding.EncodeToString(mac.Sum(nil))
-	return hmac.Equal([]byte(signature), []byte(expected))
+// CreateRefund — возврат по заказу администратором: за позиции, на сумму или полностью
+func (h *PaymentHandler) CreateRefund(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var body dto.CreateRefundDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	refunds, err := h.paymentService.CreateRefund(adminId, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNothingToRefund):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, service.ErrRefundExceedsPayment), errors.Is(err, service.ErrInvalidRefundItem):
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// заказ, оплаченный из нескольких источников, возвращается несколькими возвратами
+	result := make([]dto.RefundDTO, 0, len(refunds))
+	for i := range refunds {
+		result = append(result, toRefundDTO(&refunds[i]))
+	}
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// GetOrderRefunds — история возвратов по заказу
+func (h *PaymentHandler) GetOrderRefunds(c *fiber.Ctx) error {
+	orderID, err := uuid.Parse(c.Params("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order id"})
+	}
+
+	refunds, err := h.paymentService.GetOrderRefunds(orderID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	result := make([]dto.RefundDTO, 0, len(refunds))
+	for i := range refunds {
+		result = append(result, toRefundDTO(&refunds[i]))
+	}
+	return c.JSON(result)
+}
+
+func toRefundDTO(refund *models.Refund) dto.RefundDTO {
+	items := make([]dto.RefundItemResponse, 0, len(refund.Items))
+	for _, item := range refund.Items {
+		items = append(items, dto.RefundItemResponse{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			Amount:      item.Amount,
+		})
+	}
+
+	return dto.RefundDTO{
+		ID:               refund.ID,
+		OrderID:          refund.OrderID,
+		PaymentID:        refund.PaymentID,
+		ProviderRefundID: refund.ProviderRefundID,
+		Status:           string(refund.Status),
+		Amount:           refund.Amount,
+		Currency:         refund.Currency,
+		Reason:           refund.Reason,
+		ReceiptStatus:    refund.ReceiptStatus,
+		ToStoreCredit:    refund.ToStoreCredit,
+		Items:            items,
+		CreatedAt:        refund.CreatedAt,
+	}
+}
+
+// GetEvents — журнал уведомлений шлюза. Фильтры: status, object_id; пагинация page, limit.
+func (h *PaymentHandler) GetEvents(c *fiber.Ctx) error {
+	status := types.PaymentEventStatus(c.Query("status"))
+	switch status {
+	case "", types.PaymentEventReceived, types.PaymentEventProcessed, types.PaymentEventIgnored, types.PaymentEventRejected:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid status"})
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit must be between 1 and 100"})
+	}
+
+	events, total, err := h.paymentService.GetEvents(status, c.Query("object_id"), page, limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := dto.PaymentEventsResponse{Total: total, Events: make([]dto.PaymentEventDTO, 0, len(events))}
+	for _, event := range events {
+		response.Events = append(response.Events, dto.PaymentEventDTO{
+			ID:          event.ID,
+			Provider:    event.Provider,
+			Event:       event.Event,
+			ObjectID:    event.ObjectID,
+			Status:      string(event.Status),
+			Payload:     event.Payload,
+			Error:       event.Error,
+			Deliveries:  event.Deliveries,
+			ReceivedAt:  event.ReceivedAt,
+			ProcessedAt: event.ProcessedAt,
+		})
+	}
+	return c.JSON(response)
+}
+
+// Reconcile — внеплановая сверка зависших платежей со шлюзом
+func (h *PaymentHandler) Reconcile(c *fiber.Ctx) error {
+	fixed, err := h.paymentService.ReconcilePending(service.DefaultReconcileAfter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"fixed": fixed})
+}
+
+// CapturePayment — списание заблокированных денег: полностью или частично (amount)
+func (h *PaymentHandler) CapturePayment(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	paymentID, err := uuid.Parse(c.Params("payment_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid payment id"})
+	}
+
+	var body struct {
+		Amount money.Money `json:"amount"` // 0 — вся заблокированная сумма
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+		}
+	}
+	if body.Amount.IsNegative() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "amount must not be negative"})
+	}
+
+	payment, err := h.paymentService.CapturePayment(adminId, paymentID, body.Amount)
+	if err != nil {
+		return holdError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"payment_id":  payment.ID,
+		"status":      payment.Status,
+		"amount":      payment.Amount,
+		"held_amount": payment.HeldAmount,
+	})
+}
+
+// VoidPayment — снятие блокировки денег без списания
+func (h *PaymentHandler) VoidPayment(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	paymentID, err := uuid.Parse(c.Params("payment_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid payment id"})
+	}
+
+	payment, err := h.paymentService.VoidPayment(adminId, paymentID)
+	if err != nil {
+		return holdError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"payment_id": payment.ID,
+		"status":     payment.Status,
+	})
+}
+
+// ConfirmOfflinePayment — подтверждение оплаты по счёту или при получении по платёжному документу
+func (h *PaymentHandler) ConfirmOfflinePayment(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	paymentID, err := uuid.Parse(c.Params("payment_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid payment id"})
+	}
+
+	var body dto.ConfirmOfflinePaymentDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid body"})
+	}
+	if err := body.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	payment, err := h.paymentService.ConfirmOfflinePayment(adminId, paymentID, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "payment not found"})
+		case errors.Is(err, service.ErrNotOfflinePayment), errors.Is(err, service.ErrOrderNotAwaitingPayment):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		case errors.Is(err, service.ErrAmountMismatch):
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"payment_id":      payment.ID,
+		"status":          payment.Status,
+		"amount":          payment.Amount,
+		"document_number": payment.DocumentNumber,
+		"document_date":   payment.DocumentDate,
+	})
+}
+
+func holdError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "payment not found"})
+	case errors.Is(err, service.ErrPaymentNotHeld):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, service.ErrCaptureExceedsHold):
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": err.Error()})
 }
@@ -0,0 +1,375 @@
+package provider
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidPaymentState = fmt.Errorf("%w: операция недоступна в текущем статусе платежа", ErrRejected)
+
+// FakeProvider — платёжный шлюз в памяти для локальной разработки и автотестов.
+// Платёж подтверждается на собственной странице /payments/fake/:id, после чего
+// провайдер отправляет подписанный вебхук в формате ЮKassa, как настоящий шлюз.
+type FakeProvider struct {
+	BaseURL       string // адрес приложения, на нём живёт страница подтверждения
+	WebhookURL    string
+	WebhookSecret string
+
+	// Emit доставляет уведомление; по умолчанию — POST на WebhookURL.
+	// В тестах можно подменить и проверять события без HTTP.
+	Emit func(event string, body []byte) error
+
+	mu       sync.Mutex
+	payments map[string]*paymentObject
+	refunds  map[string]*refundObject
+	byKey    map[string]string // ключ идемпотентности -> ID платежа или возврата
+	holds    map[string]bool   // платежи с capture=false
+	saves    map[string]bool   // платежи с save_payment_method=true
+	methods  map[string]*paymentMethodObject
+}
+
+func NewFakeProvider(baseURL, webhookSecret string) *FakeProvider {
+	p := &FakeProvider{
+		BaseURL:       baseURL,
+		WebhookURL:    baseURL + "/payments/webhook",
+		WebhookSecret: webhookSecret,
+		payments:      map[string]*paymentObject{},
+		refunds:       map[string]*refundObject{},
+		byKey:         map[string]string{},
+		holds:         map[string]bool{},
+		saves:         map[string]bool{},
+		methods:       map[string]*paymentMethodObject{},
+	}
+	p.Emit = p.post
+	return p
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*PaymentInfo, error) {
+	p.mu.Lock()
+
+	if id, ok := p.byKey[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		info, err := p.payments[id].info()
+		p.mu.Unlock()
+		return info, err
+	}
+	if err := checkReceipt(req.Receipt, req.Amount); err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+
+	id := "fake-" + uuid.NewString()
+	payment := &paymentObject{
+		ID:     id,
+		Status: string(types.PaymentStatusPending),
+		Amount: newAmount(req.Amount, req.Currency),
+		Confirmation: &confirmation{
+			Type:            "redirect",
+			ReturnURL:       req.ReturnURL,
+			ConfirmationURL: p.BaseURL + "/payments/fake/" + id,
+		},
+		Metadata: req.Metadata,
+	}
+	if req.Receipt != nil {
+		payment.ReceiptRegistration = ReceiptPending
+	}
+
+	// сохранённой картой платёж проходит сразу, без страницы подтверждения
+	if req.PaymentMethodID != "" {
+		method, ok := p.methods[req.PaymentMethodID]
+		if !ok {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("payment method %s not found", req.PaymentMethodID)
+		}
+		saved := *method
+		payment.PaymentMethod = &saved
+		payment.Confirmation = nil
+		payment.Paid = true
+		payment.Status = string(types.PaymentStatusWaitingForCapture)
+		if req.Capture {
+			payment.Status = string(types.PaymentStatusSucceeded)
+			if payment.ReceiptRegistration == ReceiptPending {
+				payment.ReceiptRegistration = ReceiptSucceeded
+			}
+		}
+	}
+
+	p.payments[id] = payment
+	if !req.Capture {
+		p.holds[id] = true
+	}
+	if req.SavePaymentMethod {
+		p.saves[id] = true
+	}
+	if req.IdempotencyKey != "" {
+		p.byKey[req.IdempotencyKey] = id
+	}
+	snapshot := *payment
+	p.mu.Unlock()
+
+	if snapshot.Status != string(types.PaymentStatusPending) {
+		p.notify("payment."+snapshot.Status, &snapshot)
+	}
+	return snapshot.info()
+}
+
+func (p *FakeProvider) GetPayment(ctx context.Context, paymentID string) (*PaymentInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payment, ok := p.payments[paymentID]
+	if !ok {
+		return nil, ErrPaymentNotFound
+	}
+	return payment.info()
+}
+
+func (p *FakeProvider) CapturePayment(ctx context.Context, paymentID string, value money.Money, currency string, receipt *Receipt) (*PaymentInfo, error) {
+	if err := checkReceipt(receipt, value); err != nil {
+		return nil, err
+	}
+
+	return p.transition(paymentID, types.PaymentStatusSucceeded, func(payment *paymentObject) error {
+		if payment.Status != string(types.PaymentStatusWaitingForCapture) {
+			return ErrInvalidPaymentState
+		}
+		if receipt != nil {
+			payment.ReceiptRegistration = ReceiptSucceeded
+		}
+		if value.IsPositive() {
+			payment.Amount = newAmount(value, currency)
+		}
+		return nil
+	})
+}
+
+func (p *FakeProvider) CancelPayment(ctx context.Context, paymentID string) (*PaymentInfo, error) {
+	return p.transition(paymentID, types.PaymentStatusCanceled, func(payment *paymentObject) error {
+		if payment.Status != string(types.PaymentStatusPending) && payment.Status != string(types.PaymentStatusWaitingForCapture) {
+			return ErrInvalidPaymentState
+		}
+		return nil
+	})
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, req RefundRequest) (*RefundInfo, error) {
+	p.mu.Lock()
+
+	if id, ok := p.byKey[req.IdempotencyKey]; ok && req.IdempotencyKey != "" {
+		refund := p.refunds[id]
+		p.mu.Unlock()
+		return refund.info()
+	}
+
+	payment, ok := p.payments[req.PaymentID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, ErrPaymentNotFound
+	}
+	if payment.Status != string(types.PaymentStatusSucceeded) {
+		p.mu.Unlock()
+		return nil, ErrInvalidPaymentState
+	}
+
+	paid, err := payment.Amount.toMoney()
+	if err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+	var refunded money.Money
+	for _, r := range p.refunds {
+		if r.PaymentID == req.PaymentID {
+			value, err := r.Amount.toMoney()
+			if err != nil {
+				p.mu.Unlock()
+				return nil, err
+			}
+			refunded = refunded.Add(value)
+		}
+	}
+	if err := checkReceipt(req.Receipt, req.Amount); err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+	if remaining := paid.Sub(refunded); remaining.Less(req.Amount) {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("%w: refund %s exceeds remaining %s", ErrRejected, req.Amount.Decimal(), remaining.Decimal())
+	}
+
+	refund := &refundObject{
+		ID:          "fake-refund-" + uuid.NewString(),
+		PaymentID:   req.PaymentID,
+		Status:      "succeeded",
+		Amount:      newAmount(req.Amount, req.Currency),
+		Description: req.Description,
+	}
+	if req.Receipt != nil {
+		refund.ReceiptRegistration = ReceiptSucceeded
+	}
+	p.refunds[refund.ID] = refund
+	if req.IdempotencyKey != "" {
+		p.byKey[req.IdempotencyKey] = refund.ID
+	}
+	p.mu.Unlock()
+
+	p.notify("refund.succeeded", refund)
+	return refund.info()
+}
+
+func (p *FakeProvider) ParseWebhook(header func(key string) string, body []byte) (*WebhookEvent, error) {
+	return parseNotification(p.WebhookSecret, header, body)
+}
+
+// Confirm — покупатель оплатил на странице подтверждения.
+// capture=false у платежа оставляет его в waiting_for_capture до списания магазином.
+func (p *FakeProvider) Confirm(paymentID string) (*PaymentInfo, error) {
+	p.mu.Lock()
+	status := types.PaymentStatusSucceeded
+	if p.holds[paymentID] {
+		status = types.PaymentStatusWaitingForCapture
+	}
+	p.mu.Unlock()
+
+	return p.transition(paymentID, status, func(payment *paymentObject) error {
+		if payment.Status != string(types.PaymentStatusPending) {
+			return ErrInvalidPaymentState
+		}
+		payment.Paid = true
+		if p.saves[payment.ID] {
+			payment.PaymentMethod = p.saveCard()
+		}
+		// по двухстадийному платежу чек регистрируется при списании
+		if payment.ReceiptRegistration == ReceiptPending && !p.holds[payment.ID] {
+			payment.ReceiptRegistration = ReceiptSucceeded
+		}
+		return nil
+	})
+}
+
+// saveCard сохраняет тестовую карту для повторных оплат; вызывается под p.mu
+func (p *FakeProvider) saveCard() *paymentMethodObject {
+	method := &paymentMethodObject{
+		Type:  string(types.PaymentMethodCard),
+		ID:    "fake-pm-" + uuid.NewString(),
+		Saved: true,
+		Title: "Bank card *4444",
+		Card: &cardObject{
+			Last4:       "4444",
+			ExpiryMonth: "12",
+			ExpiryYear:  fmt.Sprint(time.Now().Year() + 3),
+			CardType:    "MasterCard",
+		},
+	}
+	p.methods[method.ID] = method
+	return method
+}
+
+// Decline — покупатель отказался от оплаты
+func (p *FakeProvider) Decline(paymentID string) (*PaymentInfo, error) {
+	return p.CancelPayment(context.Background(), paymentID)
+}
+
+// ReturnURL — куда вернуть покупателя со страницы подтверждения
+func (p *FakeProvider) ReturnURL(paymentID string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if payment, ok := p.payments[paymentID]; ok && payment.Confirmation != nil {
+		return payment.Confirmation.ReturnURL
+	}
+	return ""
+}
+
+func (p *FakeProvider) transition(paymentID string, status types.PaymentStatus, check func(payment *paymentObject) error) (*PaymentInfo, error) {
+	p.mu.Lock()
+	payment, ok := p.payments[paymentID]
+	if !ok {
+		p.mu.Unlock()
+		return nil, ErrPaymentNotFound
+	}
+	if err := check(payment); err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+	payment.Status = string(status)
+	snapshot := *payment
+	p.mu.Unlock()
+
+	p.notify("payment."+string(status), &snapshot)
+	return snapshot.info()
+}
+
+// checkReceipt проверяет чек так же, как ЮKassa: сумма позиций равна сумме операции
+func checkReceipt(receipt *Receipt, amount money.Money) error {
+	if receipt == nil {
+		return nil
+	}
+	if len(receipt.Items) == 0 || receipt.CustomerEmail == "" && receipt.CustomerPhone == "" {
+		return fmt.Errorf("receipt: items and customer contact are required")
+	}
+	if !receipt.Total().Equal(amount) {
+		return fmt.Errorf("receipt: items total %s does not match amount %s", receipt.Total().Decimal(), amount.Decimal())
+	}
+	return nil
+}
+
+// notify формирует подписанное уведомление и отдаёт его в Emit
+func (p *FakeProvider) notify(event string, object any) {
+	raw, err := json.Marshal(object)
+	if err != nil {
+		log.Printf("fake payment webhook %s: %v", event, err)
+		return
+	}
+	body, err := json.Marshal(notification{Type: "notification", Event: event, Object: raw})
+	if err != nil {
+		log.Printf("fake payment webhook %s: %v", event, err)
+		return
+	}
+	if err := p.Emit(event, body); err != nil {
+		log.Printf("fake payment webhook %s: %v", event, err)
+	}
+}
+
+// post отправляет вебхук в приложение в фоне, с несколькими попытками — как настоящий шлюз
+func (p *FakeProvider) post(event string, body []byte) error {
+	go func() {
+		client := &http.Client{Timeout: 5 * time.Second}
+		for attempt := 0; attempt < 3; attempt++ {
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * time.Second)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, p.WebhookURL, bytes.NewReader(body))
+			if err != nil {
+				log.Printf("fake payment webhook %s: %v", event, err)
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(SignatureHeader, Sign(p.WebhookSecret, body))
+
+			resp, err := client.Do(req)
+			if err == nil {
+				resp.Body.Close()
+				if resp.StatusCode < 300 {
+					return
+				}
+				err = fmt.Errorf("status %d", resp.StatusCode)
+			}
+			log.Printf("fake payment webhook %s attempt %d: %v", event, attempt+1, err)
+		}
+	}()
+	return nil
+}
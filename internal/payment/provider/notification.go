@@ -0,0 +1,220 @@
+package provider
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SignatureHeader — заголовок с HMAC-SHA256 тела уведомления
+const SignatureHeader = "X-Request-Signature-SHA256"
+
+// amount — сумма в формате ЮKassa: строка с двумя знаками после точки
+type amount struct {
+	Value    string `json:"value"`
+	Currency string `json:"currency"`
+}
+
+func newAmount(value money.Money, currency string) amount {
+	return amount{Value: value.Decimal(), Currency: currency}
+}
+
+func (a amount) toMoney() (money.Money, error) {
+	v, err := money.Parse(a.Value)
+	if err != nil {
+		return money.Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, a.Value)
+	}
+	return v, nil
+}
+
+// paymentObject — платёж в формате API ЮKassa; его же отдаёт fake-провайдер
+type paymentObject struct {
+	ID           string            `json:"id"`
+	Status       string            `json:"status"`
+	Amount       amount            `json:"amount"`
+	Confirmation *confirmation     `json:"confirmation,omitempty"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+	Paid         bool              `json:"paid"`
+
+	PaymentMethod       *paymentMethodObject `json:"payment_method,omitempty"`
+	ReceiptRegistration string               `json:"receipt_registration,omitempty"`
+}
+
+// paymentMethodObject — способ оплаты платежа; saved = true, если шлюз сохранил его для повторных оплат
+type paymentMethodObject struct {
+	Type  string      `json:"type"`
+	ID    string      `json:"id"`
+	Saved bool        `json:"saved"`
+	Title string      `json:"title,omitempty"`
+	Card  *cardObject `json:"card,omitempty"`
+}
+
+// cardObject — маскированные данные карты
+type cardObject struct {
+	Last4       string `json:"last4"`
+	ExpiryMonth string `json:"expiry_month"`
+	ExpiryYear  string `json:"expiry_year"`
+	CardType    string `json:"card_type"`
+}
+
+type confirmation struct {
+	Type            string `json:"type"`
+	ReturnURL       string `json:"return_url,omitempty"`
+	ConfirmationURL string `json:"confirmation_url,omitempty"`
+}
+
+type refundObject struct {
+	ID          string `json:"id"`
+	PaymentID   string `json:"payment_id"`
+	Status      string `json:"status"`
+	Amount      amount `json:"amount"`
+	Description string `json:"description,omitempty"`
+
+	Receipt             *receiptObject `json:"receipt,omitempty"`
+	ReceiptRegistration string         `json:"receipt_registration,omitempty"`
+}
+
+// receiptObject — чек в формате ЮKassa
+type receiptObject struct {
+	Customer      receiptCustomer `json:"customer"`
+	Items         []receiptItem   `json:"items"`
+	TaxSystemCode int             `json:"tax_system_code,omitempty"`
+}
+
+type receiptCustomer struct {
+	Email string `json:"email,omitempty"`
+	Phone string `json:"phone,omitempty"`
+}
+
+type receiptItem struct {
+	Description    string `json:"description"`
+	Quantity       string `json:"quantity"`
+	Amount         amount `json:"amount"`
+	VATCode        int    `json:"vat_code"`
+	PaymentSubject string `json:"payment_subject"`
+	PaymentMode    string `json:"payment_mode"`
+}
+
+// receiptDescriptionLimit — ЮKassa принимает название позиции не длиннее 128 символов
+const receiptDescriptionLimit = 128
+
+func newReceipt(r *Receipt) *receiptObject {
+	if r == nil {
+		return nil
+	}
+
+	obj := &receiptObject{
+		Customer:      receiptCustomer{Email: r.CustomerEmail, Phone: r.CustomerPhone},
+		Items:         make([]receiptItem, 0, len(r.Items)),
+		TaxSystemCode: r.TaxSystemCode,
+	}
+	for _, item := range r.Items {
+		description := []rune(item.Description)
+		if len(description) > receiptDescriptionLimit {
+			description = description[:receiptDescriptionLimit]
+		}
+		obj.Items = append(obj.Items, receiptItem{
+			Description:    string(description),
+			Quantity:       strconv.Itoa(item.Quantity),
+			Amount:         newAmount(item.Price, item.Currency),
+			VATCode:        item.VATCode,
+			PaymentSubject: item.PaymentSubject,
+			PaymentMode:    item.PaymentMode,
+		})
+	}
+	return obj
+}
+
+// notification — уведомление в формате ЮKassa
+type notification struct {
+	Type   string          `json:"type"`
+	Event  string          `json:"event"`
+	Object json.RawMessage `json:"object"`
+}
+
+// info переводит платёж в PaymentInfo; при неразборчивой сумме возвращает и ID платежа, и ошибку
+func (p paymentObject) info() (*PaymentInfo, error) {
+	value, err := p.Amount.toMoney()
+	info := &PaymentInfo{
+		ID:       p.ID,
+		Status:   types.PaymentStatus(p.Status),
+		Amount:   value,
+		Currency: p.Amount.Currency,
+		Metadata: p.Metadata,
+
+		ReceiptStatus: p.ReceiptRegistration,
+	}
+	if p.Confirmation != nil {
+		info.ConfirmationURL = p.Confirmation.ConfirmationURL
+	}
+	if m := p.PaymentMethod; m != nil && m.Saved {
+		info.SavedMethod = &SavedMethod{ID: m.ID, Type: m.Type, Title: m.Title}
+		if m.Card != nil {
+			info.SavedMethod.CardType = m.Card.CardType
+			info.SavedMethod.Last4 = m.Card.Last4
+			info.SavedMethod.ExpiryMonth = m.Card.ExpiryMonth
+			info.SavedMethod.ExpiryYear = m.Card.ExpiryYear
+		}
+	}
+	return info, err
+}
+
+// info переводит возврат в RefundInfo; при неразборчивой сумме возвращает и ID возврата, и ошибку
+func (r refundObject) info() (*RefundInfo, error) {
+	value, err := r.Amount.toMoney()
+	return &RefundInfo{
+		ID:        r.ID,
+		PaymentID: r.PaymentID,
+		Status:    r.Status,
+		Amount:    value,
+		Currency:  r.Amount.Currency,
+
+		ReceiptStatus: r.ReceiptRegistration,
+	}, err
+}
+
+// Sign — подпись тела уведомления секретом вебхука
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseNotification проверяет подпись и разбирает уведомление в формате ЮKassa.
+// Если сумма неразборчива, событие возвращается вместе с ErrInvalidAmount, чтобы его можно было отклонить в журнале.
+func parseNotification(secret string, header func(key string) string, body []byte) (*WebhookEvent, error) {
+	if !hmac.Equal([]byte(header(SignatureHeader)), []byte(Sign(secret, body))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var n notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, fmt.Errorf("invalid webhook body: %w", err)
+	}
+
+	event := &WebhookEvent{Event: n.Event}
+	var err error
+	switch {
+	case strings.HasPrefix(n.Event, "payment."):
+		var obj paymentObject
+		if err := json.Unmarshal(n.Object, &obj); err != nil {
+			return nil, fmt.Errorf("invalid webhook payment: %w", err)
+		}
+		event.Payment, err = obj.info()
+	case strings.HasPrefix(n.Event, "refund."):
+		var obj refundObject
+		if err := json.Unmarshal(n.Object, &obj); err != nil {
+			return nil, fmt.Errorf("invalid webhook refund: %w", err)
+		}
+		event.Refund, err = obj.info()
+	default:
+		return nil, fmt.Errorf("unknown webhook event: %s", n.Event)
+	}
+	return event, err
+}
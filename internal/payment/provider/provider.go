@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrPaymentNotFound  = errors.New("payment not found at provider")
+	// ErrRejected — шлюз отказал в операции; повтор с теми же данными не поможет
+	ErrRejected = errors.New("operation rejected by provider")
+	// ErrInvalidAmount — шлюз прислал сумму, которую нельзя разобрать
+	ErrInvalidAmount = errors.New("invalid amount from provider")
+)
+
+// CreatePaymentRequest — платёж, который нужно создать у провайдера.
+// IdempotencyKey защищает от двойного создания при повторе запроса.
+type CreatePaymentRequest struct {
+	IdempotencyKey string
+	OrderID        uuid.UUID
+	Amount         money.Money
+	Currency       string
+	Method         types.PaymentMethod
+	Description    string
+	ReturnURL      string
+	Metadata       map[string]string
+	// Capture = false — двухстадийная оплата: деньги только блокируются (waiting_for_capture)
+	// и списываются позже через CapturePayment
+	Capture bool
+	Receipt *Receipt // nil — чек не передаётся
+	// SavePaymentMethod — сохранить карту у шлюза для следующих оплат
+	SavePaymentMethod bool
+	// PaymentMethodID — оплата сохранённой картой без перехода на страницу шлюза
+	PaymentMethodID string
+}
+
+// PaymentInfo — состояние платежа у провайдера
+type PaymentInfo struct {
+	ID              string
+	Status          types.PaymentStatus
+	Amount          money.Money
+	Currency        string
+	ConfirmationURL string
+	Metadata        map[string]string
+	ReceiptStatus   string       // регистрация чека: pending, succeeded, canceled; пусто — чека нет
+	SavedMethod     *SavedMethod // способ оплаты, сохранённый шлюзом; nil — не сохранялся
+}
+
+// SavedMethod — сохранённый у шлюза способ оплаты. Кроме токена шлюза известны только маскированные данные карты.
+type SavedMethod struct {
+	ID          string // токен шлюза для повторных оплат
+	Type        string
+	Title       string
+	CardType    string
+	Last4       string
+	ExpiryMonth string
+	ExpiryYear  string
+}
+
+type RefundRequest struct {
+	IdempotencyKey string
+	PaymentID      string // ID платежа у провайдера
+	Amount         money.Money
+	Currency       string
+	Description    string
+	Receipt        *Receipt // чек возврата прихода
+}
+
+type RefundInfo struct {
+	ID            string
+	PaymentID     string
+	Status        string // pending, succeeded, canceled
+	Amount        money.Money
+	Currency      string
+	ReceiptStatus string
+}
+
+// WebhookEvent — разобранное уведомление провайдера: меняется либо платёж, либо возврат
+type WebhookEvent struct {
+	Event   string // payment.succeeded, payment.canceled, refund.succeeded, ...
+	Payment *PaymentInfo
+	Refund  *RefundInfo
+}
+
+// PaymentProvider — платёжный шлюз. Реализации: ЮKassa и локальный fake для разработки и тестов.
+type PaymentProvider interface {
+	Name() string
+	CreatePayment(ctx context.Context, req CreatePaymentRequest) (*PaymentInfo, error)
+	GetPayment(ctx context.Context, paymentID string) (*PaymentInfo, error)
+	// receipt нужен, если списывается не вся заблокированная сумма
+	CapturePayment(ctx context.Context, paymentID string, amount money.Money, currency string, receipt *Receipt) (*PaymentInfo, error)
+	CancelPayment(ctx context.Context, paymentID string) (*PaymentInfo, error)
+	Refund(ctx context.Context, req RefundRequest) (*RefundInfo, error)
+	// ParseWebhook проверяет подлинность уведомления и разбирает его; header — доступ к заголовкам запроса.
+	// Подлинное уведомление с неразборчивой суммой возвращается вместе с ErrInvalidAmount — для журнала.
+	ParseWebhook(header func(key string) string, body []byte) (*WebhookEvent, error)
+}
@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"Market_backend/internal/common/money"
+	"fmt"
+)
+
+// Признаки предмета и способа расчёта по 54-ФЗ в терминах API ЮKassa
+const (
+	PaymentSubjectCommodity = "commodity" // товар
+	PaymentSubjectService   = "service"   // услуга (доставка)
+
+	PaymentModeFullPrepayment = "full_prepayment" // полная предоплата: товар передаётся позже
+	PaymentModeFullPayment    = "full_payment"    // полный расчёт: оплата в момент передачи товара
+)
+
+// Статус регистрации чека у провайдера
+const (
+	ReceiptPending   = "pending"
+	ReceiptSucceeded = "succeeded"
+	ReceiptCanceled  = "canceled"
+)
+
+// Receipt — фискальный чек, который провайдер передаёт в онлайн-кассу вместе с платежом или возвратом.
+// Сумма позиций должна совпадать с суммой операции.
+type Receipt struct {
+	CustomerEmail string
+	CustomerPhone string
+	TaxSystemCode int // код системы налогообложения; 0 — не передавать
+	Items         []ReceiptItem
+}
+
+type ReceiptItem struct {
+	Description    string
+	Quantity       int
+	Price          money.Money // цена за единицу с НДС
+	Currency       string
+	VATCode        int
+	PaymentSubject string
+	PaymentMode    string
+}
+
+func (r *Receipt) Total() money.Money {
+	var total money.Money
+	for _, item := range r.Items {
+		total = total.Add(item.Price.Mul(item.Quantity))
+	}
+	return total
+}
+
+// Scale возвращает чек на часть суммы (частичный возврат или списание): позиции берутся целиком
+// по порядку, остаток оформляется одной единицей следующей позиции по неполной цене.
+func (r *Receipt) Scale(amount money.Money) (*Receipt, error) {
+	target := amount.Kopecks
+	if amount.Equal(r.Total()) {
+		return r, nil
+	}
+
+	scaled := &Receipt{
+		CustomerEmail: r.CustomerEmail,
+		CustomerPhone: r.CustomerPhone,
+		TaxSystemCode: r.TaxSystemCode,
+	}
+
+	remaining := target
+	var partial *ReceiptItem
+	for _, item := range r.Items {
+		price := item.Price.Kopecks
+		if price <= 0 || remaining <= 0 {
+			continue
+		}
+
+		n := remaining / price
+		if n > int64(item.Quantity) {
+			n = int64(item.Quantity)
+		}
+		if n > 0 {
+			whole := item
+			whole.Quantity = int(n)
+			scaled.Items = append(scaled.Items, whole)
+			remaining -= n * price
+		}
+		if n < int64(item.Quantity) && partial == nil {
+			rest := item
+			partial = &rest
+		}
+	}
+
+	if remaining > 0 {
+		if partial == nil {
+			return nil, fmt.Errorf("receipt: amount %s exceeds receipt total %s", amount.Decimal(), r.Total().Decimal())
+		}
+		partial.Quantity = 1
+		partial.Price = money.New(remaining)
+		scaled.Items = append(scaled.Items, *partial)
+	}
+
+	return scaled, nil
+}
+
+// WithMode — копия чека с другим способом расчёта у всех позиций
+func (r *Receipt) WithMode(mode string) *Receipt {
+	copied := *r
+	copied.Items = make([]ReceiptItem, len(r.Items))
+	for i, item := range r.Items {
+		item.PaymentMode = mode
+		copied.Items[i] = item
+	}
+	return &copied
+}
@@ -0,0 +1,252 @@
+package provider
+
+import (
+	"Market_backend/internal/common/money"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	yooKassaBaseURL    = "https://api.yookassa.ru/v3"
+	yooKassaTimeout    = 10 * time.Second
+	yooKassaRetries    = 3
+	yooKassaRetryDelay = 500 * time.Millisecond
+)
+
+// YooKassaProvider — платежи через API ЮKassa.
+// Запросы повторяются при сетевых ошибках и ответах 5xx/429 с тем же Idempotence-Key,
+// поэтому повтор не создаёт второй платёж или возврат.
+type YooKassaProvider struct {
+	ShopID        string
+	SecretKey     string
+	WebhookSecret string
+	Test          bool
+
+	BaseURL    string
+	Client     *http.Client
+	Retries    int
+	RetryDelay time.Duration
+}
+
+func NewYooKassaProvider(shopID, secretKey, webhookSecret string, test bool) *YooKassaProvider {
+	return &YooKassaProvider{
+		ShopID:        shopID,
+		SecretKey:     secretKey,
+		WebhookSecret: webhookSecret,
+		Test:          test,
+		BaseURL:       yooKassaBaseURL,
+		Client:        &http.Client{Timeout: yooKassaTimeout},
+		Retries:       yooKassaRetries,
+		RetryDelay:    yooKassaRetryDelay,
+	}
+}
+
+func (p *YooKassaProvider) Name() string {
+	return "yookassa"
+}
+
+type yooKassaPaymentRequest struct {
+	Amount            amount             `json:"amount"`
+	PaymentMethodData *paymentMethodData `json:"payment_method_data,omitempty"`
+	// оплата сохранённым способом проходит без подтверждения покупателем:
+	// payment_method_data и confirmation в таком запросе не передаются
+	PaymentMethodID   string            `json:"payment_method_id,omitempty"`
+	SavePaymentMethod bool              `json:"save_payment_method,omitempty"`
+	Confirmation      *confirmation     `json:"confirmation,omitempty"`
+	Capture           bool              `json:"capture"`
+	Description       string            `json:"description"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+	Receipt           *receiptObject    `json:"receipt,omitempty"`
+	Test              bool              `json:"test"`
+}
+
+type paymentMethodData struct {
+	Type string `json:"type"` // "bank_card" или "sbp"
+}
+
+func (p *YooKassaProvider) CreatePayment(ctx context.Context, req CreatePaymentRequest) (*PaymentInfo, error) {
+	body := yooKassaPaymentRequest{
+		Amount:      newAmount(req.Amount, req.Currency),
+		Capture:     req.Capture,
+		Description: req.Description,
+		Metadata:    req.Metadata,
+		Receipt:     newReceipt(req.Receipt),
+		Test:        p.Test,
+	}
+	if req.PaymentMethodID != "" {
+		body.PaymentMethodID = req.PaymentMethodID
+	} else {
+		body.PaymentMethodData = &paymentMethodData{Type: string(req.Method)}
+		body.SavePaymentMethod = req.SavePaymentMethod
+		body.Confirmation = &confirmation{Type: "redirect", ReturnURL: req.ReturnURL}
+	}
+
+	var resp paymentObject
+	if err := p.do(ctx, http.MethodPost, "/payments", req.IdempotencyKey, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.info()
+}
+
+func (p *YooKassaProvider) GetPayment(ctx context.Context, paymentID string) (*PaymentInfo, error) {
+	var resp paymentObject
+	if err := p.do(ctx, http.MethodGet, "/payments/"+paymentID, "", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.info()
+}
+
+func (p *YooKassaProvider) CapturePayment(ctx context.Context, paymentID string, value money.Money, currency string, receipt *Receipt) (*PaymentInfo, error) {
+	body := struct {
+		Amount  amount         `json:"amount"`
+		Receipt *receiptObject `json:"receipt,omitempty"`
+	}{Amount: newAmount(value, currency), Receipt: newReceipt(receipt)}
+
+	var resp paymentObject
+	if err := p.do(ctx, http.MethodPost, "/payments/"+paymentID+"/capture", uuid.NewString(), body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.info()
+}
+
+func (p *YooKassaProvider) CancelPayment(ctx context.Context, paymentID string) (*PaymentInfo, error) {
+	var resp paymentObject
+	if err := p.do(ctx, http.MethodPost, "/payments/"+paymentID+"/cancel", uuid.NewString(), struct{}{}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.info()
+}
+
+func (p *YooKassaProvider) Refund(ctx context.Context, req RefundRequest) (*RefundInfo, error) {
+	body := refundObject{
+		PaymentID:   req.PaymentID,
+		Amount:      newAmount(req.Amount, req.Currency),
+		Description: req.Description,
+		Receipt:     newReceipt(req.Receipt),
+	}
+
+	key := req.IdempotencyKey
+	if key == "" {
+		key = uuid.NewString()
+	}
+
+	var resp refundObject
+	if err := p.do(ctx, http.MethodPost, "/refunds", key, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.info()
+}
+
+func (p *YooKassaProvider) ParseWebhook(header func(key string) string, body []byte) (*WebhookEvent, error) {
+	return parseNotification(p.WebhookSecret, header, body)
+}
+
+// yooKassaError — ответ API с ошибкой
+type yooKassaError struct {
+	StatusCode  int
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+func (e *yooKassaError) Error() string {
+	return fmt.Sprintf("YooKassa error %d %s: %s", e.StatusCode, e.Code, e.Description)
+}
+
+// Unwrap — ошибка, которую не исправит повтор, считается отказом шлюза
+func (e *yooKassaError) Unwrap() error {
+	if e.retryable() {
+		return nil
+	}
+	return ErrRejected
+}
+
+func (e *yooKassaError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+}
+
+func (p *YooKassaProvider) do(ctx context.Context, method, path, idempotenceKey string, reqBody any, out any) error {
+	var payload []byte
+	if reqBody != nil {
+		var err error
+		if payload, err = json.Marshal(reqBody); err != nil {
+			return err
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(p.RetryDelay * time.Duration(1<<(attempt-1))):
+			}
+		}
+
+		lastErr = p.send(ctx, method, path, idempotenceKey, payload, out)
+		if lastErr == nil {
+			return nil
+		}
+
+		var apiErr *yooKassaError
+		if errors.As(lastErr, &apiErr) && !apiErr.retryable() || errors.Is(lastErr, ErrPaymentNotFound) {
+			return lastErr
+		}
+		if ctx.Err() != nil {
+			return lastErr
+		}
+	}
+	return lastErr
+}
+
+func (p *YooKassaProvider) send(ctx context.Context, method, path, idempotenceKey string, payload []byte, out any) error {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.BaseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(p.ShopID, p.SecretKey)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idempotenceKey != "" {
+		req.Header.Set("Idempotence-Key", idempotenceKey)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrPaymentNotFound
+	}
+	if resp.StatusCode >= 400 {
+		apiErr := &yooKassaError{StatusCode: resp.StatusCode}
+		_ = json.Unmarshal(respBody, apiErr)
+		if apiErr.Description == "" {
+			apiErr.Description = string(respBody)
+		}
+		return apiErr
+	}
+
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *PaymentRepository) CreateGiftCardTx(tx *gorm.DB, card *models.GiftCard) error {
+	return tx.Create(card).Error
+}
+
+func (r *PaymentRepository) GetGiftCardByCode(code string) (*models.GiftCard, error) {
+	var card models.GiftCard
+	if err := r.db.First(&card, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// GetGiftCardByCodeTx — подарочная карта по коду с блокировкой строки
+func (r *PaymentRepository) GetGiftCardByCodeTx(tx *gorm.DB, code string) (*models.GiftCard, error) {
+	var card models.GiftCard
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&card, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+// GetGiftCardByIdTx — подарочная карта по ID с блокировкой строки
+func (r *PaymentRepository) GetGiftCardByIdTx(tx *gorm.DB, id uuid.UUID) (*models.GiftCard, error) {
+	var card models.GiftCard
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&card, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (r *PaymentRepository) UpdateGiftCardTx(tx *gorm.DB, card *models.GiftCard) error {
+	return tx.Save(card).Error
+}
+
+func (r *PaymentRepository) GetGiftCards(limit, offset int) ([]models.GiftCard, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.GiftCard{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var cards []models.GiftCard
+	err := r.db.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&cards).Error
+	return cards, total, err
+}
+
+// LockWalletTx — баланс покупателя с блокировкой строки; если баланса ещё нет, он создаётся нулевым
+func (r *PaymentRepository) LockWalletTx(tx *gorm.DB, userID uuid.UUID) (*models.StoreCreditWallet, error) {
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.StoreCreditWallet{UserID: userID, UpdatedAt: time.Now()}).Error; err != nil {
+		return nil, err
+	}
+
+	var wallet models.StoreCreditWallet
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&wallet, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (r *PaymentRepository) UpdateWalletTx(tx *gorm.DB, wallet *models.StoreCreditWallet) error {
+	return tx.Save(wallet).Error
+}
+
+// GetWalletBalance — баланс покупателя; нулевой, если начислений ещё не было
+func (r *PaymentRepository) GetWalletBalance(userID uuid.UUID) (money.Money, error) {
+	var kopecks int64
+	err := r.db.Model(&models.StoreCreditWallet{}).
+		Select("COALESCE(SUM(balance), 0)").
+		Where("user_id = ?", userID).
+		Scan(&kopecks).Error
+	return money.New(kopecks), err
+}
+
+func (r *PaymentRepository) CreateBalanceTransactionTx(tx *gorm.DB, transaction *models.BalanceTransaction) error {
+	return tx.Create(transaction).Error
+}
+
+// GetBalanceTransactions — движения по подарочной карте или балансу покупателя, новые первыми
+func (r *PaymentRepository) GetBalanceTransactions(giftCardID, userID *uuid.UUID, limit, offset int) ([]models.BalanceTransaction, int64, error) {
+	query := r.db.Model(&models.BalanceTransaction{})
+	if giftCardID != nil {
+		query = query.Where("gift_card_id = ?", *giftCardID)
+	}
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transactions []models.BalanceTransaction
+	err := query.
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&transactions).Error
+	return transactions, total, err
+}
+
+// BalancePaidSumTx — сколько по заказу оплачено подарочными картами и балансом за вычетом возвратов
+func (r *PaymentRepository) BalancePaidSumTx(tx *gorm.DB, orderID uuid.UUID) (money.Money, error) {
+	var kopecks int64
+	err := tx.Model(&models.Payment{}).
+		Select("COALESCE(SUM(amount - refunded_amount), 0)").
+		Where("order_id = ? AND method IN ? AND status IN ?", orderID,
+			[]types.PaymentMethod{types.PaymentMethodGiftCard, types.PaymentMethodStoreCredit},
+			[]types.PaymentStatus{types.PaymentStatusSucceeded, types.PaymentStatusPartiallyRefunded}).
+		Scan(&kopecks).Error
+	return money.New(kopecks), err
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordEvent сохраняет уведомление шлюза. Повторная доставка того же события
+// возвращает уже существующую запись со счётчиком доставок, увеличенным на единицу.
+func (r *PaymentRepository) RecordEvent(event *models.PaymentEvent) (*models.PaymentEvent, error) {
+	var record models.PaymentEvent
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 1 {
+			record = *event
+			return nil
+		}
+
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&record, "provider = ? AND event = ? AND object_id = ?", event.Provider, event.Event, event.ObjectID).Error; err != nil {
+			return err
+		}
+		record.Deliveries++
+		return tx.Model(&record).Update("deliveries", record.Deliveries).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// GetEventTx блокирует запись события: параллельная доставка дубля дождётся окончания обработки
+func (r *PaymentRepository) GetEventTx(tx *gorm.DB, id uuid.UUID) (*models.PaymentEvent, error) {
+	var event models.PaymentEvent
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&event, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FinishEventTx фиксирует результат обработки события
+func (r *PaymentRepository) FinishEventTx(tx *gorm.DB, id uuid.UUID, status types.PaymentEventStatus, note string) error {
+	now := time.Now()
+	return tx.Model(&models.PaymentEvent{}).Where("id = ?", id).Updates(map[string]any{
+		"status":       status,
+		"error":        note,
+		"processed_at": &now,
+	}).Error
+}
+
+// FailEvent запоминает ошибку обработки; событие остаётся received и обработается при повторной доставке
+func (r *PaymentRepository) FailEvent(id uuid.UUID, reason string) error {
+	return r.db.Model(&models.PaymentEvent{}).Where("id = ?", id).Update("error", reason).Error
+}
+
+// GetEvents — журнал уведомлений, новые сверху. Пустые фильтры не применяются.
+func (r *PaymentRepository) GetEvents(status types.PaymentEventStatus, objectID string, limit, offset int) ([]models.PaymentEvent, int64, error) {
+	query := r.db.Model(&models.PaymentEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if objectID != "" {
+		query = query.Where("object_id = ?", objectID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.PaymentEvent
+	err := query.
+		Order("received_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&events).Error
+	return events, total, err
+}
@@ -2,8 +2,13 @@ package repository
 
 import (
 	"Market_backend/internal/common"
+	"Market_backend/internal/common/types"
 	"Market_backend/models"
+	"time"
+
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentRepository struct {
@@ -18,6 +23,10 @@ func (r *PaymentRepository) Create(payment *models.Payment) error {
 	return r.db.Create(payment).Error
 }
 
+func (r *PaymentRepository) CreateTx(tx *gorm.DB, payment *models.Payment) error {
+	return tx.Omit(clause.Associations).Create(payment).Error
+}
+
 func (r *PaymentRepository) GetByPaymentID(paymentID string) (*models.Payment, error) {
 	var payment models.Payment
 	if err := r.db.Where("payment_id = ?", paymentID).First(&payment).Error; err != nil {
@@ -29,3 +38,146 @@ func (r *PaymentRepository) GetByPaymentID(paymentID string) (*models.Payment, e
 func (r *PaymentRepository) Update(payment *models.Payment) error {
 	return r.db.Save(payment).Error
 }
+
+func (r *PaymentRepository) DB() *gorm.DB {
+	return r.db
+}
+
+// GetRefundableByOrderTx — оплаченные платежи заказа, по которым ещё можно вернуть деньги, в порядке возврата:
+// сначала оплата через шлюз и офлайн, затем подарочные карты, последним — баланс покупателя.
+// Строки блокируются, чтобы параллельные возвраты не превысили суммы платежей.
+func (r *PaymentRepository) GetRefundableByOrderTx(tx *gorm.DB, orderID uuid.UUID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderID, []types.PaymentStatus{
+			types.PaymentStatusSucceeded, types.PaymentStatusPartiallyRefunded,
+		}).
+		Order(clause.Expr{
+			SQL:  "CASE method WHEN ? THEN 2 WHEN ? THEN 1 ELSE 0 END, created_at DESC",
+			Vars: []any{types.PaymentMethodStoreCredit, types.PaymentMethodGiftCard},
+		}).
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *PaymentRepository) GetByIdTx(tx *gorm.DB, id uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&payment, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *PaymentRepository) UpdateTx(tx *gorm.DB, payment *models.Payment) error {
+	return tx.Omit(clause.Associations).Save(payment).Error
+}
+
+// CancelPendingByOrderTx аннулирует неоплаченные платежи заказа
+func (r *PaymentRepository) CancelPendingByOrderTx(tx *gorm.DB, orderID uuid.UUID) (int64, error) {
+	res := tx.Model(&models.Payment{}).
+		Where("order_id = ? AND status = ?", orderID, types.PaymentStatusPending).
+		Updates(map[string]any{"status": types.PaymentStatusCanceled, "updated_at": time.Now()})
+	return res.RowsAffected, res.Error
+}
+
+// GetByPaymentIDTx — платёж по ID у шлюза с блокировкой строки
+func (r *PaymentRepository) GetByPaymentIDTx(tx *gorm.DB, paymentID string) (*models.Payment, error) {
+	var payment models.Payment
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("payment_id = ?", paymentID).
+		First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetStalePending — платежи, созданные у шлюза до before и всё ещё ожидающие оплаты
+func (r *PaymentRepository) GetStalePending(before time.Time, limit int) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.
+		Where("status = ? AND payment_id <> '' AND created_at < ?", types.PaymentStatusPending, before).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&payments).Error
+	return payments, err
+}
+
+// SetProviderID сохраняет ID платежа у шлюза и статус чека, не трогая статус платежа: его мог уже обновить вебхук
+func (r *PaymentRepository) SetProviderID(id uuid.UUID, paymentID, receiptStatus string) error {
+	return r.db.Model(&models.Payment{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"payment_id": paymentID, "receipt_status": receiptStatus}).Error
+}
+
+// GetPendingByOrderTx — ожидающие оплаты платежи заказа с блокировкой строк
+func (r *PaymentRepository) GetPendingByOrderTx(tx *gorm.DB, orderID uuid.UUID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, types.PaymentStatusPending).
+		Find(&payments).Error
+	return payments, err
+}
+
+// GetHeldByOrderTx — платёж заказа, деньги по которому заблокированы и ждут списания
+func (r *PaymentRepository) GetHeldByOrderTx(tx *gorm.DB, orderID uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", orderID, types.PaymentStatusWaitingForCapture).
+		Order("created_at DESC").
+		First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetPendingByOrder — ожидающие оплаты платежи заказа без блокировки, для сверки со шлюзом
+func (r *PaymentRepository) GetPendingByOrder(orderID uuid.UUID) ([]models.Payment, error) {
+	var payments []models.Payment
+	err := r.db.
+		Where("order_id = ? AND status = ?", orderID, types.PaymentStatusPending).
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *PaymentRepository) GetById(id uuid.UUID) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.First(&payment, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetVoidPending — платежи, блокировку по которым ещё нужно снять у шлюза.
+// orderID != nil — только по заказу; before — отменённые раньше этого момента.
+func (r *PaymentRepository) GetVoidPending(orderID *uuid.UUID, before time.Time, limit int) ([]models.Payment, error) {
+	query := r.db.Where("void_pending = true AND updated_at < ?", before)
+	if orderID != nil {
+		query = query.Where("order_id = ?", *orderID)
+	}
+	var payments []models.Payment
+	err := query.Order("updated_at ASC").Limit(limit).Find(&payments).Error
+	return payments, err
+}
+
+// GetCapturePending — платежи, списание по которым ещё нужно отправить шлюзу.
+// orderID != nil — только по заказу; before — записанные раньше этого момента.
+func (r *PaymentRepository) GetCapturePending(orderID *uuid.UUID, before time.Time, limit int) ([]models.Payment, error) {
+	query := r.db.Where("capture_pending = true AND updated_at < ?", before)
+	if orderID != nil {
+		query = query.Where("order_id = ?", *orderID)
+	}
+	var payments []models.Payment
+	err := query.Order("updated_at ASC").Limit(limit).Find(&payments).Error
+	return payments, err
+}
+
+// ClearVoidPending отмечает, что шлюз снял блокировку
+func (r *PaymentRepository) ClearVoidPending(id uuid.UUID) error {
+	return r.db.Model(&models.Payment{}).Where("id = ?", id).Update("void_pending", false).Error
+}
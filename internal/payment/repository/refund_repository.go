@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *PaymentRepository) CreateRefundTx(tx *gorm.DB, refund *models.Refund) error {
+	return tx.Create(refund).Error
+}
+
+func (r *PaymentRepository) SaveRefundTx(tx *gorm.DB, refund *models.Refund) error {
+	return tx.Omit(clause.Associations).Save(refund).Error
+}
+
+func (r *PaymentRepository) GetRefundByProviderIDTx(tx *gorm.DB, providerRefundID string) (*models.Refund, error) {
+	var refund models.Refund
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&refund, "provider_refund_id = ?", providerRefundID).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *PaymentRepository) GetRefund(id uuid.UUID) (*models.Refund, error) {
+	var refund models.Refund
+	if err := r.db.Preload("Items").First(&refund, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *PaymentRepository) GetRefundTx(tx *gorm.DB, id uuid.UUID) (*models.Refund, error) {
+	var refund models.Refund
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&refund, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// GetUnsubmittedRefunds — возвраты, ещё не отправленные шлюзу (нет ID у шлюза).
+// orderID != nil — только по заказу; before — созданные раньше этого момента.
+func (r *PaymentRepository) GetUnsubmittedRefunds(orderID *uuid.UUID, before time.Time, limit int) ([]models.Refund, error) {
+	query := r.db.Where("status = ? AND provider_refund_id = '' AND created_at < ?", types.RefundPending, before)
+	if orderID != nil {
+		query = query.Where("order_id = ?", *orderID)
+	}
+	var refunds []models.Refund
+	err := query.Order("created_at ASC").Limit(limit).Find(&refunds).Error
+	return refunds, err
+}
+
+// GetUnsubmittedRefundTx — самый ранний неотправленный возврат по платежу на сумму amount:
+// по нему пришло уведомление раньше, чем сохранён ответ шлюза
+func (r *PaymentRepository) GetUnsubmittedRefundTx(tx *gorm.DB, paymentID uuid.UUID, amount money.Money) (*models.Refund, error) {
+	var refund models.Refund
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("payment_id = ? AND status = ? AND provider_refund_id = '' AND amount = ?", paymentID, types.RefundPending, amount).
+		Order("created_at ASC").
+		First(&refund).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *PaymentRepository) GetRefundsByOrder(orderID uuid.UUID) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := r.db.
+		Preload("Items").
+		Where("order_id = ?", orderID).
+		Order("created_at ASC").
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// PendingRefundSumTx — сумма возвратов по платежу, ещё не подтверждённых шлюзом
+func (r *PaymentRepository) PendingRefundSumTx(tx *gorm.DB, paymentID uuid.UUID) (money.Money, error) {
+	var kopecks int64
+	err := tx.Model(&models.Refund{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("payment_id = ? AND status = ?", paymentID, types.RefundPending).
+		Scan(&kopecks).Error
+	return money.New(kopecks), err
+}
+
+// RefundedQuantitiesTx — сколько штук каждой позиции заказа уже возвращено или возвращается
+func (r *PaymentRepository) RefundedQuantitiesTx(tx *gorm.DB, orderID uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		OrderItemID uuid.UUID
+		Quantity    int
+	}
+	err := tx.Table("refund_items").
+		Select("refund_items.order_item_id, SUM(refund_items.quantity) AS quantity").
+		Joins("JOIN refunds ON refunds.id = refund_items.refund_id").
+		Where("refunds.order_id = ? AND refunds.status IN ?", orderID, []types.RefundStatus{types.RefundPending, types.RefundSucceeded}).
+		Group("refund_items.order_item_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		result[row.OrderItemID] = row.Quantity
+	}
+	return result, nil
+}
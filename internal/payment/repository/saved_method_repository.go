@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"Market_backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertSavedMethodTx сохраняет способ оплаты; повторное сохранение того же токена обновляет данные карты
+func (r *PaymentRepository) UpsertSavedMethodTx(tx *gorm.DB, method *models.SavedPaymentMethod) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "provider_method_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "card_type", "last4", "expiry_month", "expiry_year"}),
+	}).Create(method).Error
+}
+
+func (r *PaymentRepository) GetSavedMethods(userID uuid.UUID) ([]models.SavedPaymentMethod, error) {
+	var methods []models.SavedPaymentMethod
+	err := r.db.
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&methods).Error
+	return methods, err
+}
+
+func (r *PaymentRepository) GetSavedMethod(userID, id uuid.UUID) (*models.SavedPaymentMethod, error) {
+	var method models.SavedPaymentMethod
+	if err := r.db.First(&method, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, err
+	}
+	return &method, nil
+}
+
+func (r *PaymentRepository) DeleteSavedMethod(userID, id uuid.UUID) error {
+	res := r.db.Delete(&models.SavedPaymentMethod{}, "id = ? AND user_id = ?", id, userID)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *PaymentRepository) TouchSavedMethodTx(tx *gorm.DB, id uuid.UUID, usedAt time.Time) error {
+	return tx.Model(&models.SavedPaymentMethod{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
@@ -3,17 +3,70 @@ package router
 import (
 	"Market_backend/internal/middleware"
 	"Market_backend/internal/payment/handler"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
 )
 
-func RegisterPaymentRouter(app *fiber.App, h *handler.PaymentHandler) {
+// idempotent — middleware Idempotency-Key для создания платежа
+func RegisterPaymentRouter(app *fiber.App, h *handler.PaymentHandler, idempotent fiber.Handler) {
 	payments := app.Group("/payments")
 
 	// 2. Вебхук ЮKassa
 	payments.Post("/webhook", h.Webhook)
 
+	// 3. Возвраты (админ)
+	payments.Post("/refunds", middleware.AuthRequired(), middleware.AdminOnly(), h.CreateRefund)
+	payments.Get("/refunds/:order_id", middleware.AuthRequired(), middleware.AdminOnly(), h.GetOrderRefunds)
+
+	// 4. Журнал уведомлений шлюза и ручная сверка (админ)
+	payments.Get("/events", middleware.AuthRequired(), middleware.AdminOnly(), h.GetEvents)
+	payments.Post("/reconcile", middleware.AuthRequired(), middleware.AdminOnly(), h.Reconcile)
+
+	// 5. Двухстадийные платежи: списание и снятие блокировки (админ)
+	payments.Post("/:payment_id/capture", middleware.AuthRequired(), middleware.AdminOnly(), h.CapturePayment)
+	payments.Post("/:payment_id/void", middleware.AuthRequired(), middleware.AdminOnly(), h.VoidPayment)
+
+	// 6. Подтверждение офлайн-оплаты: по счёту или при получении (админ)
+	payments.Post("/:payment_id/confirm", middleware.AuthRequired(), middleware.AdminOnly(), h.ConfirmOfflinePayment)
+
 	// 1. Создание платежа для фронтенда
-	payments.Post("/:order_id", middleware.AuthRequired(), h.CreatePayment)
+	payments.Post("/:order_id", middleware.AuthRequired(), idempotent, h.CreatePayment)
+
+	// 7. Сохранённые карты в профиле пользователя
+	methods := app.Group("/users/me/payment-methods", middleware.AuthRequired())
+	methods.Get("/", h.GetSavedMethods)
+	methods.Delete("/:id", h.DeleteSavedMethod)
+
+	// 8. Подарочные карты: выпуск и журнал (админ), проверка остатка по коду (покупатель)
+	giftCards := app.Group("/gift-cards", middleware.AuthRequired())
+	giftCards.Post("/", middleware.AdminOnly(), h.IssueGiftCard)
+	giftCards.Get("/", middleware.AdminOnly(), h.GetGiftCards)
+	giftCards.Get("/:id/transactions", middleware.AdminOnly(), h.GetGiftCardTransactions)
+	// Проверка по коду подсказывает, действует ли код, — лимит против перебора
+	giftCardLimiter := limiter.New(limiter.Config{
+		Max:        10,
+		Expiration: 15 * time.Minute,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			if userId, ok := c.Locals("userId").(string); ok {
+				return userId
+			}
+			return c.IP()
+		},
+	})
+	giftCards.Get("/:code", giftCardLimiter, h.CheckGiftCard)
+
+	// 9. Баланс покупателя: свой баланс и журнал, корректировка (админ)
+	app.Get("/users/me/store-credit", middleware.AuthRequired(), h.GetStoreCredit)
+	app.Post("/users/:user_id/store-credit", middleware.AuthRequired(), middleware.AdminOnly(), h.AdjustStoreCredit)
+}
+
+// RegisterFakePaymentRouter — страница подтверждения fake-провайдера, только для локальной разработки
+func RegisterFakePaymentRouter(app *fiber.App, h *handler.FakePaymentHandler) {
+	fake := app.Group("/payments/fake")
 
+	fake.Get("/:id", h.Page)
+	fake.Post("/:id/confirm", h.Confirm)
+	fake.Post("/:id/decline", h.Decline)
 }
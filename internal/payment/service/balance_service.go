@@ -0,0 +1,369 @@
+package service
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/payment/dto"
+	"Market_backend/models"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrGiftCardNotFound        = errors.New("подарочная карта не найдена")
+	ErrGiftCardUnavailable     = errors.New("подарочная карта недействительна или израсходована")
+	ErrInsufficientStoreCredit = errors.New("недостаточно средств на балансе")
+)
+
+// giftCardAlphabet — символы кода подарочной карты, без похожих 0/O и 1/I
+const giftCardAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// newGiftCardCode — случайный код вида XXXX-XXXX-XXXX
+func newGiftCardCode() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+
+	code := make([]byte, 0, 14)
+	for i, c := range b {
+		if i > 0 && i%4 == 0 {
+			code = append(code, '-')
+		}
+		code = append(code, giftCardAlphabet[int(c)%len(giftCardAlphabet)])
+	}
+	return string(code)
+}
+
+func normalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// giftCardAvailable — картой можно оплатить заказ
+func giftCardAvailable(card *models.GiftCard, now time.Time) bool {
+	return !card.Disabled && card.Balance.IsPositive() && (card.ExpiresAt == nil || now.Before(*card.ExpiresAt))
+}
+
+// balanceEntry — основание движения по подарочной карте или балансу для журнала
+type balanceEntry struct {
+	Reason    types.BalanceReason
+	OrderID   *uuid.UUID
+	PaymentID *uuid.UUID
+	CreatedBy *uuid.UUID
+	Comment   string
+}
+
+// IssueGiftCard выпускает проданную подарочную карту с новым кодом
+func (s *PaymentService) IssueGiftCard(adminId uuid.UUID, issueDto dto.IssueGiftCardDTO) (*models.GiftCard, error) {
+	if err := issueDto.Validate(); err != nil {
+		return nil, err
+	}
+	expiresAt, _ := issueDto.Expiry()
+
+	now := time.Now()
+	card := &models.GiftCard{
+		ID:            uuid.New(),
+		Code:          newGiftCardCode(),
+		InitialAmount: issueDto.Amount,
+		Balance:       issueDto.Amount,
+		ExpiresAt:     expiresAt,
+		Comment:       issueDto.Comment,
+		IssuedByID:    &adminId,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		if err := s.paymentRepo.CreateGiftCardTx(tx, card); err != nil {
+			return err
+		}
+		return s.paymentRepo.CreateBalanceTransactionTx(tx, &models.BalanceTransaction{
+			ID:           uuid.New(),
+			GiftCardID:   &card.ID,
+			Amount:       card.Balance,
+			BalanceAfter: card.Balance,
+			Reason:       types.BalanceIssue,
+			Comment:      card.Comment,
+			CreatedByID:  &adminId,
+			CreatedAt:    now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (s *PaymentService) GetGiftCards(page, limit int) ([]models.GiftCard, int64, error) {
+	return s.paymentRepo.GetGiftCards(limit, (page-1)*limit)
+}
+
+// CheckGiftCard — остаток подарочной карты по коду и можно ли ею сейчас оплатить заказ
+func (s *PaymentService) CheckGiftCard(code string) (*models.GiftCard, bool, error) {
+	card, err := s.paymentRepo.GetGiftCardByCode(normalizeGiftCardCode(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, ErrGiftCardNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return card, giftCardAvailable(card, time.Now()), nil
+}
+
+func (s *PaymentService) GetGiftCardTransactions(giftCardID uuid.UUID, page, limit int) ([]models.BalanceTransaction, int64, error) {
+	return s.paymentRepo.GetBalanceTransactions(&giftCardID, nil, limit, (page-1)*limit)
+}
+
+// GetStoreCredit — баланс покупателя и движения по нему
+func (s *PaymentService) GetStoreCredit(userId uuid.UUID, page, limit int) (money.Money, []models.BalanceTransaction, int64, error) {
+	balance, err := s.paymentRepo.GetWalletBalance(userId)
+	if err != nil {
+		return money.Money{}, nil, 0, err
+	}
+	transactions, total, err := s.paymentRepo.GetBalanceTransactions(nil, &userId, limit, (page-1)*limit)
+	if err != nil {
+		return money.Money{}, nil, 0, err
+	}
+	return balance, transactions, total, nil
+}
+
+// AdjustStoreCredit — ручное начисление или списание баланса покупателя администратором
+func (s *PaymentService) AdjustStoreCredit(adminId, userId uuid.UUID, adjustDto dto.AdjustStoreCreditDTO) (money.Money, error) {
+	if err := adjustDto.Validate(); err != nil {
+		return money.Money{}, err
+	}
+
+	var balance money.Money
+	err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Select("id").First(&user, "id = ?", userId).Error; err != nil {
+			return err
+		}
+
+		wallet, err := s.moveWalletTx(tx, userId, adjustDto.Amount, balanceEntry{
+			Reason:    types.BalanceAdjustment,
+			CreatedBy: &adminId,
+			Comment:   adjustDto.Comment,
+		})
+		if err != nil {
+			return err
+		}
+		balance = wallet.Balance
+		return nil
+	})
+	return balance, err
+}
+
+// moveWalletTx меняет баланс покупателя на delta и записывает движение в журнал. Баланс не уходит в минус.
+func (s *PaymentService) moveWalletTx(tx *gorm.DB, userID uuid.UUID, delta money.Money, entry balanceEntry) (*models.StoreCreditWallet, error) {
+	wallet, err := s.paymentRepo.LockWalletTx(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := wallet.Balance.Add(delta)
+	if balance.IsNegative() {
+		return nil, ErrInsufficientStoreCredit
+	}
+	wallet.Balance = balance
+	wallet.UpdatedAt = time.Now()
+	if err := s.paymentRepo.UpdateWalletTx(tx, wallet); err != nil {
+		return nil, err
+	}
+
+	return wallet, s.paymentRepo.CreateBalanceTransactionTx(tx, &models.BalanceTransaction{
+		ID:           uuid.New(),
+		UserID:       &userID,
+		OrderID:      entry.OrderID,
+		PaymentID:    entry.PaymentID,
+		Amount:       delta,
+		BalanceAfter: balance,
+		Reason:       entry.Reason,
+		Comment:      entry.Comment,
+		CreatedByID:  entry.CreatedBy,
+		CreatedAt:    wallet.UpdatedAt,
+	})
+}
+
+// moveGiftCardTx меняет остаток заблокированной подарочной карты на delta и записывает движение в журнал
+func (s *PaymentService) moveGiftCardTx(tx *gorm.DB, card *models.GiftCard, delta money.Money, entry balanceEntry) error {
+	balance := card.Balance.Add(delta)
+	if balance.IsNegative() {
+		return ErrGiftCardUnavailable
+	}
+	card.Balance = balance
+	card.UpdatedAt = time.Now()
+	if err := s.paymentRepo.UpdateGiftCardTx(tx, card); err != nil {
+		return err
+	}
+
+	return s.paymentRepo.CreateBalanceTransactionTx(tx, &models.BalanceTransaction{
+		ID:           uuid.New(),
+		GiftCardID:   &card.ID,
+		OrderID:      entry.OrderID,
+		PaymentID:    entry.PaymentID,
+		Amount:       delta,
+		BalanceAfter: balance,
+		Reason:       entry.Reason,
+		Comment:      entry.Comment,
+		CreatedByID:  entry.CreatedBy,
+		CreatedAt:    card.UpdatedAt,
+	})
+}
+
+// dueTx — сколько по заказу осталось оплатить через шлюз или офлайн после подарочных карт и баланса
+func (s *PaymentService) dueTx(tx *gorm.DB, orderID uuid.UUID, total money.Money) (money.Money, error) {
+	paid, err := s.paymentRepo.BalancePaidSumTx(tx, orderID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	due := total.Sub(paid)
+	if due.IsNegative() {
+		return money.Money{}, nil
+	}
+	return due, nil
+}
+
+// payWithBalances списывает с подарочной карты, затем с баланса покупателя столько, сколько осталось оплатить.
+// Ожидающие платежи на прежнюю сумму аннулируются. Если заказ оплачен целиком, он становится оплаченным.
+// Возвращает последний созданный платёж и остаток к оплате.
+func (s *PaymentService) payWithBalances(order *models.Order, opts CreatePaymentOptions) (*models.Payment, money.Money, error) {
+	var (
+		last *models.Payment
+		due  money.Money
+	)
+	err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		var locked models.Order
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&locked, "id = ?", order.ID).Error; err != nil {
+			return err
+		}
+		if locked.Status != types.InProgress {
+			return ErrOrderNotAwaitingPayment
+		}
+
+		var err error
+		if due, err = s.dueTx(tx, locked.ID, locked.Total); err != nil {
+			return err
+		}
+		entry := balanceEntry{
+			Reason:  types.BalancePayment,
+			OrderID: &locked.ID,
+			Comment: fmt.Sprintf("оплата заказа №%d", locked.OrderNumber),
+		}
+
+		if opts.GiftCardCode != "" && due.IsPositive() {
+			card, err := s.paymentRepo.GetGiftCardByCodeTx(tx, normalizeGiftCardCode(opts.GiftCardCode))
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrGiftCardNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if !giftCardAvailable(card, time.Now()) {
+				return ErrGiftCardUnavailable
+			}
+
+			amount := card.Balance.Min(due)
+			if last, err = s.balancePaymentTx(tx, &locked, types.PaymentMethodGiftCard, amount, &card.ID); err != nil {
+				return err
+			}
+			entry.PaymentID = &last.ID
+			if err := s.moveGiftCardTx(tx, card, amount.Mul(-1), entry); err != nil {
+				return err
+			}
+			due = due.Sub(amount)
+		}
+
+		if opts.UseStoreCredit && due.IsPositive() {
+			wallet, err := s.paymentRepo.LockWalletTx(tx, locked.UserID)
+			if err != nil {
+				return err
+			}
+			if !wallet.Balance.IsPositive() {
+				return ErrInsufficientStoreCredit
+			}
+
+			amount := wallet.Balance.Min(due)
+			if last, err = s.balancePaymentTx(tx, &locked, types.PaymentMethodStoreCredit, amount, nil); err != nil {
+				return err
+			}
+			entry.PaymentID = &last.ID
+			if _, err := s.moveWalletTx(tx, locked.UserID, amount.Mul(-1), entry); err != nil {
+				return err
+			}
+			due = due.Sub(amount)
+		}
+
+		if last == nil {
+			return nil
+		}
+		// ссылки на оплату прежней суммы больше не действуют
+		if _, err := s.paymentRepo.CancelPendingByOrderTx(tx, locked.ID); err != nil {
+			return err
+		}
+		if due.IsPositive() {
+			return nil
+		}
+		return s.orderRepo.ChangeStatusTx(tx, locked.ID, types.Paid, nil, "заказ оплачен подарочной картой и балансом")
+	})
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+	return last, due, nil
+}
+
+// balancePaymentTx — проведённый платёж подарочной картой или балансом
+func (s *PaymentService) balancePaymentTx(tx *gorm.DB, order *models.Order, method types.PaymentMethod, amount money.Money, giftCardID *uuid.UUID) (*models.Payment, error) {
+	now := time.Now()
+	payment := &models.Payment{
+		ID:         uuid.New(),
+		OrderID:    order.ID,
+		Method:     method,
+		Status:     types.PaymentStatusSucceeded,
+		Amount:     amount,
+		Currency:   "RUB",
+		GiftCardID: giftCardID,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.paymentRepo.CreateTx(tx, payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// refundToBalanceTx зачисляет возврат на подарочную карту, которой платили, или на баланс покупателя
+func (s *PaymentService) refundToBalanceTx(tx *gorm.DB, payment *models.Payment, refund *models.Refund) error {
+	entry := balanceEntry{
+		Reason:    types.BalanceRefund,
+		OrderID:   &refund.OrderID,
+		PaymentID: &payment.ID,
+		CreatedBy: refund.CreatedByID,
+		Comment:   refund.Reason,
+	}
+
+	if payment.Method == types.PaymentMethodGiftCard && !refund.ToStoreCredit && payment.GiftCardID != nil {
+		card, err := s.paymentRepo.GetGiftCardByIdTx(tx, *payment.GiftCardID)
+		if err != nil {
+			return err
+		}
+		// на истёкшую или заблокированную карту деньги не вернуть — они уходят на баланс покупателя
+		if !card.Disabled && (card.ExpiresAt == nil || time.Now().Before(*card.ExpiresAt)) {
+			return s.moveGiftCardTx(tx, card, refund.Amount, entry)
+		}
+	}
+
+	var order models.Order
+	if err := tx.Select("id", "user_id").First(&order, "id = ?", refund.OrderID).Error; err != nil {
+		return err
+	}
+	_, err := s.moveWalletTx(tx, order.UserID, refund.Amount, entry)
+	return err
+}
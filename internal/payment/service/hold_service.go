@@ -0,0 +1,300 @@
+package service
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/config"
+	"Market_backend/internal/payment/provider"
+	"Market_backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPaymentNotHeld     = errors.New("платёж не ожидает списания")
+	ErrCaptureExceedsHold = errors.New("сумма списания больше заблокированной")
+	ErrOrderAlreadyHeld   = errors.New("деньги по заказу уже заблокированы")
+)
+
+// requiresHold — крупные (оптовые) заказы оплачиваются в две стадии, порог — HOLD_PAYMENT_MIN_TOTAL
+func requiresHold(order *models.Order) bool {
+	threshold := utils.ParseMoney(config.HoldPaymentMinTotal)
+	return threshold.IsPositive() && !order.Total.Less(threshold)
+}
+
+// CapturePayment списывает заблокированные деньги. amount <= 0 — вся заблокированная сумма,
+// меньшая сумма — частичное списание, остаток шлюз разблокирует.
+func (s *PaymentService) CapturePayment(adminId, paymentID uuid.UUID, amount money.Money) (*models.Payment, error) {
+	err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		payment, err := s.paymentRepo.GetByIdTx(tx, paymentID)
+		if err != nil {
+			return err
+		}
+		return s.captureTx(tx, payment, amount, &adminId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.submitCapture(paymentID); err != nil {
+		// списание отправит сверка
+		log.Printf("payment %s: capture: %v", paymentID, err)
+	}
+	return s.paymentRepo.GetById(paymentID)
+}
+
+// VoidPayment снимает блокировку денег без списания; заказ снова ждёт оплаты
+func (s *PaymentService) VoidPayment(adminId, paymentID uuid.UUID) (*models.Payment, error) {
+	var payment *models.Payment
+	err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		if payment, err = s.paymentRepo.GetByIdTx(tx, paymentID); err != nil {
+			return err
+		}
+		return s.voidTx(tx, payment, &adminId, "блокировка оплаты снята")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.releaseHold(payment.ID); err != nil {
+		// блокировку снимет сверка
+		log.Printf("payment %s: release hold: %v", payment.ID, err)
+	}
+	return payment, nil
+}
+
+// CaptureOrderHoldTx записывает списание заблокированной по заказу суммы (в пределах остатка к оплате) —
+// вызывается при отгрузке. false — блокировки нет. Шлюзу списание отправляется после фиксации
+// транзакции вызывающего (SettleOrder); оплаченным заказ становится, когда шлюз его подтвердит.
+func (s *PaymentService) CaptureOrderHoldTx(tx *gorm.DB, orderID uuid.UUID, changedBy *uuid.UUID) (bool, error) {
+	payment, err := s.paymentRepo.GetHeldByOrderTx(tx, orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var order models.Order
+	if err := tx.Select("id", "total").First(&order, "id = ?", orderID).Error; err != nil {
+		return false, err
+	}
+
+	// часть заказа могла быть оплачена подарочной картой или балансом
+	due, err := s.dueTx(tx, order.ID, order.Total)
+	if err != nil {
+		return false, err
+	}
+	return true, s.captureTx(tx, payment, payment.HeldAmount.Min(due), changedBy)
+}
+
+// VoidOrderHoldTx снимает блокировку денег по заказу, например при его отмене. false — блокировки нет.
+// У шлюза блокировка снимается после фиксации транзакции вызывающего (SettleOrder).
+func (s *PaymentService) VoidOrderHoldTx(tx *gorm.DB, orderID uuid.UUID, changedBy *uuid.UUID, comment string) (bool, error) {
+	payment, err := s.paymentRepo.GetHeldByOrderTx(tx, orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// списание уже отправлено шлюзу: если заказ отменён, деньги вернёт capturedTx
+	if payment.CapturePending {
+		return false, nil
+	}
+	return true, s.voidTx(tx, payment, changedBy, comment)
+}
+
+// HasHoldTx — по заказу есть заблокированные, но не списанные деньги
+func (s *PaymentService) HasHoldTx(tx *gorm.DB, orderID uuid.UUID) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Payment{}).
+		Where("order_id = ? AND status = ?", orderID, types.PaymentStatusWaitingForCapture).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// captureTx записывает списание у нас; запрос к шлюзу делает submitCapture после фиксации транзакции:
+// она держит блокировки заказа и платежа, а откат после списания оставил бы деньги без записи.
+func (s *PaymentService) captureTx(tx *gorm.DB, payment *models.Payment, amount money.Money, changedBy *uuid.UUID) error {
+	if payment.Status != types.PaymentStatusWaitingForCapture || payment.CapturePending {
+		return ErrPaymentNotHeld
+	}
+
+	if !amount.IsPositive() {
+		amount = payment.HeldAmount
+	}
+	if payment.HeldAmount.Less(amount) {
+		return ErrCaptureExceedsHold
+	}
+
+	payment.CapturePending = true
+	payment.CaptureAmount = amount
+	payment.UpdatedAt = time.Now()
+	if err := s.paymentRepo.UpdateTx(tx, payment); err != nil {
+		return err
+	}
+	return s.orderRepo.AddNoteTx(tx, payment.OrderID, changedBy,
+		fmt.Sprintf("списание %s %s отправлено платёжному шлюзу", amount.Decimal(), payment.Currency))
+}
+
+// capturedTx отмечает в заказе подтверждённое шлюзом списание. Заказ к этому времени мог уйти
+// в отгрузку — тогда статус не меняется; если заказ отменён, пока списание шло, деньги возвращаются.
+func (s *PaymentService) capturedTx(tx *gorm.DB, payment *models.Payment) error {
+	var order models.Order
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status").
+		First(&order, "id = ?", payment.OrderID).Error; err != nil {
+		return err
+	}
+
+	if order.Status == types.Cancelled {
+		_, err := s.refundTx(tx, payment.ID, payment.Amount, "заказ отменён до списания оплаты", nil, nil, false)
+		return err
+	}
+
+	comment := fmt.Sprintf("списано %s %s", payment.Amount.Decimal(), payment.Currency)
+	if payment.Amount.Less(payment.HeldAmount) {
+		comment += fmt.Sprintf(" из заблокированных %s", payment.HeldAmount.Decimal())
+	}
+	if order.Status.CanTransitionTo(types.Paid) {
+		return s.orderRepo.ChangeStatusTx(tx, order.ID, types.Paid, nil, comment)
+	}
+	return s.orderRepo.AddNoteTx(tx, order.ID, nil, comment)
+}
+
+// submitCapture отправляет шлюзу записанное списание и применяет ответ так же, как уведомление.
+// Если шлюз отказал (например, списание уже прошло или блокировка истекла), состояние платежа
+// берётся у шлюза.
+func (s *PaymentService) submitCapture(paymentID uuid.UUID) error {
+	payment, err := s.paymentRepo.GetById(paymentID)
+	if err != nil {
+		return err
+	}
+	if !payment.CapturePending {
+		return nil
+	}
+
+	receipt, err := s.receiptTx(s.paymentRepo.DB(), payment, payment.CaptureAmount, nil)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+
+	info, err := s.provider.CapturePayment(ctx, payment.PaymentID, payment.CaptureAmount, payment.Currency, receipt)
+	if errors.Is(err, provider.ErrRejected) {
+		log.Printf("payment %s: capture rejected by provider: %v", payment.ID, err)
+		info, err = s.provider.GetPayment(ctx, payment.PaymentID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		_, err := s.applyPaymentInfoTx(tx, info)
+		return err
+	})
+}
+
+// voidTx аннулирует блокировку у нас и помечает, что её ещё нужно снять у шлюза (releaseHold).
+// Запрос к шлюзу не делается в транзакции: она держит блокировки заказа и платежа.
+func (s *PaymentService) voidTx(tx *gorm.DB, payment *models.Payment, changedBy *uuid.UUID, comment string) error {
+	// списание уже отправлено шлюзу — снимать нечего
+	if payment.Status != types.PaymentStatusWaitingForCapture || payment.CapturePending {
+		return ErrPaymentNotHeld
+	}
+
+	payment.Status = types.PaymentStatusCanceled
+	payment.VoidPending = true
+	payment.UpdatedAt = time.Now()
+	if err := s.paymentRepo.UpdateTx(tx, payment); err != nil {
+		return err
+	}
+	return s.orderRepo.AddNoteTx(tx, payment.OrderID, changedBy, comment)
+}
+
+// releaseHold снимает у шлюза блокировку, аннулированную у нас. Повторный вызов безопасен:
+// если шлюз уже снял блокировку (или списания по платежу нет), отметка просто снимается.
+func (s *PaymentService) releaseHold(paymentID uuid.UUID) error {
+	payment, err := s.paymentRepo.GetById(paymentID)
+	if err != nil {
+		return err
+	}
+	if !payment.VoidPending {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+
+	_, err = s.provider.CancelPayment(ctx, payment.PaymentID)
+	if errors.Is(err, provider.ErrRejected) || errors.Is(err, provider.ErrPaymentNotFound) {
+		log.Printf("payment %s: release hold rejected by provider: %v", payment.ID, err)
+	} else if err != nil {
+		return err
+	}
+	return s.paymentRepo.ClearVoidPending(payment.ID)
+}
+
+// SettleOrder доводит до шлюза записанные по заказу операции: отправляет списания и возвраты,
+// снимает аннулированные блокировки. Вызывается после фиксации транзакции, которая их записала;
+// ошибки только логируются — неотправленное подберёт сверка.
+func (s *PaymentService) SettleOrder(orderID uuid.UUID) {
+	s.settle(&orderID, time.Now())
+}
+
+// settle отправляет шлюзу списания, возвраты и снятия блокировок, записанные раньше before;
+// orderID == nil — по всем заказам
+func (s *PaymentService) settle(orderID *uuid.UUID, before time.Time) int {
+	done := 0
+
+	captures, err := s.paymentRepo.GetCapturePending(orderID, before, reconcileBatch)
+	if err != nil {
+		log.Printf("payment settle: %v", err)
+	}
+	for _, payment := range captures {
+		if err := s.submitCapture(payment.ID); err != nil {
+			log.Printf("payment %s: capture: %v", payment.ID, err)
+			continue
+		}
+		done++
+	}
+
+	refunds, err := s.paymentRepo.GetUnsubmittedRefunds(orderID, before, reconcileBatch)
+	if err != nil {
+		log.Printf("payment settle: %v", err)
+	}
+	for _, refund := range refunds {
+		if err := s.submitRefund(refund.ID); err != nil {
+			log.Printf("refund %s: submit: %v", refund.ID, err)
+			continue
+		}
+		done++
+	}
+
+	payments, err := s.paymentRepo.GetVoidPending(orderID, before, reconcileBatch)
+	if err != nil {
+		log.Printf("payment settle: %v", err)
+	}
+	for _, payment := range payments {
+		if err := s.releaseHold(payment.ID); err != nil {
+			log.Printf("payment %s: release hold: %v", payment.ID, err)
+			continue
+		}
+		done++
+	}
+
+	return done
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/config"
+	"Market_backend/internal/payment/dto"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultBankTransferDeadline — срок оплаты по счёту, если не задан BANK_TRANSFER_DEADLINE_DAYS
+const DefaultBankTransferDeadline = 5 * 24 * time.Hour
+
+var (
+	ErrNotOfflinePayment         = errors.New("платёж не ожидает ручного подтверждения")
+	ErrCashOnDeliveryUnavailable = errors.New("оплата при получении доступна только для курьерской доставки и самовывоза из магазина")
+	ErrOrderNotAwaitingPayment   = errors.New("заказ не ожидает оплаты")
+)
+
+// bankTransferDeadline — срок оплаты счёта, выставленного в момент from
+func bankTransferDeadline(from time.Time) time.Time {
+	if days := utils.ParseInt(config.BankTransferDeadlineDays); days > 0 {
+		return from.AddDate(0, 0, days)
+	}
+	return from.Add(DefaultBankTransferDeadline)
+}
+
+// createOfflinePayment регистрирует ожидаемую офлайн-оплату. Шлюз не участвует: платёж подтверждает
+// администратор, когда деньги поступят. Оплата по счёту продлевает срок оплаты заказа,
+// при оплате при получении срок снимается — заказ оплачивается после доставки.
+func (s *PaymentService) createOfflinePayment(order *models.Order, method types.PaymentMethod) (*models.Payment, error) {
+	var payment *models.Payment
+	err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		var locked models.Order
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&locked, "id = ?", order.ID).Error; err != nil {
+			return err
+		}
+		if locked.Status != types.InProgress {
+			return ErrOrderNotAwaitingPayment
+		}
+		if method == types.PaymentMethodCashOnDelivery && locked.DeliveryMethod == types.DeliveryPickupPoint {
+			return ErrCashOnDeliveryUnavailable
+		}
+
+		due, err := s.dueTx(tx, locked.ID, locked.Total)
+		if err != nil {
+			return err
+		}
+
+		// повторный выбор того же способа возвращает уже созданный платёж
+		existing, err := s.paymentRepo.GetPendingByOrderTx(tx, locked.ID)
+		if err != nil {
+			return err
+		}
+		for i := range existing {
+			if existing[i].Method == method && existing[i].Amount.Equal(due) {
+				payment = &existing[i]
+				return nil
+			}
+		}
+
+		now := time.Now()
+		payment = &models.Payment{
+			ID:        uuid.New(),
+			OrderID:   locked.ID,
+			Method:    method,
+			Status:    types.PaymentStatusPending,
+			Amount:    due,
+			Currency:  "RUB",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := s.paymentRepo.CreateTx(tx, payment); err != nil {
+			return err
+		}
+
+		var deadline *time.Time
+		comment := "выбрана оплата при получении, срок оплаты снят"
+		if method == types.PaymentMethodBankTransfer {
+			due := bankTransferDeadline(now)
+			deadline = &due
+			comment = fmt.Sprintf("выбрана оплата по счёту, срок оплаты до %s", due.Format("02.01.2006 15:04"))
+		}
+		if err := tx.Model(&models.Order{}).Where("id = ?", locked.ID).Update("payment_deadline", deadline).Error; err != nil {
+			return err
+		}
+		order.PaymentDeadline = deadline
+		return s.orderRepo.AddNoteTx(tx, locked.ID, nil, comment)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// ConfirmOfflinePayment — администратор подтверждает поступление офлайн-оплаты по платёжному документу.
+// Заказ, ожидающий оплаты, становится оплаченным; заказ с оплатой при получении, уже переданный
+// покупателю, сохраняет свой статус — в истории остаётся отметка об оплате.
+func (s *PaymentService) ConfirmOfflinePayment(adminId, paymentID uuid.UUID, confirmDto dto.ConfirmOfflinePaymentDTO) (*models.Payment, error) {
+	if err := confirmDto.Validate(); err != nil {
+		return nil, err
+	}
+	documentDate, _ := confirmDto.Date()
+
+	var payment *models.Payment
+	err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		if payment, err = s.paymentRepo.GetByIdTx(tx, paymentID); err != nil {
+			return err
+		}
+		if !payment.Method.IsOffline() || payment.Status != types.PaymentStatusPending {
+			return ErrNotOfflinePayment
+		}
+		if confirmDto.Amount.IsPositive() && !confirmDto.Amount.Equal(payment.Amount) {
+			return fmt.Errorf("%w: поступило %s, ожидается %s", ErrAmountMismatch, confirmDto.Amount.Decimal(), payment.Amount.Decimal())
+		}
+
+		var order models.Order
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "status").
+			First(&order, "id = ?", payment.OrderID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		payment.Status = types.PaymentStatusSucceeded
+		payment.DocumentNumber = confirmDto.DocumentNumber
+		payment.DocumentDate = &documentDate
+		payment.ConfirmedByID = &adminId
+		payment.ConfirmedAt = &now
+		payment.UpdatedAt = now
+		if err := s.paymentRepo.UpdateTx(tx, payment); err != nil {
+			return err
+		}
+
+		comment := fmt.Sprintf("оплата подтверждена: документ №%s от %s", payment.DocumentNumber, documentDate.Format("02.01.2006"))
+		switch {
+		case order.Status.CanTransitionTo(types.Paid):
+			return s.orderRepo.ChangeStatusTx(tx, order.ID, types.Paid, &adminId, comment)
+		case payment.Method == types.PaymentMethodCashOnDelivery &&
+			(order.Status == types.Shipped || order.Status == types.Delivered || order.Status == types.Completed):
+			return s.orderRepo.AddNoteTx(tx, order.ID, &adminId, comment)
+		}
+		return ErrOrderNotAwaitingPayment
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// HasCashOnDeliveryTx — заказ оплачивается при получении: его можно отгружать до оплаты
+func (s *PaymentService) HasCashOnDeliveryTx(tx *gorm.DB, orderID uuid.UUID) (bool, error) {
+	var count int64
+	err := tx.Model(&models.Payment{}).
+		Where("order_id = ? AND method = ? AND status = ?", orderID, types.PaymentMethodCashOnDelivery, types.PaymentStatusPending).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -2,159 +2,495 @@ package service
 
 import (
 	"Market_backend/internal/common/types"
+	"Market_backend/internal/config"
+	LoyaltyService "Market_backend/internal/loyalty/service"
 	orderRepo "Market_backend/internal/order/repository"
+	"Market_backend/internal/payment/provider"
 	paymentRepo "Market_backend/internal/payment/repository"
 	"Market_backend/models"
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrOrderAwaitingApproval = errors.New("заказ ещё не согласован компанией")
+	ErrAmountMismatch        = errors.New("сумма оплаты не совпадает с заказом")
+	ErrPaymentDeadlinePassed = errors.New("срок оплаты заказа истёк")
+)
+
+// providerTimeout — общее время на операцию со шлюзом, включая повторы
+const providerTimeout = 30 * time.Second
+
 type PaymentService struct {
-	paymentRepo *paymentRepo.PaymentRepository
-	orderRepo   *orderRepo.OrderRepository
+	paymentRepo    *paymentRepo.PaymentRepository
+	orderRepo      *orderRepo.OrderRepository
+	provider       provider.PaymentProvider
+	loyaltyService *LoyaltyService.LoyaltyService
 }
 
-func NewPaymentService(paymentRepo *paymentRepo.PaymentRepository, orderRepo *orderRepo.OrderRepository) *PaymentService {
-	return &PaymentService{paymentRepo: paymentRepo, orderRepo: orderRepo}
+func NewPaymentService(
+	paymentRepo *paymentRepo.PaymentRepository,
+	orderRepo *orderRepo.OrderRepository,
+	provider provider.PaymentProvider,
+	loyaltyService *LoyaltyService.LoyaltyService,
+) *PaymentService {
+	return &PaymentService{paymentRepo: paymentRepo, orderRepo: orderRepo, provider: provider, loyaltyService: loyaltyService}
 }
 
-// CreatePayment создаёт Payment и возвращает confirmation_url
-func (s *PaymentService) CreatePayment(order *models.Order, method types.PaymentMethod) (*models.Payment, string, error) {
+// CreatePayment создаёт Payment и возвращает confirmation_url.
+// Для офлайн-оплаты (по счёту, при получении) шлюз не вызывается и ссылка пустая.
+// Оплата сохранённой картой проходит без подтверждения покупателем — ссылка тоже пустая.
+// Подарочная карта и баланс покупателя покрывают часть суммы, остаток оплачивается выбранным способом.
+func (s *PaymentService) CreatePayment(order *models.Order, method types.PaymentMethod, opts CreatePaymentOptions) (*models.Payment, string, error) {
+	if order.Status == types.AwaitingApproval {
+		return nil, "", ErrOrderAwaitingApproval
+	}
+	if order.PaymentDeadline != nil && time.Now().After(*order.PaymentDeadline) {
+		return nil, "", ErrPaymentDeadlinePassed
+	}
+
+	held, err := s.HasHoldTx(s.paymentRepo.DB(), order.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if held {
+		return nil, "", ErrOrderAlreadyHeld
+	}
+
+	// Подарочная карта и баланс списываются первыми; если их хватило, шлюз не нужен
+	if opts.GiftCardCode != "" || opts.UseStoreCredit {
+		payment, due, err := s.payWithBalances(order, opts)
+		if err != nil {
+			return nil, "", err
+		}
+		if !due.IsPositive() {
+			return payment, "", nil
+		}
+	}
+
+	if method.IsOffline() {
+		payment, err := s.createOfflinePayment(order, method)
+		return payment, "", err
+	}
+
+	saved, err := s.savedMethodFor(order, method, opts)
+	if err != nil {
+		return nil, "", err
+	}
+
 	payment := &models.Payment{
 		ID:        uuid.New(),
 		OrderID:   order.ID,
 		Method:    method,
 		Status:    types.PaymentStatusPending,
-		Amount:    order.Total,
 		Currency:  "RUB",
+		TwoStage:  requiresHold(order),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+
+		SaveMethod: opts.SaveMethod && saved == nil,
+	}
+	providerMethodID := ""
+	if saved != nil {
+		payment.SavedMethodID = &saved.ID
+		providerMethodID = saved.ProviderMethodID
+	}
+
+	// Сохраняем запись Payment в БД под блокировкой заказа: уже оплаченный заказ второй раз не оплачивается
+	err = s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		var locked models.Order
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&locked, "id = ?", order.ID).Error; err != nil {
+			return err
+		}
+		if locked.Status != types.InProgress {
+			return ErrOrderNotAwaitingPayment
+		}
+		held, err := s.HasHoldTx(tx, locked.ID)
+		if err != nil {
+			return err
+		}
+		if held {
+			return ErrOrderAlreadyHeld
+		}
+
+		if payment.Amount, err = s.dueTx(tx, locked.ID, locked.Total); err != nil {
+			return err
+		}
+		if !payment.Amount.IsPositive() {
+			return ErrOrderNotAwaitingPayment
+		}
+		return s.paymentRepo.CreateTx(tx, payment)
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
-	// Сохраняем запись Payment в БД
-	if err := s.paymentRepo.Create(payment); err != nil {
+	receipt, err := s.receiptTx(s.paymentRepo.DB(), payment, payment.Amount, nil)
+	if err != nil {
 		return nil, "", err
 	}
 
-	// Создание платежа через API ЮKassa
-	confirmationURL, paymentID, err := createYooKassaPayment(payment)
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+
+	// ID нашего платежа — ключ идемпотентности: повтор запроса не создаст второй платёж у шлюза
+	info, err := s.provider.CreatePayment(ctx, provider.CreatePaymentRequest{
+		IdempotencyKey: payment.ID.String(),
+		OrderID:        order.ID,
+		Amount:         payment.Amount,
+		Currency:       payment.Currency,
+		Method:         method,
+		Description:    fmt.Sprintf("Заказ №%d", order.OrderNumber),
+		ReturnURL:      config.FrontendURL + "/profile?tab=orders",
+		Metadata:       map[string]string{"order_id": order.ID.String(), "payment_id": payment.ID.String()},
+		Capture:        !payment.TwoStage,
+		Receipt:        receipt,
+
+		SavePaymentMethod: payment.SaveMethod,
+		PaymentMethodID:   providerMethodID,
+	})
 	if err != nil {
+		payment.Status = types.PaymentStatusCanceled
+		payment.UpdatedAt = time.Now()
+		if updErr := s.paymentRepo.Update(payment); updErr != nil {
+			log.Printf("payment %s: %v", payment.ID, updErr)
+		}
 		return nil, "", err
 	}
 
-	// Сохраняем paymentID, полученный от ЮKassa
-	payment.PaymentID = paymentID
-	if err := s.paymentRepo.Update(payment); err != nil {
+	// Сохраняем ID платежа у шлюза
+	payment.PaymentID = info.ID
+	payment.ReceiptStatus = info.ReceiptStatus
+	if err := s.paymentRepo.SetProviderID(payment.ID, info.ID, info.ReceiptStatus); err != nil {
 		return nil, "", err
 	}
 
-	return payment, confirmationURL, nil
+	// Сохранённой картой шлюз проводит платёж сразу — применяем результат, не дожидаясь вебхука
+	if saved != nil && info.Status != types.PaymentStatusPending {
+		err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+			if _, err := s.applyPaymentInfoTx(tx, info); err != nil {
+				return err
+			}
+			return s.paymentRepo.TouchSavedMethodTx(tx, saved.ID, time.Now())
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		s.SettleOrder(order.ID)
+		if updated, err := s.paymentRepo.GetByPaymentID(info.ID); err == nil {
+			payment = updated
+		}
+	}
+
+	return payment, info.ConfirmationURL, nil
 }
 
-// UpdatePaymentStatus обновляет статус Payment и связанного заказа
-func (s *PaymentService) UpdatePaymentStatus(paymentID string, status types.PaymentStatus) error {
-	payment, err := s.paymentRepo.GetByPaymentID(paymentID)
-	if err != nil {
+// HandleWebhook проверяет уведомление шлюза, сохраняет его в журнал и применяет ровно один раз.
+// Повторная доставка уже обработанного события ничего не меняет.
+func (s *PaymentService) HandleWebhook(header func(key string) string, body []byte) error {
+	event, err := s.provider.ParseWebhook(header, body)
+	// подлинное уведомление с неразборчивой суммой записывается в журнал отклонённым, а не применяется
+	invalid := errors.Is(err, provider.ErrInvalidAmount) && event != nil
+	if err != nil && !invalid {
 		return err
 	}
+	parseErr := err
 
-	payment.Status = status
-	payment.UpdatedAt = time.Now()
-	if err := s.paymentRepo.Update(payment); err != nil {
+	objectID := ""
+	switch {
+	case event.Refund != nil:
+		objectID = event.Refund.ID
+	case event.Payment != nil:
+		objectID = event.Payment.ID
+	}
+
+	record, err := s.paymentRepo.RecordEvent(&models.PaymentEvent{
+		ID:         uuid.New(),
+		Provider:   s.provider.Name(),
+		Event:      event.Event,
+		ObjectID:   objectID,
+		Status:     types.PaymentEventReceived,
+		Payload:    string(body),
+		Deliveries: 1,
+		ReceivedAt: time.Now(),
+	})
+	if err != nil {
 		return err
 	}
+	if record.Status.IsFinal() {
+		return nil
+	}
+	if invalid {
+		log.Printf("payment webhook %s %s: %v", event.Event, objectID, parseErr)
+		return s.paymentRepo.FinishEventTx(s.paymentRepo.DB(), record.ID, types.PaymentEventRejected, parseErr.Error())
+	}
+
+	err = s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		locked, err := s.paymentRepo.GetEventTx(tx, record.ID)
+		if err != nil {
+			return err
+		}
+		// дубль, доставленный параллельно, уже обработан
+		if locked.Status.IsFinal() {
+			return nil
+		}
 
-	if status == types.PaymentStatusSucceeded {
-		return s.orderRepo.ChangeStatus(payment.OrderID, types.Paid)
+		applied := false
+		switch {
+		case event.Refund != nil:
+			applied, err = s.updateRefundStatusTx(tx, event.Refund)
+		case event.Payment != nil:
+			applied, err = s.applyPaymentInfoTx(tx, event.Payment)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !applied {
+			return s.paymentRepo.FinishEventTx(tx, record.ID, types.PaymentEventIgnored, "")
+		}
+		return s.paymentRepo.FinishEventTx(tx, record.ID, types.PaymentEventProcessed, "")
+	})
+
+	if errors.Is(err, ErrAmountMismatch) {
+		// повтор не поможет: фиксируем и отвечаем шлюзу 200, платёж разбирается вручную
+		log.Printf("payment webhook %s %s: %v", event.Event, objectID, err)
+		return s.paymentRepo.FinishEventTx(s.paymentRepo.DB(), record.ID, types.PaymentEventRejected, err.Error())
+	}
+	if err != nil {
+		if failErr := s.paymentRepo.FailEvent(record.ID, err.Error()); failErr != nil {
+			log.Printf("payment event %s: %v", record.ID, failErr)
+		}
+		return err
 	}
 
+	// оплата отменённого заказа записала возврат или снятие блокировки — отправляем шлюзу
+	if event.Payment != nil {
+		if orderID, err := uuid.Parse(event.Payment.Metadata["order_id"]); err == nil {
+			s.SettleOrder(orderID)
+		}
+	}
 	return nil
 }
 
-type YooKassaRequest struct {
-	Amount struct {
-		Value    string `json:"value"`
-		Currency string `json:"currency"`
-	} `json:"amount"`
-	PaymentMethodData struct {
-		Type string `json:"type"` // "bank_card" или "sbp"
-	} `json:"payment_method_data"`
-	Confirmation struct {
-		Type      string `json:"type"`       // "redirect"
-		ReturnURL string `json:"return_url"` // URL возврата после оплаты
-	} `json:"confirmation"`
-	Description string            `json:"description"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
-	Test        bool              `json:"test"` // для тестового режима
-}
+// applyPaymentInfoTx применяет состояние платежа у шлюза к локальному платежу и заказу.
+// Статус меняется только вперёд; false — применять нечего (повтор, устаревший статус, чужой платёж).
+func (s *PaymentService) applyPaymentInfoTx(tx *gorm.DB, info *provider.PaymentInfo) (bool, error) {
+	payment, err := s.lockPaymentByInfoTx(tx, info)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
 
-type YooKassaResponse struct {
-	ID           string `json:"id"`
-	Confirmation struct {
-		ConfirmationURL string `json:"confirmation_url"`
-	} `json:"confirmation"`
-}
+	// Платёж аннулирован после изменения заказа, но покупатель успел оплатить старую сумму —
+	// возвращаем деньги, заказ остаётся неоплаченным
+	if payment.Status == types.PaymentStatusCanceled && info.Status == types.PaymentStatusSucceeded {
+		_, err := s.refundTx(tx, payment.ID, payment.Amount, "платёж аннулирован: заказ изменён", nil, nil, false)
+		return err == nil, err
+	}
 
-func createYooKassaPayment(payment *models.Payment) (string, string, error) {
-	shopID := os.Getenv("YKASSA_SHOP_ID")
-	secretKey := os.Getenv("YKASSA_SECRET_KEY")
-	testMode := os.Getenv("YKASSA_TEST_MODE") == "true"
+	// То же для двухстадийного платежа: деньги только заблокированы — блокировку снимет SettleOrder
+	if payment.Status == types.PaymentStatusCanceled && info.Status == types.PaymentStatusWaitingForCapture {
+		if payment.VoidPending {
+			return false, nil
+		}
+		payment.VoidPending = true
+		payment.UpdatedAt = time.Now()
+		return true, s.paymentRepo.UpdateTx(tx, payment)
+	}
 
-	frontend_url := os.Getenv("FRONTEND_URL")
+	// Статус регистрации чека может прийти и без смены статуса платежа
+	receiptChanged := info.ReceiptStatus != "" && info.ReceiptStatus != payment.ReceiptStatus
+	if receiptChanged {
+		payment.ReceiptStatus = info.ReceiptStatus
+	}
 
-	reqBody := YooKassaRequest{}
-	reqBody.Amount.Value = fmt.Sprintf("%.2f", payment.Amount)
-	reqBody.Amount.Currency = payment.Currency
-	reqBody.PaymentMethodData.Type = string(payment.Method) // "bank_card" или "sbp"
-	reqBody.Confirmation.Type = "redirect"
-	reqBody.Confirmation.ReturnURL = frontend_url + "/profile?tab=orders" // URL возврата
-	reqBody.Description = fmt.Sprintf("Order %s", payment.OrderID)
-	reqBody.Metadata = map[string]string{"order_id": payment.OrderID.String()}
-	reqBody.Test = testMode
+	if !payment.Status.CanTransitionTo(info.Status) {
+		if receiptChanged {
+			return true, s.paymentRepo.UpdateTx(tx, payment)
+		}
+		return false, nil
+	}
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", "", err
+	captured := payment.Status == types.PaymentStatusWaitingForCapture && info.Status == types.PaymentStatusSucceeded
+	switch {
+	case captured:
+		// списание сделано мимо нас (например, в личном кабинете шлюза) — сумма может быть меньше блокировки
+		if payment.HeldAmount.Less(info.Amount) {
+			return false, fmt.Errorf("%w: списано %s, заблокировано %s", ErrAmountMismatch, info.Amount.Decimal(), payment.HeldAmount.Decimal())
+		}
+		payment.Amount = info.Amount
+	case info.Status == types.PaymentStatusSucceeded, info.Status == types.PaymentStatusWaitingForCapture:
+		if err := s.verifyAmountTx(tx, payment, info); err != nil {
+			return false, err
+		}
 	}
 
-	url := "https://api.yookassa.ru/v3/payments"
+	previous := payment.Status
+	payment.Status = info.Status
+	payment.UpdatedAt = time.Now()
+	if info.Status == types.PaymentStatusWaitingForCapture {
+		payment.HeldAmount = payment.Amount
+	} else {
+		// блокировка списана или снята — отправлять шлюзу больше нечего
+		payment.CapturePending = false
+	}
+	if err := s.paymentRepo.UpdateTx(tx, payment); err != nil {
+		return false, err
+	}
+	if info.Status == types.PaymentStatusSucceeded || info.Status == types.PaymentStatusWaitingForCapture {
+		if err := s.saveMethodTx(tx, payment, info); err != nil {
+			return false, err
+		}
+	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		return "", "", err
+	switch {
+	case captured:
+		return true, s.capturedTx(tx, payment)
+	case info.Status == types.PaymentStatusWaitingForCapture:
+		var order models.Order
+		if err := tx.Select("id", "status").First(&order, "id = ?", payment.OrderID).Error; err != nil {
+			return false, err
+		}
+		if order.Status != types.InProgress {
+			return true, s.voidTx(tx, payment, nil, "заказ отменён до блокировки оплаты: блокировка снята")
+		}
+		return true, s.orderRepo.AddNoteTx(tx, payment.OrderID, nil,
+			fmt.Sprintf("%s %s заблокировано на карте, списание при отгрузке", payment.Amount.Decimal(), payment.Currency))
+	case info.Status == types.PaymentStatusCanceled && previous == types.PaymentStatusWaitingForCapture:
+		return true, s.orderRepo.AddNoteTx(tx, payment.OrderID, nil, "блокировка оплаты снята платёжным шлюзом")
+	case info.Status != types.PaymentStatusSucceeded:
+		return true, nil
 	}
 
-	req.SetBasicAuth(shopID, secretKey)
-	req.Header.Set("Content-Type", "application/json")
+	var order models.Order
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status").
+		First(&order, "id = ?", payment.OrderID).Error; err != nil {
+		return false, err
+	}
 
-	// Idempotence-Key — уникальный ключ для предотвращения двойной оплаты
-	idempotenceKey := uuid.New().String()
-	req.Header.Set("Idempotence-Key", idempotenceKey)
+	if order.Status.CanTransitionTo(types.Paid) {
+		return true, s.orderRepo.ChangeStatusTx(tx, order.ID, types.Paid, nil, "оплата подтверждена платёжным шлюзом")
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	// Заказ отменён, пока покупатель платил, или уже оплачен другим платежом — деньги возвращаем
+	reason := "заказ отменён до поступления оплаты"
+	if order.Status != types.Cancelled && order.Status != types.Failed {
+		reason = "заказ уже оплачен другим платежом"
+	}
+	_, err = s.refundTx(tx, payment.ID, payment.Amount, reason, nil, nil, false)
+	return err == nil, err
+}
+
+// lockPaymentByInfoTx ищет платёж по ID у шлюза, а если уведомление пришло раньше,
+// чем этот ID сохранён, — по нашему ID из метаданных
+func (s *PaymentService) lockPaymentByInfoTx(tx *gorm.DB, info *provider.PaymentInfo) (*models.Payment, error) {
+	payment, err := s.paymentRepo.GetByPaymentIDTx(tx, info.ID)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return payment, err
+	}
+
+	id, parseErr := uuid.Parse(info.Metadata["payment_id"])
+	if parseErr != nil {
+		return nil, err
+	}
+	payment, err = s.paymentRepo.GetByIdTx(tx, id)
 	if err != nil {
-		return "", "", err
+		return nil, err
+	}
+	if payment.PaymentID != "" && payment.PaymentID != info.ID {
+		return nil, gorm.ErrRecordNotFound
 	}
-	defer resp.Body.Close()
+	payment.PaymentID = info.ID
+	return payment, nil
+}
 
-	body, _ := ioutil.ReadAll(resp.Body)
+// verifyAmountTx сверяет сумму, списанную шлюзом, с платежом и итогом заказа
+func (s *PaymentService) verifyAmountTx(tx *gorm.DB, payment *models.Payment, info *provider.PaymentInfo) error {
+	if !info.Amount.Equal(payment.Amount) || (info.Currency != "" && info.Currency != payment.Currency) {
+		return fmt.Errorf("%w: шлюз %s %s, платёж %s %s",
+			ErrAmountMismatch, info.Amount.Decimal(), info.Currency, payment.Amount.Decimal(), payment.Currency)
+	}
 
-	if resp.StatusCode >= 400 {
-		return "", "", fmt.Errorf("YooKassa error: %s", string(body))
+	var order models.Order
+	if err := tx.Select("id", "total").First(&order, "id = ?", payment.OrderID).Error; err != nil {
+		return err
 	}
+	due, err := s.dueTx(tx, order.ID, order.Total)
+	if err != nil {
+		return err
+	}
+	if !due.Equal(payment.Amount) {
+		return fmt.Errorf("%w: платёж %s, к оплате по заказу %s", ErrAmountMismatch, payment.Amount.Decimal(), due.Decimal())
+	}
+	return nil
+}
+
+// SyncPendingPayments запрашивает у шлюза состояние ожидающих оплаты платежей заказа перед его
+// отменой. Если платёж всё-таки прошёл или деньги заблокированы, это применяется к заказу
+// и возвращается true. Вызывается вне транзакции: запросы к шлюзу не держат блокировки.
+func (s *PaymentService) SyncPendingPayments(orderID uuid.UUID) (bool, error) {
+	payments, err := s.paymentRepo.GetPendingByOrder(orderID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, payment := range payments {
+		// офлайн-платёж и платёж, не дошедший до шлюза, отменяются только у нас
+		if payment.PaymentID == "" || payment.Method.IsOffline() {
+			continue
+		}
 
-	var ykResp YooKassaResponse
-	if err := json.Unmarshal(body, &ykResp); err != nil {
-		return "", "", err
+		ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+		info, err := s.provider.GetPayment(ctx, payment.PaymentID)
+		cancel()
+		if err != nil {
+			if !errors.Is(err, provider.ErrPaymentNotFound) {
+				log.Printf("payment %s: get from provider: %v", payment.ID, err)
+			}
+			continue
+		}
+		// неоплаченный pending истечёт у шлюза сам
+		if info.Status != types.PaymentStatusSucceeded && info.Status != types.PaymentStatusWaitingForCapture {
+			continue
+		}
+
+		applied := false
+		err = s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+			var err error
+			applied, err = s.applyPaymentInfoTx(tx, info)
+			return err
+		})
+		if err != nil {
+			return false, err
+		}
+		if applied {
+			return true, nil
+		}
 	}
 
-	return ykResp.Confirmation.ConfirmationURL, ykResp.ID, nil
+	return false, nil
+}
+
+// InvalidatePendingTx аннулирует ожидающие оплаты платежи заказа, например после изменения его суммы
+func (s *PaymentService) InvalidatePendingTx(tx *gorm.DB, orderID uuid.UUID) (int64, error) {
+	return s.paymentRepo.CancelPendingByOrderTx(tx, orderID)
+}
+
+// GetEvents — журнал уведомлений шлюза для разбора инцидентов
+func (s *PaymentService) GetEvents(status types.PaymentEventStatus, objectID string, page, limit int) ([]models.PaymentEvent, int64, error) {
+	return s.paymentRepo.GetEvents(status, objectID, limit, (page-1)*limit)
 }
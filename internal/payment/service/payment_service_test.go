@@ -0,0 +1,363 @@
+package service
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/config"
+	LoyaltyRepo "Market_backend/internal/loyalty/repository"
+	LoyaltyService "Market_backend/internal/loyalty/service"
+	orderRepo "Market_backend/internal/order/repository"
+	"Market_backend/internal/payment/dto"
+	"Market_backend/internal/payment/provider"
+	paymentRepo "Market_backend/internal/payment/repository"
+	"Market_backend/models"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "test-secret"
+
+var initTestDB sync.Once
+
+// testGateway — FakeProvider, уведомления которого не уходят по HTTP, а копятся до deliver
+type testGateway struct {
+	*provider.FakeProvider
+
+	mu     sync.Mutex
+	events [][]byte
+}
+
+// deliver передаёт накопленные уведомления в HandleWebhook так же, как их доставил бы шлюз
+func (g *testGateway) deliver(t *testing.T, s *PaymentService) {
+	t.Helper()
+
+	g.mu.Lock()
+	events := g.events
+	g.events = nil
+	g.mu.Unlock()
+
+	for _, body := range events {
+		header := func(key string) string {
+			if key == provider.SignatureHeader {
+				return provider.Sign(testWebhookSecret, body)
+			}
+			return ""
+		}
+		if err := s.HandleWebhook(header, body); err != nil {
+			t.Fatalf("webhook %s: %v", body, err)
+		}
+	}
+}
+
+// newTestService поднимает сервис на базе TEST_DATABASE_DSN с FakeProvider.
+// Тесты пишут в базу, поэтому нужна отдельная; без TEST_DATABASE_DSN они пропускаются.
+func newTestService(t *testing.T) (*PaymentService, *testGateway) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN не задан")
+	}
+	initTestDB.Do(func() {
+		config.Cfg.DBUrl = dsn
+		common.InitDB()
+	})
+
+	fiscal, hold := config.FiscalReceipts, config.HoldPaymentMinTotal
+	config.FiscalReceipts = "false"
+	config.HoldPaymentMinTotal = ""
+	t.Cleanup(func() {
+		config.FiscalReceipts, config.HoldPaymentMinTotal = fiscal, hold
+	})
+
+	gateway := &testGateway{FakeProvider: provider.NewFakeProvider("http://localhost", testWebhookSecret)}
+	gateway.Emit = func(event string, body []byte) error {
+		gateway.mu.Lock()
+		defer gateway.mu.Unlock()
+		gateway.events = append(gateway.events, body)
+		return nil
+	}
+
+	loyaltyService := LoyaltyService.NewLoyaltyService(LoyaltyRepo.NewLoyaltyRepository())
+	s := NewPaymentService(paymentRepo.NewPaymentRepository(), orderRepo.NewOrderRepository(), gateway, loyaltyService)
+	return s, gateway
+}
+
+// newTestOrder создаёт покупателя и ожидающий оплаты заказ на total
+func newTestOrder(t *testing.T, total money.Money) *models.Order {
+	t.Helper()
+
+	user := &models.User{
+		Name:   "Тест",
+		Email:  uuid.NewString() + "@example.com",
+		CartID: uuid.New(),
+	}
+	if err := common.DB.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	order := &models.Order{
+		ID:     uuid.New(),
+		UserID: user.ID,
+		Status: types.InProgress,
+		Total:  total,
+	}
+	if err := common.DB.Omit("User").Create(order).Error; err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	return order
+}
+
+func orderStatus(t *testing.T, orderID uuid.UUID) types.OrderStatus {
+	t.Helper()
+
+	var order models.Order
+	if err := common.DB.Select("id", "status").First(&order, "id = ?", orderID).Error; err != nil {
+		t.Fatalf("get order: %v", err)
+	}
+	return order.Status
+}
+
+// hasNote — в истории заказа есть запись, содержащая text
+func hasNote(t *testing.T, orderID uuid.UUID, text string) bool {
+	t.Helper()
+
+	var history []models.OrderStatusHistory
+	if err := common.DB.Where("order_id = ?", orderID).Find(&history).Error; err != nil {
+		t.Fatalf("get history: %v", err)
+	}
+	for _, entry := range history {
+		if strings.Contains(entry.Comment, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// payByCard создаёт платёж картой, подтверждает его у шлюза и доставляет уведомление
+func payByCard(t *testing.T, s *PaymentService, gateway *testGateway, order *models.Order) *models.Payment {
+	t.Helper()
+
+	payment, _, err := s.CreatePayment(order, types.PaymentMethodCard, CreatePaymentOptions{})
+	if err != nil {
+		t.Fatalf("create payment: %v", err)
+	}
+	if _, err := gateway.Confirm(payment.PaymentID); err != nil {
+		t.Fatalf("confirm payment: %v", err)
+	}
+	gateway.deliver(t, s)
+
+	payment, err = s.paymentRepo.GetById(payment.ID)
+	if err != nil {
+		t.Fatalf("get payment: %v", err)
+	}
+	return payment
+}
+
+// Вторая оплата уже оплаченного заказа не создаётся, а оплата по ссылке, открытой до первой, возвращается
+func TestDoublePaymentIsRefunded(t *testing.T) {
+	s, gateway := newTestService(t)
+	order := newTestOrder(t, money.New(150000))
+
+	// покупатель открыл оплату в двух вкладках
+	first, _, err := s.CreatePayment(order, types.PaymentMethodCard, CreatePaymentOptions{})
+	if err != nil {
+		t.Fatalf("create first payment: %v", err)
+	}
+	second, _, err := s.CreatePayment(order, types.PaymentMethodCard, CreatePaymentOptions{})
+	if err != nil {
+		t.Fatalf("create second payment: %v", err)
+	}
+
+	if _, err := gateway.Confirm(first.PaymentID); err != nil {
+		t.Fatalf("confirm first payment: %v", err)
+	}
+	gateway.deliver(t, s)
+	if status := orderStatus(t, order.ID); status != types.Paid {
+		t.Fatalf("order status = %s, want %s", status, types.Paid)
+	}
+
+	if _, _, err := s.CreatePayment(order, types.PaymentMethodCard, CreatePaymentOptions{}); !errors.Is(err, ErrOrderNotAwaitingPayment) {
+		t.Fatalf("payment for a paid order: err = %v, want %v", err, ErrOrderNotAwaitingPayment)
+	}
+
+	if _, err := gateway.Confirm(second.PaymentID); err != nil {
+		t.Fatalf("confirm second payment: %v", err)
+	}
+	gateway.deliver(t, s)
+
+	refunds, err := s.GetOrderRefunds(order.ID)
+	if err != nil {
+		t.Fatalf("get refunds: %v", err)
+	}
+	if len(refunds) != 1 {
+		t.Fatalf("refunds = %d, want 1", len(refunds))
+	}
+	refund := refunds[0]
+	if refund.PaymentID != second.ID || !refund.Amount.Equal(second.Amount) {
+		t.Fatalf("refund %s of payment %s, want %s of payment %s", refund.Amount, refund.PaymentID, second.Amount, second.ID)
+	}
+	if refund.Status != types.RefundSucceeded {
+		t.Fatalf("refund status = %s, want %s", refund.Status, types.RefundSucceeded)
+	}
+	if status := orderStatus(t, order.ID); status != types.Paid {
+		t.Fatalf("order status after refund = %s, want %s", status, types.Paid)
+	}
+}
+
+// Возврат, отклонённый шлюзом, отменяется, а причина отказа остаётся в истории заказа
+func TestRejectedRefundIsCanceled(t *testing.T) {
+	s, gateway := newTestService(t)
+	order := newTestOrder(t, money.New(50000))
+	payment := payByCard(t, s, gateway, order)
+
+	// деньги уже вернули в личном кабинете шлюза — наш возврат превысит остаток
+	if _, err := gateway.Refund(context.Background(), provider.RefundRequest{
+		PaymentID: payment.PaymentID,
+		Amount:    payment.Amount,
+		Currency:  payment.Currency,
+	}); err != nil {
+		t.Fatalf("refund at provider: %v", err)
+	}
+	gateway.mu.Lock()
+	gateway.events = nil
+	gateway.mu.Unlock()
+
+	refunds, err := s.CreateRefund(order.UserID, dto.CreateRefundDTO{OrderID: order.ID, Reason: "возврат"})
+	if err != nil {
+		t.Fatalf("create refund: %v", err)
+	}
+	if len(refunds) != 1 {
+		t.Fatalf("refunds = %d, want 1", len(refunds))
+	}
+	if refunds[0].Status != types.RefundCanceled {
+		t.Fatalf("refund status = %s, want %s", refunds[0].Status, types.RefundCanceled)
+	}
+	if !hasNote(t, order.ID, "exceeds remaining") {
+		t.Fatal("order history has no note with the provider error")
+	}
+
+	// отменённый возврат не занимает сумму платежа
+	payment, err = s.paymentRepo.GetById(payment.ID)
+	if err != nil {
+		t.Fatalf("get payment: %v", err)
+	}
+	available, err := s.refundableTx(common.DB, payment)
+	if err != nil {
+		t.Fatalf("refundable: %v", err)
+	}
+	if !available.Equal(payment.Amount) {
+		t.Fatalf("refundable = %s, want %s", available, payment.Amount)
+	}
+}
+
+// Списание блокировки записывается в транзакции, отправляется шлюзу после неё и переводит заказ в оплаченные
+func TestCaptureHold(t *testing.T) {
+	s, gateway := newTestService(t)
+	config.HoldPaymentMinTotal = "1000"
+	order := newTestOrder(t, money.New(200000))
+
+	payment := payByCard(t, s, gateway, order)
+	if payment.Status != types.PaymentStatusWaitingForCapture || !payment.HeldAmount.Equal(order.Total) {
+		t.Fatalf("payment %s held %s, want %s held %s",
+			payment.Status, payment.HeldAmount, types.PaymentStatusWaitingForCapture, order.Total)
+	}
+
+	err := common.DB.Transaction(func(tx *gorm.DB) error {
+		held, err := s.CaptureOrderHoldTx(tx, order.ID, nil)
+		if err == nil && !held {
+			err = errors.New("no hold")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("capture order hold: %v", err)
+	}
+
+	// до фиксации шлюз ничего не получил: у него платёж по-прежнему заблокирован
+	info, err := gateway.GetPayment(context.Background(), payment.PaymentID)
+	if err != nil {
+		t.Fatalf("get payment at provider: %v", err)
+	}
+	if info.Status != types.PaymentStatusWaitingForCapture {
+		t.Fatalf("provider status before settle = %s, want %s", info.Status, types.PaymentStatusWaitingForCapture)
+	}
+	pending, err := s.paymentRepo.GetById(payment.ID)
+	if err != nil {
+		t.Fatalf("get payment: %v", err)
+	}
+	if !pending.CapturePending || !pending.CaptureAmount.Equal(order.Total) {
+		t.Fatalf("capture pending = %v for %s, want true for %s", pending.CapturePending, pending.CaptureAmount, order.Total)
+	}
+
+	s.SettleOrder(order.ID)
+	// уведомление о списании приходит повторно и ничего не меняет
+	gateway.deliver(t, s)
+
+	captured, err := s.paymentRepo.GetById(payment.ID)
+	if err != nil {
+		t.Fatalf("get payment: %v", err)
+	}
+	if captured.Status != types.PaymentStatusSucceeded || captured.CapturePending {
+		t.Fatalf("payment %s, capture pending %v; want %s, false", captured.Status, captured.CapturePending, types.PaymentStatusSucceeded)
+	}
+	if status := orderStatus(t, order.ID); status != types.Paid {
+		t.Fatalf("order status = %s, want %s", status, types.Paid)
+	}
+}
+
+// Если заказ отменили, пока списание шло к шлюзу, списанные деньги возвращаются
+func TestCaptureOfCancelledOrderIsRefunded(t *testing.T) {
+	s, gateway := newTestService(t)
+	config.HoldPaymentMinTotal = "1000"
+	order := newTestOrder(t, money.New(200000))
+
+	payment := payByCard(t, s, gateway, order)
+	if _, err := s.CapturePayment(order.UserID, payment.ID, money.New(150000)); err != nil {
+		t.Fatalf("capture payment: %v", err)
+	}
+	gateway.deliver(t, s)
+
+	captured, err := s.paymentRepo.GetById(payment.ID)
+	if err != nil {
+		t.Fatalf("get payment: %v", err)
+	}
+	if captured.Status != types.PaymentStatusSucceeded || !captured.Amount.Equal(money.New(150000)) {
+		t.Fatalf("payment %s for %s, want %s for 1500.00", captured.Status, captured.Amount, types.PaymentStatusSucceeded)
+	}
+	if !hasNote(t, order.ID, "из заблокированных") {
+		t.Fatal("order history has no note about the partial capture")
+	}
+
+	// второй заказ: отмена успевает между записью списания и ответом шлюза
+	order = newTestOrder(t, money.New(200000))
+	payment = payByCard(t, s, gateway, order)
+
+	err = common.DB.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.CaptureOrderHoldTx(tx, order.ID, nil); err != nil {
+			return err
+		}
+		return tx.Model(&models.Order{}).Where("id = ?", order.ID).Update("status", types.Cancelled).Error
+	})
+	if err != nil {
+		t.Fatalf("capture and cancel: %v", err)
+	}
+	s.SettleOrder(order.ID)
+	gateway.deliver(t, s)
+
+	refunds, err := s.GetOrderRefunds(order.ID)
+	if err != nil {
+		t.Fatalf("get refunds: %v", err)
+	}
+	if len(refunds) != 1 || refunds[0].Status != types.RefundSucceeded || !refunds[0].Amount.Equal(order.Total) {
+		t.Fatalf("refunds = %+v, want one succeeded refund of %s", refunds, order.Total)
+	}
+}
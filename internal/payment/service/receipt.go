@@ -0,0 +1,166 @@
+package service
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/config"
+	"Market_backend/internal/payment/provider"
+	"Market_backend/models"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const defaultVATRate = 20.0
+
+// fiscalReceipts — чеки 54-ФЗ передаются шлюзу, если не выключены FISCAL_RECEIPTS=false
+func fiscalReceipts() bool {
+	return config.FiscalReceipts != "false"
+}
+
+// vatCode — код ставки НДС ЮKassa по VAT_RATE. При предоплате по 54-ФЗ применяется расчётная ставка (20/120).
+func vatCode(prepayment bool) int {
+	rate := defaultVATRate
+	switch strings.TrimSpace(config.VATRate) {
+	case "":
+	case "none":
+		return 1
+	default:
+		rate = utils.ParseFloat(config.VATRate)
+	}
+
+	codes := map[float64][2]int{
+		0:  {2, 2},
+		5:  {7, 9},
+		7:  {8, 10},
+		10: {3, 5},
+		20: {4, 6},
+	}
+	code, ok := codes[rate]
+	if !ok {
+		code = codes[defaultVATRate]
+	}
+	if prepayment {
+		return code[1]
+	}
+	return code[0]
+}
+
+// receiptTx собирает чек операции по платежу на сумму amount: из позиций возврата, если они заданы,
+// иначе из всех позиций заказа и доставки. Чек на часть суммы формируется через Receipt.Scale.
+// nil — чеки выключены.
+func (s *PaymentService) receiptTx(tx *gorm.DB, payment *models.Payment, amount money.Money, refundItems []models.RefundItem) (*provider.Receipt, error) {
+	if !fiscalReceipts() {
+		return nil, nil
+	}
+
+	var order models.Order
+	if err := tx.Preload("Items").Preload("User").First(&order, "id = ?", payment.OrderID).Error; err != nil {
+		return nil, err
+	}
+
+	// Двухстадийный платёж списывается при отгрузке — это полный расчёт, иначе предоплата
+	mode := provider.PaymentModeFullPrepayment
+	if payment.TwoStage {
+		mode = provider.PaymentModeFullPayment
+	}
+	prepayment := mode == provider.PaymentModeFullPrepayment
+
+	receipt := &provider.Receipt{
+		CustomerEmail: order.User.Email,
+		TaxSystemCode: utils.ParseInt(config.TaxSystemCode),
+	}
+	if receipt.CustomerEmail == "" {
+		receipt.CustomerPhone = digits(order.User.Number)
+	}
+
+	quantities := make(map[uuid.UUID]int, len(order.Items))
+	if refundItems != nil {
+		for _, item := range refundItems {
+			quantities[item.OrderItemID] += item.Quantity
+		}
+	} else {
+		for _, item := range order.Items {
+			quantities[item.ID] = item.Quantity
+		}
+	}
+
+	for _, item := range order.Items {
+		quantity := quantities[item.ID]
+		if quantity == 0 {
+			continue
+		}
+		name, err := productNameTx(tx, item.ProductType, item.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		receipt.Items = append(receipt.Items, provider.ReceiptItem{
+			Description:    name,
+			Quantity:       quantity,
+			Price:          item.UnitPrice,
+			Currency:       payment.Currency,
+			VATCode:        vatCode(prepayment),
+			PaymentSubject: provider.PaymentSubjectCommodity,
+			PaymentMode:    mode,
+		})
+	}
+
+	if refundItems == nil && order.ShippingCost.IsPositive() {
+		receipt.Items = append(receipt.Items, provider.ReceiptItem{
+			Description:    "Доставка",
+			Quantity:       1,
+			Price:          order.ShippingCost,
+			Currency:       payment.Currency,
+			VATCode:        vatCode(prepayment),
+			PaymentSubject: provider.PaymentSubjectService,
+			PaymentMode:    mode,
+		})
+	}
+
+	scaled, err := receipt.Scale(amount)
+	if err != nil {
+		// сумма не раскладывается по позициям (например, возврат платежа по старой сумме изменённого заказа) —
+		// пробиваем одной позицией на всю сумму
+		log.Printf("payment %s: %v", payment.ID, err)
+		receipt.Items = []provider.ReceiptItem{{
+			Description:    fmt.Sprintf("Заказ №%d", order.OrderNumber),
+			Quantity:       1,
+			Price:          amount,
+			Currency:       payment.Currency,
+			VATCode:        vatCode(prepayment),
+			PaymentSubject: provider.PaymentSubjectCommodity,
+			PaymentMode:    mode,
+		}}
+		return receipt, nil
+	}
+	return scaled, nil
+}
+
+func productNameTx(tx *gorm.DB, productType types.ProductType, productID uuid.UUID) (string, error) {
+	var name string
+	var err error
+	switch productType {
+	case types.Processor:
+		err = tx.Model(&models.Processor{}).Select("name").Where("id = ?", productID).Scan(&name).Error
+	case types.FlashDriver:
+		err = tx.Model(&models.FlashDrive{}).Select("name").Where("id = ?", productID).Scan(&name).Error
+	default:
+		err = fmt.Errorf("unknown product type: %s", productType)
+	}
+	return name, err
+}
+
+// digits — телефон в формате ЮKassa: только цифры, 79001234567
+func digits(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/payment/provider"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultReconcileInterval = 10 * time.Minute
+	DefaultReconcileAfter    = 15 * time.Minute
+
+	// reconcileBatch — сколько зависших платежей проверяется за один проход
+	reconcileBatch = 100
+	// settleAfter — через сколько неотправленный возврат или снятие блокировки подбирает сверка
+	settleAfter = time.Minute
+)
+
+// ReconcilePending запрашивает у шлюза состояние платежей, которые дольше after остаются pending,
+// и применяет его так же, как вебхук. Возвращает число исправленных платежей.
+func (s *PaymentService) ReconcilePending(after time.Duration) (int, error) {
+	payments, err := s.paymentRepo.GetStalePending(time.Now().Add(-after), reconcileBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	fixed := 0
+	for _, payment := range payments {
+		ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+		info, err := s.provider.GetPayment(ctx, payment.PaymentID)
+		cancel()
+
+		if errors.Is(err, provider.ErrPaymentNotFound) {
+			// шлюз не знает платёж — оплатить его уже нельзя
+			info = &provider.PaymentInfo{ID: payment.PaymentID, Status: types.PaymentStatusCanceled}
+		} else if err != nil {
+			log.Printf("payment reconcile %s: %v", payment.ID, err)
+			continue
+		}
+
+		applied := false
+		err = s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+			var err error
+			applied, err = s.applyPaymentInfoTx(tx, info)
+			return err
+		})
+		if err != nil {
+			log.Printf("payment reconcile %s: %v", payment.ID, err)
+			continue
+		}
+		if applied {
+			log.Printf("payment reconcile %s: pending -> %s", payment.ID, info.Status)
+			s.SettleOrder(payment.OrderID)
+			fixed++
+		}
+	}
+
+	return fixed, nil
+}
+
+// SettlePending отправляет шлюзу возвраты и снятия блокировок, которые не ушли после фиксации
+// (сбой шлюза, перезапуск). Свежие записи пропускаются — их сейчас отправляет тот, кто их создал.
+func (s *PaymentService) SettlePending() int {
+	return s.settle(nil, time.Now().Add(-settleAfter))
+}
+
+// StartReconciliation периодически сверяет зависшие платежи со шлюзом и досылает ему записанные операции
+func (s *PaymentService) StartReconciliation(interval, after time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.ReconcilePending(after); err != nil {
+				log.Printf("payment reconcile: %v", err)
+			}
+			if n := s.SettlePending(); n > 0 {
+				log.Printf("payment settle: %d operations sent to provider", n)
+			}
+		}
+	}()
+}
+
+// ReconcileInterval — период сверки из PAYMENT_RECONCILE_INTERVAL_MINUTES
+func ReconcileInterval(minutes string) time.Duration {
+	if m := utils.ParseInt(minutes); m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return DefaultReconcileInterval
+}
+
+// ReconcileAfter — через сколько минут pending-платёж считается зависшим, из PAYMENT_RECONCILE_AFTER_MINUTES
+func ReconcileAfter(minutes string) time.Duration {
+	if m := utils.ParseInt(minutes); m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return DefaultReconcileAfter
+}
@@ -0,0 +1,492 @@
+package service
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/payment/dto"
+	"Market_backend/internal/payment/provider"
+	"Market_backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNothingToRefund      = errors.New("у заказа нет оплаченного платежа для возврата")
+	ErrRefundExceedsPayment = errors.New("сумма возврата больше доступного остатка платежа")
+	ErrInvalidRefundItem    = errors.New("позиция не может быть возвращена")
+)
+
+// RefundOrderPaymentTx возвращает деньги по оплаченным платежам заказа в транзакции вызывающего
+// (отмена заказа, одобрение возврата товара). amount <= 0 — весь остаток; сумма больше остатка
+// уменьшается до него. Возвращает фактически распределённую сумму (ноль — возвращать нечего).
+// Возвраты через шлюз только записываются: после фиксации транзакции вызывающий отправляет их через SettleOrder.
+func (s *PaymentService) RefundOrderPaymentTx(tx *gorm.DB, orderID uuid.UUID, amount money.Money, reason string) (money.Money, error) {
+	return s.refundOrderTx(tx, orderID, amount, reason, nil, false)
+}
+
+// RefundOrderToStoreCreditTx — то же, но деньги зачисляются на баланс покупателя, а не в источники оплаты
+func (s *PaymentService) RefundOrderToStoreCreditTx(tx *gorm.DB, orderID uuid.UUID, amount money.Money, reason string, createdBy *uuid.UUID) (money.Money, error) {
+	return s.refundOrderTx(tx, orderID, amount, reason, createdBy, true)
+}
+
+// refundOrderTx делит сумму между источниками оплаты пропорционально их остаткам: при частичном
+// возврате часть, оплаченная подарочной картой или балансом, возвращается туда же, а не деньгами
+func (s *PaymentService) refundOrderTx(tx *gorm.DB, orderID uuid.UUID, amount money.Money, reason string, createdBy *uuid.UUID, toStoreCredit bool) (money.Money, error) {
+	shares, available, err := s.refundSharesTx(tx, orderID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if !available.IsPositive() {
+		return money.Money{}, nil
+	}
+	if !amount.IsPositive() || available.Less(amount) {
+		amount = available
+	}
+
+	left := amount
+	for i, share := range shares {
+		part := amount.Share(float64(share.available.Kopecks), float64(available.Kopecks)).Min(share.available)
+		if i == len(shares)-1 {
+			part = left.Min(share.available) // остаток от округления — последнему источнику
+		}
+		if !part.IsPositive() {
+			continue
+		}
+		if _, err := s.refundTx(tx, share.payment.ID, part, reason, createdBy, nil, toStoreCredit); err != nil {
+			return money.Money{}, err
+		}
+		left = left.Sub(part)
+	}
+	return amount.Sub(left), nil
+}
+
+// CreateRefund — возврат, оформленный администратором: за позиции заказа, на сумму или весь остаток.
+// Если заказ оплачен из нескольких источников, создаётся по возврату на каждый затронутый платёж.
+func (s *PaymentService) CreateRefund(adminId uuid.UUID, refundDto dto.CreateRefundDTO) ([]models.Refund, error) {
+	if err := refundDto.Validate(); err != nil {
+		return nil, err
+	}
+
+	var refunds []models.Refund
+	err := s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		shares, available, err := s.refundSharesTx(tx, refundDto.OrderID)
+		if err != nil {
+			return err
+		}
+		if !available.IsPositive() {
+			return ErrNothingToRefund
+		}
+
+		amount := refundDto.Amount
+		var items []models.RefundItem
+
+		if len(refundDto.Items) > 0 {
+			items, amount, err = s.refundItemsTx(tx, refundDto.OrderID, refundDto.Items)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !amount.IsPositive() {
+			amount = available
+		}
+		if available.Less(amount) {
+			return ErrRefundExceedsPayment
+		}
+
+		refunds, err = s.distributeRefundTx(tx, shares, amount, refundDto.Reason, &adminId, items, refundDto.StoreCredit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Шлюзу возвраты отправляются после фиксации; в ответе — их состояние после отправки
+	s.SettleOrder(refundDto.OrderID)
+	for i := range refunds {
+		if refund, err := s.paymentRepo.GetRefund(refunds[i].ID); err == nil {
+			refunds[i] = *refund
+		}
+	}
+	return refunds, nil
+}
+
+// refundShare — сколько ещё можно вернуть по платежу
+type refundShare struct {
+	payment   *models.Payment
+	available money.Money
+}
+
+// refundSharesTx — оплаченные платежи заказа с доступными к возврату суммами, в порядке возврата
+// (см. GetRefundableByOrderTx), и их общий остаток
+func (s *PaymentService) refundSharesTx(tx *gorm.DB, orderID uuid.UUID) ([]refundShare, money.Money, error) {
+	payments, err := s.paymentRepo.GetRefundableByOrderTx(tx, orderID)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+
+	shares := make([]refundShare, 0, len(payments))
+	var total money.Money
+	for i := range payments {
+		available, err := s.refundableTx(tx, &payments[i])
+		if err != nil {
+			return nil, money.Money{}, err
+		}
+		if available.IsPositive() {
+			shares = append(shares, refundShare{payment: &payments[i], available: available})
+			total = total.Add(available)
+		}
+	}
+	return shares, total, nil
+}
+
+// distributeRefundTx возвращает amount по платежам по очереди: деньги уходят туда, откуда пришли.
+// Позиции возврата привязываются к первому возврату.
+func (s *PaymentService) distributeRefundTx(
+	tx *gorm.DB,
+	shares []refundShare,
+	amount money.Money,
+	reason string,
+	createdBy *uuid.UUID,
+	items []models.RefundItem,
+	toStoreCredit bool,
+) ([]models.Refund, error) {
+	var refunds []models.Refund
+	for _, share := range shares {
+		if !amount.IsPositive() {
+			break
+		}
+		part := share.available.Min(amount)
+		refund, err := s.refundTx(tx, share.payment.ID, part, reason, createdBy, items, toStoreCredit)
+		if err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, *refund)
+		items = nil
+		amount = amount.Sub(part)
+	}
+	return refunds, nil
+}
+
+func (s *PaymentService) GetOrderRefunds(orderID uuid.UUID) ([]models.Refund, error) {
+	return s.paymentRepo.GetRefundsByOrder(orderID)
+}
+
+// updateRefundStatusTx применяет статус возврата из уведомления шлюза.
+// Повторные и запоздавшие уведомления по завершённому возврату ничего не меняют.
+func (s *PaymentService) updateRefundStatusTx(tx *gorm.DB, info *provider.RefundInfo) (bool, error) {
+	// Сначала блокируем платёж: если ответ шлюза на этот возврат сейчас сохраняется (submitRefund
+	// держит эту блокировку), дождёмся фиксации и увидим ID возврата
+	var payment *models.Payment
+	if info.PaymentID != "" {
+		var err error
+		payment, err = s.paymentRepo.GetByPaymentIDTx(tx, info.PaymentID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+	}
+
+	refund, err := s.paymentRepo.GetRefundByProviderIDTx(tx, info.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) && payment != nil {
+		// уведомление обогнало сохранение ответа шлюза: возврат записан, но ещё без ID у шлюза
+		refund, err = s.paymentRepo.GetUnsubmittedRefundTx(tx, payment.ID, info.Amount)
+		if err == nil {
+			refund.ProviderRefundID = info.ID
+		}
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// возврат сделан мимо магазина (например, в личном кабинете шлюза)
+		log.Printf("refund webhook: unknown refund %s", info.ID)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return s.applyRefundInfoTx(tx, refund, info)
+}
+
+// applyRefundInfoTx применяет к заблокированному возврату его состояние у шлюза.
+// Завершённый возврат не меняется, кроме статуса чека.
+func (s *PaymentService) applyRefundInfoTx(tx *gorm.DB, refund *models.Refund, info *provider.RefundInfo) (bool, error) {
+	changed := false
+	if refund.ProviderRefundID == "" {
+		refund.ProviderRefundID = info.ID
+		changed = true
+	}
+	if info.ReceiptStatus != "" && info.ReceiptStatus != refund.ReceiptStatus {
+		refund.ReceiptStatus = info.ReceiptStatus
+		changed = true
+	}
+
+	if refund.Status == types.RefundPending {
+		switch types.RefundStatus(info.Status) {
+		case types.RefundSucceeded:
+			if err := s.applyRefundTx(tx, refund); err != nil {
+				return false, err
+			}
+			changed = true
+		case types.RefundCanceled:
+			refund.Status = types.RefundCanceled
+			changed = true
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+	return true, s.paymentRepo.SaveRefundTx(tx, refund)
+}
+
+// refundItemsTx проверяет позиции возврата и считает сумму по ценам заказа
+func (s *PaymentService) refundItemsTx(tx *gorm.DB, orderID uuid.UUID, itemsDto []dto.RefundItemDTO) ([]models.RefundItem, money.Money, error) {
+	var orderItems []models.OrderItem
+	if err := tx.Where("order_id = ?", orderID).Find(&orderItems).Error; err != nil {
+		return nil, money.Money{}, err
+	}
+	byId := make(map[uuid.UUID]models.OrderItem, len(orderItems))
+	for _, item := range orderItems {
+		byId[item.ID] = item
+	}
+
+	refunded, err := s.paymentRepo.RefundedQuantitiesTx(tx, orderID)
+	if err != nil {
+		return nil, money.Money{}, err
+	}
+
+	items := make([]models.RefundItem, 0, len(itemsDto))
+	var total money.Money
+	for _, itemDto := range itemsDto {
+		orderItem, ok := byId[itemDto.OrderItemID]
+		if !ok {
+			return nil, money.Money{}, fmt.Errorf("%w: %s не входит в заказ", ErrInvalidRefundItem, itemDto.OrderItemID)
+		}
+
+		left := orderItem.Quantity - refunded[orderItem.ID]
+		if itemDto.Quantity > left {
+			return nil, money.Money{}, fmt.Errorf("%w: %s, можно вернуть не более %d шт.", ErrInvalidRefundItem, orderItem.ID, left)
+		}
+		refunded[orderItem.ID] += itemDto.Quantity
+
+		amount := orderItem.UnitPrice.Mul(itemDto.Quantity)
+		items = append(items, models.RefundItem{
+			ID:          uuid.New(),
+			OrderItemID: orderItem.ID,
+			Quantity:    itemDto.Quantity,
+			Amount:      amount,
+		})
+		total = total.Add(amount)
+	}
+
+	return items, total, nil
+}
+
+// refundableTx — сколько ещё можно вернуть по платежу с учётом неподтверждённых возвратов
+func (s *PaymentService) refundableTx(tx *gorm.DB, payment *models.Payment) (money.Money, error) {
+	pending, err := s.paymentRepo.PendingRefundSumTx(tx, payment.ID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return payment.Amount.Sub(payment.RefundedAmount).Sub(pending), nil
+}
+
+// refundTx записывает возврат по платежу в транзакции вызывающего. Подарочная карта, баланс
+// и офлайн-оплата возвращаются сразу; возврат через шлюз остаётся в статусе pending и отправляется
+// после фиксации транзакции (submitRefund) — запрос к шлюзу не держит блокировки заказа и платежей,
+// а деньги не уйдут без записи о возврате. toStoreCredit — зачислить на баланс покупателя.
+func (s *PaymentService) refundTx(
+	tx *gorm.DB,
+	paymentID uuid.UUID,
+	amount money.Money,
+	reason string,
+	createdBy *uuid.UUID,
+	items []models.RefundItem,
+	toStoreCredit bool,
+) (*models.Refund, error) {
+	payment, err := s.paymentRepo.GetByIdTx(tx, paymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	available, err := s.refundableTx(tx, payment)
+	if err != nil {
+		return nil, err
+	}
+	if !amount.IsPositive() {
+		return nil, ErrNothingToRefund
+	}
+	if available.Less(amount) {
+		return nil, ErrRefundExceedsPayment
+	}
+
+	refund := &models.Refund{
+		ID:          uuid.New(),
+		PaymentID:   payment.ID,
+		OrderID:     payment.OrderID,
+		Status:      types.RefundPending,
+		Amount:      amount,
+		Currency:    payment.Currency,
+		Reason:      reason,
+		CreatedByID: createdBy,
+
+		ToStoreCredit: toStoreCredit,
+	}
+	for i := range items {
+		items[i].RefundID = refund.ID
+	}
+	refund.Items = items
+
+	if err := s.paymentRepo.CreateRefundTx(tx, refund); err != nil {
+		return nil, err
+	}
+
+	// Подарочная карта и баланс пополняются сразу, как и баланс при возврате на него
+	if payment.Method.IsBalance() || toStoreCredit {
+		if err := s.refundToBalanceTx(tx, payment, refund); err != nil {
+			return nil, err
+		}
+	}
+
+	// Офлайн-оплата возвращается вручную (переводом или из кассы), шлюз в этом не участвует —
+	// возврат сразу учитывается как проведённый
+	if payment.Method.IsOffline() || payment.Method.IsBalance() || toStoreCredit {
+		if err := s.applyRefundTx(tx, refund); err != nil {
+			return nil, err
+		}
+		if err := s.paymentRepo.SaveRefundTx(tx, refund); err != nil {
+			return nil, err
+		}
+	}
+	return refund, nil
+}
+
+// submitRefund отправляет шлюзу записанный возврат и сохраняет ответ. Ключ идемпотентности — ID возврата,
+// поэтому повторная отправка (после сбоя или при сверке) не вернёт деньги дважды.
+// Если шлюз отказал, возврат отменяется и деньги по платежу снова доступны к возврату.
+func (s *PaymentService) submitRefund(refundID uuid.UUID) error {
+	refund, err := s.paymentRepo.GetRefund(refundID)
+	if err != nil {
+		return err
+	}
+	if refund.Status != types.RefundPending || refund.ProviderRefundID != "" {
+		return nil
+	}
+	payment, err := s.paymentRepo.GetById(refund.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	// без позиций — возврат произвольной суммы, чек по всем позициям заказа
+	var items []models.RefundItem
+	if len(refund.Items) > 0 {
+		items = refund.Items
+	}
+	receipt, err := s.receiptTx(s.paymentRepo.DB(), payment, refund.Amount, items)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), providerTimeout)
+	defer cancel()
+
+	info, providerErr := s.provider.Refund(ctx, provider.RefundRequest{
+		IdempotencyKey: refund.ID.String(),
+		PaymentID:      payment.PaymentID,
+		Amount:         refund.Amount,
+		Currency:       refund.Currency,
+		Description:    refund.Reason,
+		Receipt:        receipt,
+	})
+	rejected := errors.Is(providerErr, provider.ErrRejected) || errors.Is(providerErr, provider.ErrPaymentNotFound)
+	if providerErr != nil && !rejected {
+		return providerErr
+	}
+
+	return s.paymentRepo.DB().Transaction(func(tx *gorm.DB) error {
+		// порядок блокировок тот же, что и при обработке уведомления: платёж, затем возврат
+		if _, err := s.paymentRepo.GetByIdTx(tx, refund.PaymentID); err != nil {
+			return err
+		}
+		locked, err := s.paymentRepo.GetRefundTx(tx, refund.ID)
+		if err != nil {
+			return err
+		}
+
+		if !rejected {
+			_, err := s.applyRefundInfoTx(tx, locked, info)
+			return err
+		}
+
+		if locked.Status != types.RefundPending || locked.ProviderRefundID != "" {
+			return nil
+		}
+		log.Printf("refund %s: rejected by provider: %v", locked.ID, providerErr)
+		locked.Status = types.RefundCanceled
+		if err := s.paymentRepo.SaveRefundTx(tx, locked); err != nil {
+			return err
+		}
+		return s.orderRepo.AddNoteTx(tx, locked.OrderID, nil,
+			fmt.Sprintf("шлюз отклонил возврат %s %s: %v", locked.Amount.Decimal(), locked.Currency, providerErr))
+	})
+}
+
+// applyRefundTx учитывает подтверждённый возврат в платеже и заказе.
+// Когда все платежи заказа возвращены полностью, заказ переходит в refunded, если это допустимо из его статуса.
+func (s *PaymentService) applyRefundTx(tx *gorm.DB, refund *models.Refund) error {
+	refund.Status = types.RefundSucceeded
+
+	payment, err := s.paymentRepo.GetByIdTx(tx, refund.PaymentID)
+	if err != nil {
+		return err
+	}
+
+	payment.RefundedAmount = payment.RefundedAmount.Add(refund.Amount)
+	if payment.Status.IsRefundable() {
+		payment.Status = types.PaymentStatusPartiallyRefunded
+		if !payment.RefundedAmount.Less(payment.Amount) {
+			payment.Status = types.PaymentStatusRefunded
+		}
+	}
+	if err := s.paymentRepo.UpdateTx(tx, payment); err != nil {
+		return err
+	}
+
+	var order models.Order
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id", "status", "refunded_amount").
+		First(&order, "id = ?", refund.OrderID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Order{}).
+		Where("id = ?", order.ID).
+		Update("refunded_amount", order.RefundedAmount.Add(refund.Amount)).Error; err != nil {
+		return err
+	}
+	// бонусные баллы, начисленные за заказ и потраченные на него, пересчитываются в доле возврата
+	if err := s.loyaltyService.OnRefundTx(tx, order.ID, refund.Amount); err != nil {
+		return err
+	}
+
+	if payment.Status != types.PaymentStatusRefunded || !order.Status.CanTransitionTo(types.Refunded) {
+		return nil
+	}
+	// заказ, оплаченный из нескольких источников, возвращён полностью, когда не осталось ни одного оплаченного платежа
+	var remaining int64
+	if err := tx.Model(&models.Payment{}).
+		Where("order_id = ? AND status IN ?", order.ID, []types.PaymentStatus{
+			types.PaymentStatusSucceeded, types.PaymentStatusPartiallyRefunded,
+		}).
+		Count(&remaining).Error; err != nil {
+		return err
+	}
+	if remaining > 0 {
+		return nil
+	}
+	return s.orderRepo.ChangeStatusTx(tx, order.ID, types.Refunded, refund.CreatedByID, "деньги возвращены полностью")
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/payment/provider"
+	"Market_backend/models"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSavedMethodNotFound    = errors.New("сохранённая карта не найдена")
+	ErrSavedMethodUnsupported = errors.New("сохранить можно только банковскую карту")
+)
+
+// CreatePaymentOptions — сохранение карты при оплате, оплата сохранённой картой,
+// частичная оплата подарочной картой и балансом покупателя
+type CreatePaymentOptions struct {
+	SaveMethod    bool       // сохранить карту у шлюза для следующих оплат
+	SavedMethodID *uuid.UUID // оплатить сохранённой картой без перехода на страницу шлюза
+
+	GiftCardCode   string // списать с подарочной карты, остаток — выбранным способом
+	UseStoreCredit bool   // списать с баланса покупателя
+}
+
+// savedMethodFor проверяет опции оплаты и возвращает сохранённую карту покупателя, если ею платят
+func (s *PaymentService) savedMethodFor(order *models.Order, method types.PaymentMethod, opts CreatePaymentOptions) (*models.SavedPaymentMethod, error) {
+	if (opts.SaveMethod || opts.SavedMethodID != nil) && method != types.PaymentMethodCard {
+		return nil, ErrSavedMethodUnsupported
+	}
+	if opts.SavedMethodID == nil {
+		return nil, nil
+	}
+
+	saved, err := s.paymentRepo.GetSavedMethod(order.UserID, *opts.SavedMethodID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSavedMethodNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// токен другого шлюза текущему бесполезен
+	if saved.Provider != s.provider.Name() {
+		return nil, ErrSavedMethodNotFound
+	}
+	return saved, nil
+}
+
+// saveMethodTx запоминает карту, которую шлюз сохранил по просьбе покупателя
+func (s *PaymentService) saveMethodTx(tx *gorm.DB, payment *models.Payment, info *provider.PaymentInfo) error {
+	if !payment.SaveMethod || info.SavedMethod == nil {
+		return nil
+	}
+
+	var order models.Order
+	if err := tx.Select("id", "user_id").First(&order, "id = ?", payment.OrderID).Error; err != nil {
+		return err
+	}
+
+	saved := info.SavedMethod
+	return s.paymentRepo.UpsertSavedMethodTx(tx, &models.SavedPaymentMethod{
+		ID:               uuid.New(),
+		UserID:           order.UserID,
+		Provider:         s.provider.Name(),
+		ProviderMethodID: saved.ID,
+		Type:             types.PaymentMethod(saved.Type),
+		Title:            saved.Title,
+		CardType:         saved.CardType,
+		Last4:            saved.Last4,
+		ExpiryMonth:      saved.ExpiryMonth,
+		ExpiryYear:       saved.ExpiryYear,
+		CreatedAt:        time.Now(),
+	})
+}
+
+// GetSavedMethods — сохранённые карты покупателя для текущего шлюза
+func (s *PaymentService) GetSavedMethods(userId uuid.UUID) ([]models.SavedPaymentMethod, error) {
+	methods, err := s.paymentRepo.GetSavedMethods(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.SavedPaymentMethod, 0, len(methods))
+	for _, m := range methods {
+		if m.Provider == s.provider.Name() {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+// DeleteSavedMethod удаляет карту из профиля; токен у шлюза больше не используется
+func (s *PaymentService) DeleteSavedMethod(userId, id uuid.UUID) error {
+	err := s.paymentRepo.DeleteSavedMethod(userId, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSavedMethodNotFound
+	}
+	return err
+}
@@ -1,11 +1,14 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"Market_backend/internal/common/money"
+	"github.com/google/uuid"
+)
 
 type AllProcessorsResponseDTO struct {
-	ID             uuid.UUID `json:"id"`
-	Name           string    `json:"name"`
-	RetailPrice    float64   `json:"retail_price"`
-	WholesalePrice float64   `json:"wholesale_price"`
-	ImageURL       *string   `json:"image_url,omitempty" gorm:"column:image_url"`
+	ID             uuid.UUID   `json:"id"`
+	Name           string      `json:"name"`
+	RetailPrice    money.Money `json:"retail_price"`
+	WholesalePrice money.Money `json:"wholesale_price"`
+	ImageURL       *string     `json:"image_url,omitempty" gorm:"column:image_url"`
 }
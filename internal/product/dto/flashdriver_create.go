@@ -1,6 +1,9 @@
 package dto
 
-import "mime/multipart"
+import (
+	"Market_backend/internal/common/money"
+	"mime/multipart"
+)
 
 // DTO для создания
 type FlashDriveCreateDTO struct {
@@ -8,10 +11,10 @@ type FlashDriveCreateDTO struct {
 	Name  string `json:"name"`
 	Brand string `json:"brand"`
 
-	RetailPrice     float64 `json:"retail_price"`
-	WholesalePrice  float64 `json:"wholesale_price"`
-	WholesaleMinQty int     `json:"wholesale_min_qty"`
-	Stock           int     `json:"stock"`
+	RetailPrice     money.Money `json:"retail_price"`
+	WholesalePrice  money.Money `json:"wholesale_price"`
+	WholesaleMinQty int         `json:"wholesale_min_qty"`
+	Stock           int         `json:"stock"`
 
 	CapacityGB      int    `json:"capacity_gb"`
 	USBInterface    string `json:"usb_interface"` // USB 2.0 / USB 3.0 / USB 3.2 etc.
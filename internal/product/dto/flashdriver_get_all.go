@@ -1,13 +1,15 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"Market_backend/internal/common/money"
+	"github.com/google/uuid"
+)
 
 // DTO для списка (каталога)
 type AllFlashDrivesResponseDTO struct {
-	ID             uuid.UUID `json:"id"`
-	Name           string    `json:"name"`
-	WholesalePrice float64   `json:"wholesale_price"`
-	RetailPrice    float64   `json:"retail_price"`
-	ImageURL       string    `json:"image_url"`
+	ID             uuid.UUID   `json:"id"`
+	Name           string      `json:"name"`
+	WholesalePrice money.Money `json:"wholesale_price"`
+	RetailPrice    money.Money `json:"retail_price"`
+	ImageURL       string      `json:"image_url"`
 }
- 
\ No newline at end of file
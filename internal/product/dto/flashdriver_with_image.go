@@ -1,6 +1,9 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"Market_backend/internal/common/money"
+	"github.com/google/uuid"
+)
 
 // Полная карточка с изображениями
 type FlashDriveWithImagesDTO struct {
@@ -9,10 +12,10 @@ type FlashDriveWithImagesDTO struct {
 	Name  string    `json:"name"`
 	Brand string    `json:"brand"`
 
-	RetailPrice     float64 `json:"retail_price"`
-	WholesalePrice  float64 `json:"wholesale_price"`
-	WholesaleMinQty int     `json:"wholesale_min_qty"`
-	Stock           int     `json:"stock"`
+	RetailPrice     money.Money `json:"retail_price"`
+	WholesalePrice  money.Money `json:"wholesale_price"`
+	WholesaleMinQty int         `json:"wholesale_min_qty"`
+	Stock           int         `json:"stock"`
 
 	CapacityGB      int    `json:"capacity_gb"`
 	USBInterface    string `json:"usb_interface"`
@@ -1,12 +1,15 @@
 package dto
 
-import "mime/multipart"
+import (
+	"Market_backend/internal/common/money"
+	"mime/multipart"
+)
 
 type ProcessorCreateDTO struct {
 	Name               string                  `json:"name" validate:"required"`
 	Brand              string                  `json:"brand" validate:"required"`
-	RetailPrice        float64                 `json:"retail_price" validate:"required"`
-	WholesalePrice     float64                 `json:"wholesale_price"`
+	RetailPrice        money.Money             `json:"retail_price" validate:"required"`
+	WholesalePrice     money.Money             `json:"wholesale_price"`
 	WholesaleMinQty    int                     `json:"wholesale_min_qty"`
 	Stock              int                     `json:"stock" validate:"required"`
 	Line               string                  `json:"line"`
@@ -1,12 +1,15 @@
 package dto
 
-import "mime/multipart"
+import (
+	"Market_backend/internal/common/money"
+	"mime/multipart"
+)
 
 type ProcUpdate struct {
 	Name               string                  `json:"name"`
 	Brand              string                  `json:"brand"`
-	RetailPrice        float64                 `json:"retail_price"`
-	WholesalePrice     float64                 `json:"wholesale_price"`
+	RetailPrice        money.Money             `json:"retail_price"`
+	WholesalePrice     money.Money             `json:"wholesale_price"`
 	WholesaleMinQty    int                     `json:"wholesale_min_qty"`
 	Stock              int                     `json:"stock"`
 	Line               string                  `json:"line"`
@@ -42,8 +42,8 @@ func (h *FlashDriveHandler) CreateFlashDrive(c *fiber.Ctx) error {
 		SKU:             get("sku"),
 		Name:            get("name"),
 		Brand:           get("brand"),
-		RetailPrice:     utils.ParseFloat(get("retail_price")),
-		WholesalePrice:  utils.ParseFloat(get("wholesale_price")),
+		RetailPrice:     utils.ParseMoney(get("retail_price")),
+		WholesalePrice:  utils.ParseMoney(get("wholesale_price")),
 		WholesaleMinQty: utils.ParseInt(get("wholesale_min_qty")),
 		Stock:           utils.ParseInt(get("stock")),
 
@@ -203,8 +203,8 @@ func (h *FlashDriveHandler) UpdateFlashDrive(c *fiber.Ctx) error {
 	dtoFD := dto.FlashDriveUpdateDTO{
 		Name:            get("name"),
 		Brand:           get("brand"),
-		RetailPrice:     utils.ParseFloat(get("retail_price")),
-		WholesalePrice:  utils.ParseFloat(get("wholesale_price")),
+		RetailPrice:     utils.ParseMoney(get("retail_price")),
+		WholesalePrice:  utils.ParseMoney(get("wholesale_price")),
 		WholesaleMinQty: utils.ParseInt(get("wholesale_min_qty")),
 		Stock:           utils.ParseInt(get("stock")),
 
@@ -42,8 +42,8 @@ func (h *ProcessorHandler) CreateProcessor(c *fiber.Ctx) error {
 	procDto := dto.ProcessorCreateDTO{
 		Name:               getValue("name"),
 		Brand:              getValue("brand"),
-		RetailPrice:        utils.ParseFloat(getValue("retail_price")),
-		WholesalePrice:     utils.ParseFloat(getValue("wholesale_price")),
+		RetailPrice:        utils.ParseMoney(getValue("retail_price")),
+		WholesalePrice:     utils.ParseMoney(getValue("wholesale_price")),
 		WholesaleMinQty:    utils.ParseInt(getValue("wholesale_min_qty")),
 		Stock:              utils.ParseInt(getValue("stock")),
 		Line:               getValue("line"),
@@ -191,8 +191,8 @@ func (h *ProcessorHandler) UpdateProcessor(c *fiber.Ctx) error {
 	procDto := dto.ProcUpdate{
 		Name:               getValue("name"),
 		Brand:              getValue("brand"),
-		RetailPrice:        utils.ParseFloat(getValue("retail_price")),
-		WholesalePrice:     utils.ParseFloat(getValue("wholesale_price")),
+		RetailPrice:        utils.ParseMoney(getValue("retail_price")),
+		WholesalePrice:     utils.ParseMoney(getValue("wholesale_price")),
 		WholesaleMinQty:    utils.ParseInt(getValue("wholesale_min_qty")),
 		Stock:              utils.ParseInt(getValue("stock")),
 		Line:               getValue("line"),
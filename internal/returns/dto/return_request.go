@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"Market_backend/internal/common/types"
+	"mime/multipart"
+
+	"github.com/google/uuid"
+)
+
+type ReturnItemDTO struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+type CreateReturnDTO struct {
+	OrderID uuid.UUID       `json:"order_id"`
+	Reason  string          `json:"reason"`
+	Items   []ReturnItemDTO `json:"items"`
+
+	Photos []*multipart.FileHeader `json:"-"`
+}
+
+type ReviewReturnDTO struct {
+	Comment     string `json:"comment"`
+	StoreCredit bool   `json:"store_credit"` // вернуть деньги на баланс покупателя, а не в источники оплаты
+}
+
+type ReceiveItemDTO struct {
+	ReturnItemID uuid.UUID              `json:"return_item_id"`
+	Resolution   types.ReturnResolution `json:"resolution"`
+}
+
+type ReceiveReturnDTO struct {
+	Items []ReceiveItemDTO `json:"items"`
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/returns/dto"
+	"Market_backend/internal/returns/service"
+	"encoding/json"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReturnHandler struct {
+	service *service.ReturnService
+}
+
+func NewReturnHandler(service *service.ReturnService) *ReturnHandler {
+	return &ReturnHandler{service: service}
+}
+
+// CreateReturn принимает multipart-форму: order_id, reason, items (JSON-массив) и до 5 файлов photos
+func (h *ReturnHandler) CreateReturn(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid form"})
+	}
+
+	files := form.File["photos"]
+	if len(files) > 5 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "max 5 photos allowed"})
+	}
+
+	getValue := func(key string) string {
+		if vals, ok := form.Value[key]; ok && len(vals) > 0 {
+			return vals[0]
+		}
+		return ""
+	}
+
+	orderId, err := uuid.Parse(getValue("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid order_id"})
+	}
+
+	var items []dto.ReturnItemDTO
+	if err := json.Unmarshal([]byte(getValue("items")), &items); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid items"})
+	}
+
+	request, err := h.service.CreateReturn(userId, dto.CreateReturnDTO{
+		OrderID: orderId,
+		Reason:  getValue("reason"),
+		Items:   items,
+		Photos:  files,
+	})
+	if err != nil {
+		return returnError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"return": request})
+}
+
+func (h *ReturnHandler) GetMyReturns(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	returns, err := h.service.GetUserReturns(userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"returns": returns})
+}
+
+func (h *ReturnHandler) GetAllReturns(c *fiber.Ctx) error {
+	status := types.ReturnStatus(c.Query("status"))
+	if status != "" && !status.IsValid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid status"})
+	}
+
+	returns, err := h.service.GetAllReturns(status)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"returns": returns})
+}
+
+func (h *ReturnHandler) ApproveReturn(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	returnId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid return id"})
+	}
+
+	var body dto.ReviewReturnDTO
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	request, err := h.service.ApproveReturn(adminId, returnId, body)
+	if err != nil {
+		return returnError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"return": request})
+}
+
+func (h *ReturnHandler) RejectReturn(c *fiber.Ctx) error {
+	returnId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid return id"})
+	}
+
+	var body dto.ReviewReturnDTO
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	request, err := h.service.RejectReturn(returnId, body)
+	if err != nil {
+		return returnError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"return": request})
+}
+
+func (h *ReturnHandler) ReceiveReturn(c *fiber.Ctx) error {
+	returnId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid return id"})
+	}
+
+	var body dto.ReceiveReturnDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	request, err := h.service.ReceiveReturn(returnId, body)
+	if err != nil {
+		return returnError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"return": request})
+}
+
+func returnError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "not found"})
+	case errors.Is(err, service.ErrOrderNotReturnable),
+		errors.Is(err, types.ErrInvalidReturnTransition),
+		errors.Is(err, types.ErrInvalidOrderTransition):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+}
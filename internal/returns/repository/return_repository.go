@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReturnRepository struct {
+	db *gorm.DB
+}
+
+func NewReturnRepository() *ReturnRepository {
+	return &ReturnRepository{db: common.DB}
+}
+
+func (r *ReturnRepository) DB() *gorm.DB {
+	return r.db
+}
+
+func (r *ReturnRepository) CreateTx(tx *gorm.DB, request *models.ReturnRequest) error {
+	return tx.Create(request).Error
+}
+
+func (r *ReturnRepository) SaveTx(tx *gorm.DB, request *models.ReturnRequest) error {
+	return tx.Omit(clause.Associations).Save(request).Error
+}
+
+func (r *ReturnRepository) GetByIdForUpdateTx(tx *gorm.DB, id uuid.UUID) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&request, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Preload("OrderItem").Where("return_request_id = ?", id).Find(&request.Items).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *ReturnRepository) GetById(id uuid.UUID) (*models.ReturnRequest, error) {
+	var request models.ReturnRequest
+	if err := r.db.
+		Preload("Items.OrderItem").
+		Preload("Photos").
+		First(&request, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *ReturnRepository) GetByUser(userId uuid.UUID) ([]models.ReturnRequest, error) {
+	var requests []models.ReturnRequest
+	if err := r.db.
+		Preload("Items.OrderItem").
+		Preload("Photos").
+		Where("user_id = ?", userId).
+		Order("created_at DESC").
+		Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *ReturnRepository) GetAll(status types.ReturnStatus) ([]models.ReturnRequest, error) {
+	var requests []models.ReturnRequest
+	query := r.db.
+		Preload("Items.OrderItem").
+		Preload("Photos").
+		Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ReturnedQuantitiesTx — сколько единиц каждой позиции заказа заявлено в возвратах с указанными статусами
+func (r *ReturnRepository) ReturnedQuantitiesTx(tx *gorm.DB, orderId uuid.UUID, statuses []types.ReturnStatus) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		OrderItemID uuid.UUID
+		Quantity    int
+	}
+	if err := tx.
+		Table("return_items ri").
+		Select("ri.order_item_id, SUM(ri.quantity) AS quantity").
+		Joins("JOIN return_requests rr ON rr.id = ri.return_request_id").
+		Where("rr.order_id = ? AND rr.status IN ?", orderId, statuses).
+		Group("ri.order_item_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		result[row.OrderItemID] = row.Quantity
+	}
+	return result, nil
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"Market_backend/internal/middleware"
+	"Market_backend/internal/returns/handler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterReturnRouter(app *fiber.App, h *handler.ReturnHandler) {
+	returns := app.Group("/returns")
+
+	// Покупатель
+	returns.Post("/", middleware.AuthRequired(), h.CreateReturn)
+	returns.Get("/my", middleware.AuthRequired(), h.GetMyReturns)
+
+	// Администратор
+	returns.Get("/", middleware.AuthRequired(), middleware.AdminOnly(), h.GetAllReturns)
+	returns.Post("/:id/approve", middleware.AuthRequired(), middleware.AdminOnly(), h.ApproveReturn)
+	returns.Post("/:id/reject", middleware.AuthRequired(), middleware.AdminOnly(), h.RejectReturn)
+	returns.Post("/:id/receive", middleware.AuthRequired(), middleware.AdminOnly(), h.ReceiveReturn)
+}
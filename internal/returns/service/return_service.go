@@ -0,0 +1,372 @@
+package service
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	InventoryService "Market_backend/internal/inventory/service"
+	OrderRepository "Market_backend/internal/order/repository"
+	PaymentService "Market_backend/internal/payment/service"
+	"Market_backend/internal/returns/dto"
+	"Market_backend/internal/returns/repository"
+	"Market_backend/internal/storage"
+	"Market_backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOrderNotReturnable = errors.New("вернуть можно только полученный заказ")
+	ErrReturnEmpty        = errors.New("не выбраны позиции для возврата")
+)
+
+type ReturnService struct {
+	repo             *repository.ReturnRepository
+	orderRepo        *OrderRepository.OrderRepository
+	inventoryService *InventoryService.InventoryService
+	paymentService   *PaymentService.PaymentService
+	storage          *storage.MinioStorage
+}
+
+func NewReturnService(
+	repo *repository.ReturnRepository,
+	orderRepo *OrderRepository.OrderRepository,
+	inventoryS *InventoryService.InventoryService,
+	paymentS *PaymentService.PaymentService,
+	storage *storage.MinioStorage,
+) *ReturnService {
+	return &ReturnService{
+		repo:             repo,
+		orderRepo:        orderRepo,
+		inventoryService: inventoryS,
+		paymentService:   paymentS,
+		storage:          storage,
+	}
+}
+
+// CreateReturn открывает заявку на возврат позиций своего заказа и загружает фото в MinIO.
+// Если фото не загрузилось или заявку не удалось сохранить, уже загруженные фото удаляются.
+func (s *ReturnService) CreateReturn(userId uuid.UUID, returnDto dto.CreateReturnDTO) (*models.ReturnRequest, error) {
+	if len(returnDto.Items) == 0 {
+		return nil, ErrReturnEmpty
+	}
+	if returnDto.Reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	request := &models.ReturnRequest{
+		ID:      uuid.New(),
+		OrderID: returnDto.OrderID,
+		UserID:  userId,
+		Status:  types.ReturnRequested,
+		Reason:  returnDto.Reason,
+	}
+
+	// фото сохраняются вместе с заявкой
+	var keys []string
+	for _, fileHeader := range returnDto.Photos {
+		photo, key, err := s.uploadPhoto(request.ID, fileHeader)
+		if err != nil {
+			s.removePhotos(keys)
+			return nil, err
+		}
+		keys = append(keys, key)
+		request.Photos = append(request.Photos, *photo)
+	}
+
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.First(&order, "id = ? AND user_id = ?", returnDto.OrderID, userId).Error; err != nil {
+			return err
+		}
+		if order.Status != types.Delivered && order.Status != types.Completed {
+			return ErrOrderNotReturnable
+		}
+
+		orderItems, err := s.orderRepo.GetOrderItemsTx(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		ordered := make(map[uuid.UUID]int, len(orderItems))
+		for _, item := range orderItems {
+			ordered[item.ID] = item.Quantity
+		}
+
+		returned, err := s.repo.ReturnedQuantitiesTx(tx, order.ID, []types.ReturnStatus{
+			types.ReturnRequested, types.ReturnApproved, types.ReturnReceived,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, item := range returnDto.Items {
+			qty, ok := ordered[item.OrderItemID]
+			if !ok {
+				return fmt.Errorf("order item %s does not belong to order", item.OrderItemID)
+			}
+			left := qty - returned[item.OrderItemID]
+			if item.Quantity <= 0 || item.Quantity > left {
+				return fmt.Errorf("order item %s: можно вернуть не более %d шт.", item.OrderItemID, left)
+			}
+			returned[item.OrderItemID] += item.Quantity
+
+			request.Items = append(request.Items, models.ReturnItem{
+				ID:              uuid.New(),
+				ReturnRequestID: request.ID,
+				OrderItemID:     item.OrderItemID,
+				Quantity:        item.Quantity,
+			})
+		}
+
+		return s.repo.CreateTx(tx, request)
+	})
+	if err != nil {
+		s.removePhotos(keys)
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// uploadPhoto загружает фото в S3 под собственным id: имя файла от клиента не задаёт путь объекта
+func (s *ReturnService) uploadPhoto(requestId uuid.UUID, fileHeader *multipart.FileHeader) (*models.ReturnPhoto, string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+
+	tmpFile, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := io.Copy(tmpFile, file); err != nil {
+		tmpFile.Close()
+		return nil, "", err
+	}
+	tmpFile.Close()
+
+	photo := &models.ReturnPhoto{
+		ID:              uuid.New(),
+		ReturnRequestID: requestId,
+	}
+	s3Key := fmt.Sprintf("returns/%s/%s/%s", requestId, photo.ID, filepath.Base(fileHeader.Filename))
+	photo.URL, err = s.storage.Upload(context.Background(), s3Key, tmpFile.Name())
+	if err != nil {
+		return nil, "", err
+	}
+	return photo, s3Key, nil
+}
+
+func (s *ReturnService) removePhotos(keys []string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(context.Background(), key); err != nil {
+			log.Printf("remove return photo %s: %v", key, err)
+		}
+	}
+}
+
+func (s *ReturnService) GetUserReturns(userId uuid.UUID) ([]models.ReturnRequest, error) {
+	return s.repo.GetByUser(userId)
+}
+
+func (s *ReturnService) GetAllReturns(status types.ReturnStatus) ([]models.ReturnRequest, error) {
+	return s.repo.GetAll(status)
+}
+
+// ApproveReturn одобряет заявку и делает частичный возврат денег по её позициям —
+// в источники оплаты или, по выбору администратора, на баланс покупателя. Сумма позиций
+// уменьшается на приходящуюся на них долю скидки баллами: эту часть покупатель платил не деньгами.
+// Если возвращены все позиции, возвращается весь остаток заказа вместе с доставкой;
+// в refunded заказ переводит сам возврат денег, когда он проходит.
+func (s *ReturnService) ApproveReturn(adminId, returnId uuid.UUID, review dto.ReviewReturnDTO) (*models.ReturnRequest, error) {
+	var orderId uuid.UUID
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		request, err := s.transitionTx(tx, returnId, types.ReturnApproved, review.Comment)
+		if err != nil {
+			return err
+		}
+
+		orderId = request.OrderID
+		if err := s.repo.SaveTx(tx, request); err != nil {
+			return err
+		}
+
+		var order models.Order
+		if err := tx.Select("id", "total", "points_discount", "refunded_amount").First(&order, "id = ?", request.OrderID).Error; err != nil {
+			return err
+		}
+
+		// заявка уже сохранена одобренной и учитывается в проверке
+		fully, err := s.fullyReturnedTx(tx, request.OrderID)
+		if err != nil {
+			return err
+		}
+		var amount money.Money
+		if fully {
+			amount = order.Total.Sub(order.RefundedAmount)
+		} else if amount, err = s.itemsRefundTx(tx, &order, request.Items); err != nil {
+			return err
+		}
+
+		// позиции целиком оплачены баллами — деньгами возвращать нечего (нулевая сумма означала бы «весь остаток»)
+		if !amount.IsPositive() {
+			return tx.Model(&models.ReturnRequest{}).Where("id = ?", request.ID).Update("refund_amount", amount).Error
+		}
+
+		// Возврат через шлюз записывается вместе с одобрением и отправляется после фиксации.
+		// В заявке — сумма, которая действительно ушла в возврат: остаток по заказу мог быть меньше.
+		if review.StoreCredit {
+			request.RefundAmount, err = s.paymentService.RefundOrderToStoreCreditTx(tx, request.OrderID, amount, "Возврат товара", &adminId)
+		} else {
+			request.RefundAmount, err = s.paymentService.RefundOrderPaymentTx(tx, request.OrderID, amount, "Возврат товара")
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.ReturnRequest{}).Where("id = ?", request.ID).Updates(map[string]any{
+			"refund_amount": request.RefundAmount,
+			"refunded":      request.RefundAmount.IsPositive(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.paymentService.SettleOrder(orderId)
+	return s.repo.GetById(returnId)
+}
+
+func (s *ReturnService) RejectReturn(returnId uuid.UUID, review dto.ReviewReturnDTO) (*models.ReturnRequest, error) {
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		request, err := s.transitionTx(tx, returnId, types.ReturnRejected, review.Comment)
+		if err != nil {
+			return err
+		}
+		return s.repo.SaveTx(tx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetById(returnId)
+}
+
+// ReceiveReturn фиксирует приёмку товара: каждая позиция возвращается на склад или списывается
+func (s *ReturnService) ReceiveReturn(returnId uuid.UUID, receive dto.ReceiveReturnDTO) (*models.ReturnRequest, error) {
+	resolutions := make(map[uuid.UUID]types.ReturnResolution, len(receive.Items))
+	for _, item := range receive.Items {
+		if !item.Resolution.IsValid() {
+			return nil, fmt.Errorf("invalid resolution: %s", item.Resolution)
+		}
+		resolutions[item.ReturnItemID] = item.Resolution
+	}
+
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		request, err := s.transitionTx(tx, returnId, types.ReturnReceived, "")
+		if err != nil {
+			return err
+		}
+
+		for _, item := range request.Items {
+			resolution, ok := resolutions[item.ID]
+			if !ok {
+				return fmt.Errorf("resolution for return item %s is required", item.ID)
+			}
+
+			comment := fmt.Sprintf("возврат %s", request.ID)
+			switch resolution {
+			case types.ReturnRestock:
+				err = s.inventoryService.RestockTx(tx, item.OrderItem.ProductType, item.OrderItem.ProductID, item.Quantity, &request.OrderID, comment)
+			case types.ReturnWriteOff:
+				err = s.inventoryService.WriteOffTx(tx, item.OrderItem.ProductType, item.OrderItem.ProductID, item.Quantity, &request.OrderID, comment)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Model(&models.ReturnItem{}).Where("id = ?", item.ID).Update("resolution", resolution).Error; err != nil {
+				return err
+			}
+		}
+
+		return s.repo.SaveTx(tx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetById(returnId)
+}
+
+func (s *ReturnService) transitionTx(tx *gorm.DB, returnId uuid.UUID, next types.ReturnStatus, comment string) (*models.ReturnRequest, error) {
+	request, err := s.repo.GetByIdForUpdateTx(tx, returnId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !request.Status.CanTransitionTo(next) {
+		return nil, fmt.Errorf("%w: %s -> %s", types.ErrInvalidReturnTransition, request.Status, next)
+	}
+
+	request.Status = next
+	if comment != "" {
+		request.AdminComment = comment
+	}
+	return request, nil
+}
+
+// fullyReturnedTx — все позиции заказа вошли в одобренные или принятые возвраты
+// itemsRefundTx — стоимость возвращаемых позиций за вычетом их доли скидки баллами
+func (s *ReturnService) itemsRefundTx(tx *gorm.DB, order *models.Order, items []models.ReturnItem) (money.Money, error) {
+	var amount money.Money
+	for _, item := range items {
+		amount = amount.Add(item.OrderItem.UnitPrice.Mul(item.Quantity))
+	}
+	if !order.PointsDiscount.IsPositive() {
+		return amount, nil
+	}
+
+	orderItems, err := s.orderRepo.GetOrderItemsTx(tx, order.ID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	var subtotal money.Money
+	for _, item := range orderItems {
+		subtotal = subtotal.Add(item.UnitPrice.Mul(item.Quantity))
+	}
+	if !subtotal.IsPositive() {
+		return amount, nil
+	}
+	discount := order.PointsDiscount.Share(float64(amount.Kopecks), float64(subtotal.Kopecks))
+	return amount.Sub(discount.Min(amount)), nil
+}
+
+func (s *ReturnService) fullyReturnedTx(tx *gorm.DB, orderId uuid.UUID) (bool, error) {
+	orderItems, err := s.orderRepo.GetOrderItemsTx(tx, orderId)
+	if err != nil {
+		return false, err
+	}
+
+	returned, err := s.repo.ReturnedQuantitiesTx(tx, orderId, []types.ReturnStatus{types.ReturnApproved, types.ReturnReceived})
+	if err != nil {
+		return false, err
+	}
+
+	for _, item := range orderItems {
+		if returned[item.ID] < item.Quantity {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -7,6 +7,7 @@ import (
 	AuthService "Market_backend/internal/auth/service"
 
 	PaymentHandler "Market_backend/internal/payment/handler"
+	PaymentProvider "Market_backend/internal/payment/provider"
 	PaymentRepo "Market_backend/internal/payment/repository"
 	PaymentRouter "Market_backend/internal/payment/router"
 	PaymentService "Market_backend/internal/payment/service"
@@ -26,11 +27,43 @@ import (
 	CartRouter "Market_backend/internal/cart/router"
 	CartService "Market_backend/internal/cart/service"
 
+	InventoryRepository "Market_backend/internal/inventory/repository"
+	InventoryService "Market_backend/internal/inventory/service"
+
+	ShippingHandler "Market_backend/internal/shipping/handler"
+	ShippingProvider "Market_backend/internal/shipping/provider"
+	ShippingRepository "Market_backend/internal/shipping/repository"
+	ShippingRouter "Market_backend/internal/shipping/router"
+	ShippingService "Market_backend/internal/shipping/service"
+
+	CompanyHandler "Market_backend/internal/company/handler"
+	CompanyRepository "Market_backend/internal/company/repository"
+	CompanyRouter "Market_backend/internal/company/router"
+	CompanyService "Market_backend/internal/company/service"
+
+	DocumentHandler "Market_backend/internal/documents/handler"
+	DocumentRepository "Market_backend/internal/documents/repository"
+	DocumentRouter "Market_backend/internal/documents/router"
+	DocumentService "Market_backend/internal/documents/service"
+
+	ReturnHandler "Market_backend/internal/returns/handler"
+	ReturnRepository "Market_backend/internal/returns/repository"
+	ReturnRouter "Market_backend/internal/returns/router"
+	ReturnService "Market_backend/internal/returns/service"
+
 	OrderHandler "Market_backend/internal/order/handler"
 	OrderRepository "Market_backend/internal/order/repository"
 	OrderRouter "Market_backend/internal/order/router"
 	OrderService "Market_backend/internal/order/service"
 
+	"Market_backend/internal/idempotency"
+	IdempotencyRepository "Market_backend/internal/idempotency/repository"
+
+	LoyaltyHandler "Market_backend/internal/loyalty/handler"
+	LoyaltyRepository "Market_backend/internal/loyalty/repository"
+	LoyaltyRouter "Market_backend/internal/loyalty/router"
+	LoyaltyService "Market_backend/internal/loyalty/service"
+
 	MessageHandler "Market_backend/internal/messages/handler"
 	MessageRepository "Market_backend/internal/messages/repository"
 	MessageRouter "Market_backend/internal/messages/router"
@@ -38,6 +71,7 @@ import (
 
 	"Market_backend/internal/storage"
 	"log"
+	"time"
 
 	"Market_backend/internal/config"
 	"github.com/gofiber/fiber/v2"
@@ -45,7 +79,10 @@ import (
 )
 
 func Start() {
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		// Вложения в переписке по заказу: до 5 файлов по 10 МБ
+		BodyLimit: 55 << 20,
+	})
 	miniStorage, err := storage.NewMinioStorage()
 	if err != nil {
 		log.Fatal(err)
@@ -58,7 +95,7 @@ func Start() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     config.AllowedOrigins, // Обрати внимание на запятую и пробел
 		AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Idempotency-Key",
 		AllowCredentials: true,
 		ExposeHeaders:    "Set-Cookie", // Важно для cookies!
 	}))
@@ -93,17 +130,69 @@ func Start() {
 
 	AuthRouter.RegisterAuthRouter(app, authHandler)
 
+	inventoryRepo := InventoryRepository.NewInventoryRepository()
+	inventoryService := InventoryService.NewInventoryService(inventoryRepo)
+
+	addressRepo := ShippingRepository.NewAddressRepository()
+	shippingService := ShippingService.NewShippingService(addressRepo, cartRepo, ShippingProvider.NewFlatRateProvider())
+	shippingHandler := ShippingHandler.NewShippingHandler(shippingService)
+
+	ShippingRouter.RegisterShippingRouter(app, shippingHandler)
+
+	idempotencyRepo := IdempotencyRepository.NewIdempotencyRepository()
+	idempotencyRetention := idempotency.Retention(config.IdempotencyKeyTTLHours)
+	idempotency.StartCleanup(idempotencyRepo, time.Hour)
+
 	orderRepo := OrderRepository.NewOrderRepository()
-	orderService := OrderService.NewOrderService(orderRepo, cartRepo, cartService, procService, flashdriveService)
-	orderHandler := OrderHandler.NewOrderHandler(orderService)
+	companyRepo := CompanyRepository.NewCompanyRepository()
 
-	OrderRouter.RegisterOrderRouter(app, orderHandler)
+	loyaltyRepo := LoyaltyRepository.NewLoyaltyRepository()
+	loyaltyService := LoyaltyService.NewLoyaltyService(loyaltyRepo)
+	loyaltyHandler := LoyaltyHandler.NewLoyaltyHandler(loyaltyService)
+	loyaltyService.StartExpiryWatcher(time.Hour)
+
+	LoyaltyRouter.RegisterLoyaltyRouter(app, loyaltyHandler)
 
 	paymentRepo := PaymentRepo.NewPaymentRepository()
-	paymentService := PaymentService.NewPaymentService(paymentRepo, orderRepo)
+	paymentService := PaymentService.NewPaymentService(paymentRepo, orderRepo, newPaymentProvider(app), loyaltyService)
 	paymentHandler := PaymentHandler.NewPaymentHandler(paymentService, orderRepo)
+	paymentService.StartReconciliation(
+		PaymentService.ReconcileInterval(config.PaymentReconcileIntervalMinutes),
+		PaymentService.ReconcileAfter(config.PaymentReconcileAfterMinutes),
+	)
+
+	PaymentRouter.RegisterPaymentRouter(app, paymentHandler,
+		idempotency.Idempotency(idempotencyRepo, idempotency.ScopePaymentCreate, idempotencyRetention))
+
+	shipmentRepo := ShippingRepository.NewShipmentRepository()
+	shipmentService := ShippingService.NewShipmentService(shipmentRepo, orderRepo, paymentService)
+	shipmentHandler := ShippingHandler.NewShipmentHandler(shipmentService)
 
-	PaymentRouter.RegisterPaymentRouter(app, paymentHandler)
+	ShippingRouter.RegisterShipmentRouter(app, shipmentHandler)
+
+	orderService := OrderService.NewOrderService(orderRepo, cartRepo, cartService, procService, flashdriveService, inventoryService, paymentService, shippingService, companyRepo, loyaltyService)
+	orderHandler := OrderHandler.NewOrderHandler(orderService)
+	orderService.StartPaymentDeadlineWatcher(time.Minute)
+
+	OrderRouter.RegisterOrderRouter(app, orderHandler,
+		idempotency.Idempotency(idempotencyRepo, idempotency.ScopeOrderCreate, idempotencyRetention))
+
+	companyService := CompanyService.NewCompanyService(companyRepo, orderRepo, orderService)
+	companyHandler := CompanyHandler.NewCompanyHandler(companyService)
+
+	CompanyRouter.RegisterCompanyRouter(app, companyHandler)
+
+	documentRepo := DocumentRepository.NewDocumentRepository()
+	documentService := DocumentService.NewDocumentService(documentRepo, companyRepo, miniStorage)
+	documentHandler := DocumentHandler.NewDocumentHandler(documentService)
+
+	DocumentRouter.RegisterDocumentRouter(app, documentHandler)
+
+	returnRepo := ReturnRepository.NewReturnRepository()
+	returnService := ReturnService.NewReturnService(returnRepo, orderRepo, inventoryService, paymentService, miniStorage)
+	returnHandler := ReturnHandler.NewReturnHandler(returnService)
+
+	ReturnRouter.RegisterReturnRouter(app, returnHandler)
 
 	messageRepo := MessageRepository.NewMessageRepository()
 	messageService := MessageService.NewMessageService(messageRepo)
@@ -111,6 +200,12 @@ func Start() {
 
 	MessageRouter.RegisterMessageRoutes(app, messageHandler)
 
+	orderThreadRepo := MessageRepository.NewOrderThreadRepository()
+	orderThreadService := MessageService.NewOrderThreadService(orderThreadRepo, miniStorage)
+	orderThreadHandler := MessageHandler.NewOrderThreadHandler(orderThreadService)
+
+	MessageRouter.RegisterOrderThreadRoutes(app, orderThreadHandler)
+
 	if config.AppPort != "" {
 		err = app.Listen(":" + config.AppPort)
 	} else {
@@ -120,3 +215,30 @@ func Start() {
 		log.Fatal(err)
 	}
 }
+
+// newPaymentProvider выбирает платёжный шлюз по PAYMENT_PROVIDER.
+// fake работает без внешних сервисов и регистрирует свою страницу подтверждения оплаты.
+func newPaymentProvider(app *fiber.App) PaymentProvider.PaymentProvider {
+	if config.PaymentProvider == "fake" {
+		baseURL := config.AppBaseURL
+		if baseURL == "" {
+			port := config.AppPort
+			if port == "" {
+				port = "3000"
+			}
+			baseURL = "http://localhost:" + port
+		}
+
+		fake := PaymentProvider.NewFakeProvider(baseURL, config.YKassaWebhookSecret)
+		PaymentRouter.RegisterFakePaymentRouter(app, PaymentHandler.NewFakePaymentHandler(fake))
+		log.Println("⚠️ using fake payment provider")
+		return fake
+	}
+
+	return PaymentProvider.NewYooKassaProvider(
+		config.YKassaShopID,
+		config.YKassaSecretKey,
+		config.YKassaWebhookSecret,
+		config.YKassaTestMode == "true",
+	)
+}
@@ -0,0 +1,21 @@
+package dto
+
+import (
+	"Market_backend/internal/common/validate"
+)
+
+type AddressDTO struct {
+	Recipient  string `json:"recipient"`
+	Phone      string `json:"phone"`
+	City       string `json:"city" validate:"required"`
+	Street     string `json:"street" validate:"required"`
+	House      string `json:"house" validate:"required"`
+	Apartment  string `json:"apartment"`
+	PostalCode string `json:"postal_code"`
+	Comment    string `json:"comment"`
+	IsDefault  bool   `json:"is_default"`
+}
+
+func (a *AddressDTO) Validate() error {
+	return validate.Validate.Struct(a)
+}
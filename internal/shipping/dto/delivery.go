@@ -0,0 +1,32 @@
+package dto
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+
+	"github.com/google/uuid"
+)
+
+type DeliveryMethodDTO struct {
+	Method          types.DeliveryMethod `json:"method"`
+	RequiresAddress bool                 `json:"requires_address"`
+}
+
+// DeliveryRequest — выбор способа доставки при оформлении заказа
+type DeliveryRequest struct {
+	Method    types.DeliveryMethod `json:"delivery_method"`
+	AddressID *uuid.UUID           `json:"address_id"`
+}
+
+// ParcelItem — позиция, из которой собирается посылка
+type ParcelItem struct {
+	ProductID   uuid.UUID
+	ProductType types.ProductType
+	Quantity    int
+}
+
+type QuoteResponse struct {
+	Method   types.DeliveryMethod `json:"delivery_method"`
+	Provider string               `json:"provider"`
+	Cost     money.Money          `json:"cost"`
+}
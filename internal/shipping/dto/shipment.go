@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"Market_backend/internal/common/types"
+
+	"github.com/google/uuid"
+)
+
+type ShipmentItemDTO struct {
+	OrderItemID uuid.UUID `json:"order_item_id"`
+	Quantity    int       `json:"quantity"`
+}
+
+// CreateShipmentDTO — пустой Items означает «отгрузить всё, что ещё не отгружено»
+type CreateShipmentDTO struct {
+	OrderID        uuid.UUID         `json:"order_id"`
+	Carrier        string            `json:"carrier"`
+	TrackingNumber string            `json:"tracking_number"`
+	Items          []ShipmentItemDTO `json:"items"`
+}
+
+type UpdateShipmentDTO struct {
+	Status         types.ShipmentStatus `json:"status"`
+	Carrier        *string              `json:"carrier"`
+	TrackingNumber *string              `json:"tracking_number"`
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/shipping/dto"
+	"Market_backend/internal/shipping/service"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ShipmentHandler struct {
+	service *service.ShipmentService
+}
+
+func NewShipmentHandler(service *service.ShipmentService) *ShipmentHandler {
+	return &ShipmentHandler{service: service}
+}
+
+func (h *ShipmentHandler) GetOrderShipments(c *fiber.Ctx) error {
+	orderId, err := uuid.Parse(c.Query("order_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "order_id is required",
+		})
+	}
+
+	shipments, err := h.service.GetOrderShipments(orderId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"shipments": shipments,
+	})
+}
+
+func (h *ShipmentHandler) CreateShipment(c *fiber.Ctx) error {
+	var body dto.CreateShipmentDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	shipment, err := h.service.CreateShipment(body)
+	if err != nil {
+		return shipmentError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"shipment": shipment,
+	})
+}
+
+func (h *ShipmentHandler) UpdateShipment(c *fiber.Ctx) error {
+	adminId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	shipmentId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid shipment id",
+		})
+	}
+
+	var body dto.UpdateShipmentDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if body.Status != "" && !body.Status.IsValid() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid status",
+		})
+	}
+
+	shipment, err := h.service.UpdateShipment(adminId, shipmentId, body)
+	if err != nil {
+		return shipmentError(c, err)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"shipment": shipment,
+	})
+}
+
+func shipmentError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "not found",
+		})
+	case errors.Is(err, service.ErrOrderNotFulfillable),
+		errors.Is(err, service.ErrNothingToShip),
+		errors.Is(err, types.ErrInvalidShipmentTransition),
+		errors.Is(err, types.ErrInvalidOrderTransition):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
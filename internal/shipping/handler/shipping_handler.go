@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/shipping/dto"
+	"Market_backend/internal/shipping/service"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ShippingHandler struct {
+	service *service.ShippingService
+}
+
+func NewShippingHandler(service *service.ShippingService) *ShippingHandler {
+	return &ShippingHandler{service: service}
+}
+
+func (h *ShippingHandler) GetMethods(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"methods": h.service.GetMethods(),
+	})
+}
+
+func (h *ShippingHandler) Quote(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	cartId, err := uuid.Parse(c.Query("cart_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "cart_id is required",
+		})
+	}
+
+	delivery, err := ParseDeliveryRequest(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	quote, err := h.service.QuoteCart(userId, cartId, delivery)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(quote)
+}
+
+func (h *ShippingHandler) GetAddresses(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	addresses, err := h.service.GetAddresses(userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"addresses": addresses,
+	})
+}
+
+func (h *ShippingHandler) CreateAddress(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var body dto.AddressDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	address, err := h.service.CreateAddress(userId, body)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"address": address,
+	})
+}
+
+func (h *ShippingHandler) UpdateAddress(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	addressId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid address id",
+		})
+	}
+
+	var body dto.AddressDTO
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	address, err := h.service.UpdateAddress(userId, addressId, body)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "address not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"address": address,
+	})
+}
+
+func (h *ShippingHandler) DeleteAddress(c *fiber.Ctx) error {
+	userId, err := utils.GetUserId(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	addressId, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid address id",
+		})
+	}
+
+	if err := h.service.DeleteAddress(userId, addressId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "address not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "address deleted",
+	})
+}
+
+// ParseDeliveryRequest читает delivery_method и address_id из query.
+// Без delivery_method — самовывоз, как было до появления доставки.
+func ParseDeliveryRequest(c *fiber.Ctx) (dto.DeliveryRequest, error) {
+	delivery := dto.DeliveryRequest{Method: types.DeliveryStorePickup}
+
+	if method := c.Query("delivery_method"); method != "" {
+		delivery.Method = types.DeliveryMethod(method)
+		if !delivery.Method.IsValid() {
+			return delivery, errors.New("invalid delivery_method")
+		}
+	}
+
+	if addressIdStr := c.Query("address_id"); addressIdStr != "" {
+		addressId, err := uuid.Parse(addressIdStr)
+		if err != nil {
+			return delivery, errors.New("invalid address_id")
+		}
+		delivery.AddressID = &addressId
+	}
+
+	return delivery, nil
+}
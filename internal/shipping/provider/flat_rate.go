@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/common/utils"
+	"Market_backend/internal/config"
+	"Market_backend/models"
+	"fmt"
+	"math"
+)
+
+// volumetricDivisor — делитель объёмного веса: см³ / 5000 = кг
+const volumetricDivisor = 5000.0
+
+// FlatRateProvider — встроенный расчёт: базовая ставка способа + доплата за каждый начатый килограмм
+type FlatRateProvider struct {
+	BaseRates map[types.DeliveryMethod]money.Money
+	PerKg     money.Money
+}
+
+func NewFlatRateProvider() *FlatRateProvider {
+	return &FlatRateProvider{
+		BaseRates: map[types.DeliveryMethod]money.Money{
+			types.DeliveryCourier:     envMoney(config.ShippingCourierBase, money.New(40000)),
+			types.DeliveryPickupPoint: envMoney(config.ShippingPickupPointBase, money.New(20000)),
+			types.DeliveryStorePickup: {},
+		},
+		PerKg: envMoney(config.ShippingPerKg, money.New(5000)),
+	}
+}
+
+func (p *FlatRateProvider) Name() string {
+	return "flat_rate"
+}
+
+func (p *FlatRateProvider) Methods() []types.DeliveryMethod {
+	return []types.DeliveryMethod{types.DeliveryCourier, types.DeliveryPickupPoint, types.DeliveryStorePickup}
+}
+
+func (p *FlatRateProvider) Calculate(method types.DeliveryMethod, parcel Parcel, address *models.Address) (money.Money, error) {
+	base, ok := p.BaseRates[method]
+	if !ok {
+		return money.Money{}, fmt.Errorf("unknown delivery method: %s", method)
+	}
+
+	if method == types.DeliveryStorePickup {
+		return money.Money{}, nil
+	}
+
+	if method.RequiresAddress() && address == nil {
+		return money.Money{}, fmt.Errorf("address is required for delivery method %s", method)
+	}
+
+	// Оплачиваемый вес — больший из фактического и объёмного
+	actualKg := parcel.WeightG / 1000
+	volumetricKg := parcel.VolumeCM / volumetricDivisor
+	chargeableKg := math.Ceil(math.Max(actualKg, volumetricKg))
+
+	return base.Add(p.PerKg.Mul(int(chargeableKg))), nil
+}
+
+func envMoney(value string, def money.Money) money.Money {
+	if value == "" {
+		return def
+	}
+	return utils.ParseMoney(value)
+}
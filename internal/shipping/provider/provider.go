@@ -0,0 +1,20 @@
+package provider
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+)
+
+// Parcel — посылка, собранная из позиций заказа
+type Parcel struct {
+	WeightG  float64 // фактический вес, г
+	VolumeCM float64 // объём, см³
+}
+
+// ShippingProvider рассчитывает стоимость доставки посылки выбранным способом
+type ShippingProvider interface {
+	Name() string
+	Methods() []types.DeliveryMethod
+	Calculate(method types.DeliveryMethod, parcel Parcel, address *models.Address) (money.Money, error)
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AddressRepository struct {
+	db *gorm.DB
+}
+
+func NewAddressRepository() *AddressRepository {
+	return &AddressRepository{db: common.DB}
+}
+
+func (r *AddressRepository) DB() *gorm.DB {
+	return r.db
+}
+
+func (r *AddressRepository) GetByUser(userId uuid.UUID) ([]models.Address, error) {
+	var addresses []models.Address
+	if err := r.db.
+		Where("user_id = ?", userId).
+		Order("is_default DESC, created_at DESC").
+		Find(&addresses).Error; err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+func (r *AddressRepository) GetByIdTx(tx *gorm.DB, userId, addressId uuid.UUID) (*models.Address, error) {
+	var address models.Address
+	if err := tx.First(&address, "id = ? AND user_id = ?", addressId, userId).Error; err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (r *AddressRepository) CreateTx(tx *gorm.DB, address *models.Address) error {
+	return tx.Create(address).Error
+}
+
+func (r *AddressRepository) SaveTx(tx *gorm.DB, address *models.Address) error {
+	return tx.Save(address).Error
+}
+
+// ResetDefaultTx снимает отметку «по умолчанию» со всех адресов пользователя
+func (r *AddressRepository) ResetDefaultTx(tx *gorm.DB, userId uuid.UUID) error {
+	return tx.Model(&models.Address{}).
+		Where("user_id = ? AND is_default = true", userId).
+		Update("is_default", false).Error
+}
+
+func (r *AddressRepository) Delete(userId, addressId uuid.UUID) error {
+	res := r.db.Where("id = ? AND user_id = ?", addressId, userId).Delete(&models.Address{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
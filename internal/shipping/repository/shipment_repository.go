@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"Market_backend/internal/common"
+	"Market_backend/internal/common/types"
+	"Market_backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ShipmentRepository struct {
+	db *gorm.DB
+}
+
+func NewShipmentRepository() *ShipmentRepository {
+	return &ShipmentRepository{db: common.DB}
+}
+
+func (r *ShipmentRepository) DB() *gorm.DB {
+	return r.db
+}
+
+func (r *ShipmentRepository) CreateTx(tx *gorm.DB, shipment *models.Shipment) error {
+	return tx.Create(shipment).Error
+}
+
+func (r *ShipmentRepository) SaveTx(tx *gorm.DB, shipment *models.Shipment) error {
+	return tx.Omit("Items").Save(shipment).Error
+}
+
+func (r *ShipmentRepository) GetByIdForUpdateTx(tx *gorm.DB, shipmentId uuid.UUID) (*models.Shipment, error) {
+	var shipment models.Shipment
+	if err := tx.
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&shipment, "id = ?", shipmentId).Error; err != nil {
+		return nil, err
+	}
+	return &shipment, nil
+}
+
+func (r *ShipmentRepository) GetByOrder(orderId uuid.UUID) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	if err := r.db.
+		Preload("Items").
+		Where("order_id = ?", orderId).
+		Order("created_at ASC").
+		Find(&shipments).Error; err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+func (r *ShipmentRepository) GetActiveByOrderTx(tx *gorm.DB, orderId uuid.UUID) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	if err := tx.
+		Preload("Items").
+		Where("order_id = ? AND status <> ?", orderId, types.ShipmentCancelled).
+		Find(&shipments).Error; err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
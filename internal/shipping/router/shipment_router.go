@@ -0,0 +1,16 @@
+package router
+
+import (
+	"Market_backend/internal/middleware"
+	"Market_backend/internal/shipping/handler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterShipmentRouter(app *fiber.App, h *handler.ShipmentHandler) {
+	shipments := app.Group("/shipping/shipments", middleware.AuthRequired(), middleware.AdminOnly())
+
+	shipments.Get("/", h.GetOrderShipments)
+	shipments.Post("/", h.CreateShipment)
+	shipments.Patch("/:id", h.UpdateShipment)
+}
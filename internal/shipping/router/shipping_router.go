@@ -0,0 +1,21 @@
+package router
+
+import (
+	"Market_backend/internal/middleware"
+	"Market_backend/internal/shipping/handler"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func RegisterShippingRouter(app *fiber.App, h *handler.ShippingHandler) {
+	shipping := app.Group("/shipping")
+
+	shipping.Get("/methods", h.GetMethods)
+	shipping.Get("/quote", middleware.AuthRequired(), h.Quote)
+
+	// Адресная книга пользователя
+	shipping.Get("/addresses", middleware.AuthRequired(), h.GetAddresses)
+	shipping.Post("/addresses", middleware.AuthRequired(), h.CreateAddress)
+	shipping.Patch("/addresses/:id", middleware.AuthRequired(), h.UpdateAddress)
+	shipping.Delete("/addresses/:id", middleware.AuthRequired(), h.DeleteAddress)
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"Market_backend/internal/common/types"
+	OrderRepository "Market_backend/internal/order/repository"
+	PaymentService "Market_backend/internal/payment/service"
+	"Market_backend/internal/shipping/dto"
+	"Market_backend/internal/shipping/repository"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrOrderNotFulfillable = errors.New("отгружать можно только оплаченный заказ")
+	ErrNothingToShip       = errors.New("все позиции заказа уже отгружены")
+)
+
+type ShipmentService struct {
+	repo           *repository.ShipmentRepository
+	orderRepo      *OrderRepository.OrderRepository
+	paymentService *PaymentService.PaymentService
+}
+
+func NewShipmentService(
+	repo *repository.ShipmentRepository,
+	orderRepo *OrderRepository.OrderRepository,
+	paymentService *PaymentService.PaymentService,
+) *ShipmentService {
+	return &ShipmentService{repo: repo, orderRepo: orderRepo, paymentService: paymentService}
+}
+
+func (s *ShipmentService) GetOrderShipments(orderId uuid.UUID) ([]models.Shipment, error) {
+	return s.repo.GetByOrder(orderId)
+}
+
+// CreateShipment создаёт отгрузку по части (или всем оставшимся) позициям оплаченного заказа,
+// заказа с заблокированной оплатой или заказа с оплатой при получении
+func (s *ShipmentService) CreateShipment(shipmentDto dto.CreateShipmentDTO) (*models.Shipment, error) {
+	shipment := &models.Shipment{
+		ID:             uuid.New(),
+		OrderID:        shipmentDto.OrderID,
+		Carrier:        shipmentDto.Carrier,
+		TrackingNumber: shipmentDto.TrackingNumber,
+		Status:         types.ShipmentPending,
+	}
+
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		var order models.Order
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&order, "id = ?", shipmentDto.OrderID).Error; err != nil {
+			return err
+		}
+		if order.Status != types.Paid && order.Status != types.Shipped {
+			if order.Status != types.InProgress {
+				return ErrOrderNotFulfillable
+			}
+			// двухстадийная оплата: деньги заблокированы и спишутся, когда отгрузка уйдёт со склада
+			held, err := s.paymentService.HasHoldTx(tx, order.ID)
+			if err != nil {
+				return err
+			}
+			// оплата при получении: заказ отгружается неоплаченным
+			cod, err := s.paymentService.HasCashOnDeliveryTx(tx, order.ID)
+			if err != nil {
+				return err
+			}
+			if !held && !cod {
+				return ErrOrderNotFulfillable
+			}
+		}
+
+		orderItems, err := s.orderRepo.GetOrderItemsTx(tx, order.ID)
+		if err != nil {
+			return err
+		}
+
+		remaining, err := s.remainingQuantitiesTx(tx, order.ID, orderItems)
+		if err != nil {
+			return err
+		}
+
+		requested := shipmentDto.Items
+		if len(requested) == 0 {
+			for _, item := range orderItems {
+				if remaining[item.ID] > 0 {
+					requested = append(requested, dto.ShipmentItemDTO{OrderItemID: item.ID, Quantity: remaining[item.ID]})
+				}
+			}
+		}
+		if len(requested) == 0 {
+			return ErrNothingToShip
+		}
+
+		for _, item := range requested {
+			left, ok := remaining[item.OrderItemID]
+			if !ok {
+				return fmt.Errorf("order item %s does not belong to order", item.OrderItemID)
+			}
+			if item.Quantity <= 0 || item.Quantity > left {
+				return fmt.Errorf("order item %s: можно отгрузить не более %d шт.", item.OrderItemID, left)
+			}
+			remaining[item.OrderItemID] -= item.Quantity
+
+			shipment.Items = append(shipment.Items, models.ShipmentItem{
+				ID:          uuid.New(),
+				ShipmentID:  shipment.ID,
+				OrderItemID: item.OrderItemID,
+				Quantity:    item.Quantity,
+			})
+		}
+
+		return s.repo.CreateTx(tx, shipment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return shipment, nil
+}
+
+// UpdateShipment меняет статус/трек-номер отгрузки и пересчитывает статус заказа
+func (s *ShipmentService) UpdateShipment(adminId, shipmentId uuid.UUID, update dto.UpdateShipmentDTO) (*models.Shipment, error) {
+	var shipment *models.Shipment
+
+	err := s.repo.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		shipment, err = s.repo.GetByIdForUpdateTx(tx, shipmentId)
+		if err != nil {
+			return err
+		}
+
+		if update.Carrier != nil {
+			shipment.Carrier = *update.Carrier
+		}
+		if update.TrackingNumber != nil {
+			shipment.TrackingNumber = *update.TrackingNumber
+		}
+
+		if update.Status != "" && update.Status != shipment.Status {
+			if !shipment.Status.CanTransitionTo(update.Status) {
+				return fmt.Errorf("%w: %s -> %s", types.ErrInvalidShipmentTransition, shipment.Status, update.Status)
+			}
+
+			now := time.Now()
+			shipment.Status = update.Status
+			if update.Status.IsDispatched() && shipment.ShippedAt == nil {
+				shipment.ShippedAt = &now
+			}
+			if update.Status == types.ShipmentDelivered {
+				shipment.DeliveredAt = &now
+			}
+		}
+
+		if err := s.repo.SaveTx(tx, shipment); err != nil {
+			return err
+		}
+
+		return s.syncOrderStatusTx(tx, shipment.OrderID, adminId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.paymentService.SettleOrder(shipment.OrderID)
+	return shipment, nil
+}
+
+// remainingQuantitiesTx — сколько единиц каждой позиции ещё не попало в активные отгрузки
+func (s *ShipmentService) remainingQuantitiesTx(tx *gorm.DB, orderId uuid.UUID, orderItems []models.OrderItem) (map[uuid.UUID]int, error) {
+	shipments, err := s.repo.GetActiveByOrderTx(tx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make(map[uuid.UUID]int, len(orderItems))
+	for _, item := range orderItems {
+		remaining[item.ID] = item.Quantity
+	}
+	for _, sh := range shipments {
+		for _, si := range sh.Items {
+			remaining[si.OrderItemID] -= si.Quantity
+		}
+	}
+	return remaining, nil
+}
+
+// syncOrderStatusTx выводит статус заказа из состояний отгрузок:
+// хотя бы одна отгрузка ушла со склада — shipped,
+// все позиции отгружены и все отгрузки вручены — delivered.
+func (s *ShipmentService) syncOrderStatusTx(tx *gorm.DB, orderId, adminId uuid.UUID) error {
+	var order models.Order
+	if err := tx.First(&order, "id = ?", orderId).Error; err != nil {
+		return err
+	}
+
+	orderItems, err := s.orderRepo.GetOrderItemsTx(tx, orderId)
+	if err != nil {
+		return err
+	}
+
+	shipments, err := s.repo.GetActiveByOrderTx(tx, orderId)
+	if err != nil {
+		return err
+	}
+
+	remaining, err := s.remainingQuantitiesTx(tx, orderId, orderItems)
+	if err != nil {
+		return err
+	}
+
+	fullyAllocated := true
+	for _, left := range remaining {
+		if left > 0 {
+			fullyAllocated = false
+			break
+		}
+	}
+
+	anyDispatched := false
+	allDelivered := len(shipments) > 0
+	for _, sh := range shipments {
+		if sh.Status.IsDispatched() {
+			anyDispatched = true
+		}
+		if sh.Status != types.ShipmentDelivered {
+			allDelivered = false
+		}
+	}
+
+	var path []types.OrderStatus
+	switch {
+	case fullyAllocated && allDelivered:
+		path = []types.OrderStatus{types.Shipped, types.Delivered}
+	case anyDispatched:
+		path = []types.OrderStatus{types.Shipped}
+	default:
+		return nil
+	}
+
+	current := order.Status
+
+	// Первая отгрузка ушла со склада — списываем заблокированные деньги. Шлюзу списание уходит
+	// после фиксации (SettleOrder), заказ отгружается, не дожидаясь его подтверждения.
+	if current == types.InProgress {
+		if _, err := s.paymentService.CaptureOrderHoldTx(tx, orderId, &adminId); err != nil {
+			return err
+		}
+	}
+
+	for _, next := range path {
+		if current == next || !current.CanTransitionTo(next) {
+			continue
+		}
+		if err := s.orderRepo.ChangeStatusTx(tx, orderId, next, &adminId, "по данным отгрузок"); err != nil {
+			return err
+		}
+		current = next
+	}
+
+	return nil
+}
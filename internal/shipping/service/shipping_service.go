@@ -0,0 +1,224 @@
+package service
+
+import (
+	CartRepository "Market_backend/internal/cart/repository"
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"Market_backend/internal/shipping/dto"
+	"Market_backend/internal/shipping/provider"
+	"Market_backend/internal/shipping/repository"
+	"Market_backend/models"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// У процессоров нет веса и габаритов в карточке — считаем по стандартной коробке
+const (
+	processorWeightG  = 150.0
+	processorVolumeCM = 12 * 12 * 6
+)
+
+var ErrAddressRequired = errors.New("для выбранного способа доставки нужен адрес")
+
+type ShippingService struct {
+	addressRepo *repository.AddressRepository
+	cartRepo    *CartRepository.CartRepository
+	provider    provider.ShippingProvider
+}
+
+func NewShippingService(
+	addressRepo *repository.AddressRepository,
+	cartRepo *CartRepository.CartRepository,
+	provider provider.ShippingProvider,
+) *ShippingService {
+	return &ShippingService{addressRepo: addressRepo, cartRepo: cartRepo, provider: provider}
+}
+
+// ===================== Addresses =====================
+
+func (s *ShippingService) GetAddresses(userId uuid.UUID) ([]models.Address, error) {
+	return s.addressRepo.GetByUser(userId)
+}
+
+func (s *ShippingService) CreateAddress(userId uuid.UUID, addressDto dto.AddressDTO) (*models.Address, error) {
+	var address *models.Address
+	err := s.addressRepo.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		address, err = s.CreateAddressTx(tx, userId, addressDto)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// CreateAddressTx — добавление адреса внутри чужой транзакции (например, при оформлении заказа менеджером)
+func (s *ShippingService) CreateAddressTx(tx *gorm.DB, userId uuid.UUID, addressDto dto.AddressDTO) (*models.Address, error) {
+	if err := addressDto.Validate(); err != nil {
+		return nil, err
+	}
+
+	address := &models.Address{ID: uuid.New(), UserID: userId}
+	applyAddress(address, addressDto)
+
+	// Первый адрес пользователя становится адресом по умолчанию
+	var count int64
+	if err := tx.Model(&models.Address{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		address.IsDefault = true
+	}
+
+	if address.IsDefault {
+		if err := s.addressRepo.ResetDefaultTx(tx, userId); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.addressRepo.CreateTx(tx, address); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+func (s *ShippingService) UpdateAddress(userId, addressId uuid.UUID, addressDto dto.AddressDTO) (*models.Address, error) {
+	if err := addressDto.Validate(); err != nil {
+		return nil, err
+	}
+
+	var address *models.Address
+	err := s.addressRepo.DB().Transaction(func(tx *gorm.DB) error {
+		var err error
+		address, err = s.addressRepo.GetByIdTx(tx, userId, addressId)
+		if err != nil {
+			return err
+		}
+
+		applyAddress(address, addressDto)
+
+		if address.IsDefault {
+			if err := s.addressRepo.ResetDefaultTx(tx, userId); err != nil {
+				return err
+			}
+		}
+		return s.addressRepo.SaveTx(tx, address)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+func (s *ShippingService) DeleteAddress(userId, addressId uuid.UUID) error {
+	return s.addressRepo.Delete(userId, addressId)
+}
+
+func applyAddress(address *models.Address, addressDto dto.AddressDTO) {
+	address.Recipient = addressDto.Recipient
+	address.Phone = addressDto.Phone
+	address.City = addressDto.City
+	address.Street = addressDto.Street
+	address.House = addressDto.House
+	address.Apartment = addressDto.Apartment
+	address.PostalCode = addressDto.PostalCode
+	address.Comment = addressDto.Comment
+	address.IsDefault = addressDto.IsDefault
+}
+
+// ===================== Delivery =====================
+
+func (s *ShippingService) GetMethods() []dto.DeliveryMethodDTO {
+	methods := s.provider.Methods()
+	result := make([]dto.DeliveryMethodDTO, 0, len(methods))
+	for _, m := range methods {
+		result = append(result, dto.DeliveryMethodDTO{Method: m, RequiresAddress: m.RequiresAddress()})
+	}
+	return result
+}
+
+// QuoteCart считает доставку для текущего содержимого корзины
+func (s *ShippingService) QuoteCart(userId, cartId uuid.UUID, delivery dto.DeliveryRequest) (*dto.QuoteResponse, error) {
+	cartItems, err := s.cartRepo.GetAllCartItems(userId, cartId)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.ParcelItem, 0, len(cartItems))
+	for _, ci := range cartItems {
+		items = append(items, dto.ParcelItem{ProductID: ci.ProductId, ProductType: ci.ProductType, Quantity: ci.Quantity})
+	}
+
+	cost, _, err := s.QuoteTx(s.addressRepo.DB(), userId, delivery, items)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.QuoteResponse{Method: delivery.Method, Provider: s.provider.Name(), Cost: cost}, nil
+}
+
+// QuoteTx проверяет способ и адрес доставки и считает стоимость.
+// Возвращает адрес пользователя (nil для самовывоза), чтобы сохранить его в заказе.
+func (s *ShippingService) QuoteTx(tx *gorm.DB, userId uuid.UUID, delivery dto.DeliveryRequest, items []dto.ParcelItem) (money.Money, *models.Address, error) {
+	if !delivery.Method.IsValid() {
+		return money.Money{}, nil, fmt.Errorf("unknown delivery method: %s", delivery.Method)
+	}
+
+	var address *models.Address
+	if delivery.Method.RequiresAddress() {
+		if delivery.AddressID == nil {
+			return money.Money{}, nil, ErrAddressRequired
+		}
+		var err error
+		address, err = s.addressRepo.GetByIdTx(tx, userId, *delivery.AddressID)
+		if err != nil {
+			return money.Money{}, nil, fmt.Errorf("address not found: %w", err)
+		}
+	}
+
+	parcel, err := s.buildParcelTx(tx, items)
+	if err != nil {
+		return money.Money{}, nil, err
+	}
+
+	cost, err := s.provider.Calculate(delivery.Method, parcel, address)
+	if err != nil {
+		return money.Money{}, nil, err
+	}
+
+	return cost, address, nil
+}
+
+func (s *ShippingService) buildParcelTx(tx *gorm.DB, items []dto.ParcelItem) (provider.Parcel, error) {
+	var parcel provider.Parcel
+
+	for _, item := range items {
+		qty := float64(item.Quantity)
+
+		switch item.ProductType {
+		case types.Processor:
+			parcel.WeightG += processorWeightG * qty
+			parcel.VolumeCM += processorVolumeCM * qty
+
+		case types.FlashDriver:
+			var fd models.FlashDrive
+			if err := tx.Select("id", "weight_g", "length_mm", "width_mm", "thickness_mm").
+				First(&fd, "id = ?", item.ProductID).Error; err != nil {
+				return parcel, err
+			}
+			parcel.WeightG += fd.WeightG * qty
+			// мм³ -> см³
+			parcel.VolumeCM += fd.LengthMM * fd.WidthMM * fd.ThicknessMM / 1000 * qty
+
+		default:
+			return parcel, fmt.Errorf("unknown product type: %s", item.ProductType)
+		}
+	}
+
+	return parcel, nil
+}
@@ -2,7 +2,10 @@ package storage
 
 import (
 	"Market_backend/internal/config"
+	"bytes"
 	"context"
+	"io"
+
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
@@ -51,6 +54,19 @@ func (s *MinioStorage) Upload(ctx context.Context, objectName, filePath string)
 	return s.Endpoint + "/" + s.Bucket + "/" + objectName, nil
 }
 
+// UploadBytes сохраняет содержимое из памяти (например, сгенерированный PDF)
+func (s *MinioStorage) UploadBytes(ctx context.Context, objectName string, data []byte, contentType string) error {
+	_, err := s.Client.PutObject(ctx, s.Bucket, objectName, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	return err
+}
+
+// Download открывает объект на чтение; закрыть его должен вызывающий
+func (s *MinioStorage) Download(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	return s.Client.GetObject(ctx, s.Bucket, objectName, minio.GetObjectOptions{})
+}
+
 func (s *MinioStorage) Delete(ctx context.Context, objectName string) error {
 	return s.Client.RemoveObject(ctx, s.Bucket, objectName, minio.RemoveObjectOptions{})
 }
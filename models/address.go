@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Address struct {
+	ID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;index"`
+
+	Recipient  string
+	Phone      string
+	City       string `gorm:"not null"`
+	Street     string `gorm:"not null"`
+	House      string `gorm:"not null"`
+	Apartment  string
+	PostalCode string
+	Comment    string
+	IsDefault  bool `gorm:"not null;default:false"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// String — адрес одной строкой, сохраняется в заказ как снимок
+func (a *Address) String() string {
+	parts := []string{a.PostalCode, a.City, a.Street, "д. " + a.House}
+	if a.Apartment != "" {
+		parts = append(parts, "кв. "+a.Apartment)
+	}
+
+	var nonEmpty []string
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+	return strings.Join(nonEmpty, ", ")
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	"github.com/google/uuid"
 	"time"
@@ -19,7 +20,7 @@ type CartItem struct {
 	//FlashDriveID uuid.UUID
 	//FlashDrive   *FlashDrive
 	Quantity  int
-	UnitPrice float64 // текущая цена на момент добавления в корзину
+	UnitPrice money.Money // текущая цена на момент добавления в корзину
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
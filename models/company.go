@@ -0,0 +1,41 @@
+package models
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Company — оптовый клиент-юрлицо, заказы оформляют его участники
+type Company struct {
+	ID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Name    string    `gorm:"not null"`
+	INN     string    `gorm:"not null;uniqueIndex"`
+	KPP     string
+	Address string
+
+	Bank        string
+	BIK         string
+	Account     string
+	CorrAccount string
+
+	Members   []CompanyMember `gorm:"foreignKey:CompanyID;constraint:OnDelete:CASCADE;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CompanyMember struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	CompanyID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_company_member"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_company_member"`
+	User      User
+	Role      types.CompanyRole `gorm:"type:company_role;not null;default:buyer"`
+
+	// Сумма заказа, выше которой нужен согласующий. nil — без ограничений
+	ApprovalLimit *money.Money
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"crypto/subtle"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,14 +14,34 @@ type EmailConfirmation struct {
 	User   User
 
 	Email     string `gorm:"not null;index"`         // 👈 для rate-limit
-	Type      string `gorm:"size:16;not null;index"` // login | register
+	Type      string `gorm:"size:16;not null;index"` // login | register | reset
 	Code      string `gorm:"size:6;not null"`
 	ExpiresAt time.Time
 	Used      bool `gorm:"not null;default:false;index"`
+	Attempts  int  `gorm:"not null;default:0"` // неверные вводы кода
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// MaxCodeAttempts — после стольких неверных вводов код больше не принимается
+const MaxCodeAttempts = 5
+
+// Attempt проверяет введённый код. Неверный ввод засчитывается, и после MaxCodeAttempts
+// промахов код помечается использованным — дальше не подойдёт даже верный.
+func (e *EmailConfirmation) Attempt(code string) bool {
+	if e.Used {
+		return false
+	}
+	if subtle.ConstantTimeCompare([]byte(e.Code), []byte(code)) == 1 {
+		return true
+	}
+	e.Attempts++
+	if e.Attempts >= MaxCodeAttempts {
+		e.Used = true
+	}
+	return false
+}
+
 func (e *EmailConfirmation) BeforeSave(tx *gorm.DB) error {
 	if e.ID == uuid.Nil {
 		e.ID = uuid.New()
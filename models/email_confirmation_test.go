@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestEmailConfirmationAttempt(t *testing.T) {
+	e := &EmailConfirmation{Code: "123456"}
+
+	for i := 1; i < MaxCodeAttempts; i++ {
+		if e.Attempt("000000") {
+			t.Fatalf("attempt %d: wrong code accepted", i)
+		}
+		if e.Used {
+			t.Fatalf("attempt %d: code spent before the limit", i)
+		}
+	}
+	if !e.Attempt("123456") {
+		t.Fatal("correct code rejected before the limit")
+	}
+	if e.Attempts != MaxCodeAttempts-1 {
+		t.Fatalf("attempts = %d, want %d", e.Attempts, MaxCodeAttempts-1)
+	}
+}
+
+func TestEmailConfirmationAttemptLimit(t *testing.T) {
+	e := &EmailConfirmation{Code: "123456"}
+
+	for i := 1; i <= MaxCodeAttempts+1; i++ {
+		if e.Attempt("000000") {
+			t.Fatalf("attempt %d: wrong code accepted", i)
+		}
+	}
+	if !e.Used {
+		t.Fatal("code not spent after the limit")
+	}
+	// шестая неверная попытка уже была — верный код после неё не проходит
+	if e.Attempt("123456") {
+		t.Fatal("correct code accepted after the attempt limit")
+	}
+}
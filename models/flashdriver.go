@@ -1,6 +1,7 @@
 package models
 
 import (
+	"Market_backend/internal/common/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,8 +12,8 @@ type FlashDrive struct {
 	SKU             string
 	Name            string
 	Brand           string
-	RetailPrice     float64
-	WholesalePrice  float64
+	RetailPrice     money.Money
+	WholesalePrice  money.Money
 	WholesaleMinQty int
 	Stock           int
 
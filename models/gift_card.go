@@ -0,0 +1,47 @@
+package models
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GiftCard — подарочная карта: код и остаток номинала. Каждое списание и возврат — запись в BalanceTransaction.
+type GiftCard struct {
+	ID            uuid.UUID   `gorm:"type:uuid;primaryKey"`
+	Code          string      `gorm:"not null;uniqueIndex"`
+	InitialAmount money.Money `gorm:"not null"`
+	Balance       money.Money `gorm:"not null"`
+	ExpiresAt     *time.Time
+	Disabled      bool `gorm:"not null;default:false"`
+	Comment       string
+	IssuedByID    *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// StoreCreditWallet — баланс покупателя в магазине: начисляется за возвраты и администратором,
+// тратится на оплату заказов
+type StoreCreditWallet struct {
+	UserID    uuid.UUID   `gorm:"type:uuid;primaryKey"`
+	Balance   money.Money `gorm:"not null;default:0"`
+	UpdatedAt time.Time
+}
+
+// BalanceTransaction — движение по подарочной карте (GiftCardID) или балансу покупателя (UserID).
+// Amount > 0 — начисление, Amount < 0 — списание.
+type BalanceTransaction struct {
+	ID           uuid.UUID           `gorm:"type:uuid;primaryKey"`
+	GiftCardID   *uuid.UUID          `gorm:"type:uuid;index"`
+	UserID       *uuid.UUID          `gorm:"type:uuid;index"`
+	OrderID      *uuid.UUID          `gorm:"type:uuid;index"`
+	PaymentID    *uuid.UUID          `gorm:"type:uuid"`
+	Amount       money.Money         `gorm:"not null"`
+	BalanceAfter money.Money         `gorm:"not null"`
+	Reason       types.BalanceReason `gorm:"type:balance_reason;not null"`
+	Comment      string
+	CreatedByID  *uuid.UUID `gorm:"type:uuid"` // nil — движение сделала система
+	CreatedAt    time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey — запрос с заголовком Idempotency-Key и сохранённый ответ на него.
+// Повтор с тем же ключом в пределах срока хранения получает исходный ответ.
+type IdempotencyKey struct {
+	ID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_idempotency_key"`
+	Scope  string    `gorm:"not null;uniqueIndex:idx_idempotency_key"` // какая операция: order_create, payment_create
+	Key    string    `gorm:"size:255;not null;uniqueIndex:idx_idempotency_key"`
+
+	RequestHash string `gorm:"not null"` // sha256 метода, пути и тела — ключ нельзя переиспользовать для другого запроса
+
+	// Пока запрос выполняется, ответ пустой и Completed = false.
+	// Незавершённый ключ с истёкшим LockedUntil (процесс упал или не сохранил ответ) может занять повторный запрос.
+	Completed    bool      `gorm:"not null;default:false"`
+	LockedUntil  time.Time `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	StatusCode   int
+	ContentType  string
+	ResponseBody []byte
+
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
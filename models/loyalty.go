@@ -0,0 +1,23 @@
+package models
+
+import (
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoyaltyTransaction — движение баллов. Начисления (earn, restore) хранят несписанный остаток Remaining
+// и срок ExpiresAt: баллы тратятся и сгорают начиная с ближайших к сгоранию.
+// Баланс покупателя — сумма остатков его несгоревших начислений.
+type LoyaltyTransaction struct {
+	ID        uuid.UUID         `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID         `gorm:"type:uuid;not null;index"`
+	OrderID   *uuid.UUID        `gorm:"type:uuid;index"`
+	Kind      types.LoyaltyKind `gorm:"type:loyalty_kind;not null"`
+	Points    int               `gorm:"not null"` // > 0 — пополнение, < 0 — списание
+	Remaining int               `gorm:"not null;default:0"`
+	ExpiresAt *time.Time        `gorm:"index"`
+	Comment   string
+	CreatedAt time.Time
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	"time"
 
@@ -8,13 +9,38 @@ import (
 )
 
 type Order struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
-	OrderNumber int32     `gorm:"autoincrement;not null"`
-	UserID      uuid.UUID
-	User        User
-	Status      types.OrderStatus `gorm:"type:order_status;default:in_progress"` // in_progress, paid, delivered
-	Total       float64           // общая сумма заказа
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Items       []OrderItem `gorm:"foreignKey:OrderID"`
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	OrderNumber   int32     `gorm:"autoincrement;not null"`
+	UserID        uuid.UUID
+	User          User
+	Status        types.OrderStatus `gorm:"type:order_status;default:in_progress"` // см. types.OrderStatus
+	Total         money.Money       // общая сумма заказа
+	StockReserved bool              `gorm:"not null;default:false"` // остатки по позициям списаны со склада
+
+	DeliveryMethod  types.DeliveryMethod `gorm:"type:delivery_method;default:store_pickup"`
+	AddressID       *uuid.UUID           `gorm:"type:uuid"`
+	ShippingAddress string               // снимок адреса на момент заказа
+	ShippingCost    money.Money          // входит в Total
+
+	RefundedAmount money.Money `gorm:"not null;default:0"` // возвращено покупателю; меньше Total — частичный возврат
+
+	// Часть заказа, оплаченная бонусными баллами (1 балл = 1 ₽); Total уже уменьшен на PointsDiscount
+	PointsSpent    int         `gorm:"not null;default:0"`
+	PointsDiscount money.Money `gorm:"not null;default:0"`
+
+	PaymentDeadline *time.Time `gorm:"index"` // неоплаченный к этому времени заказ отменяется автоматически
+
+	// Заказ от имени компании: UserID — оформивший участник
+	CompanyID    *uuid.UUID `gorm:"type:uuid;index"`
+	Company      *Company
+	ApprovedByID *uuid.UUID `gorm:"type:uuid"`
+	ApprovedAt   *time.Time
+
+	CreatedByID *uuid.UUID `gorm:"type:uuid"` // менеджер, оформивший заказ за покупателя
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Items     []OrderItem          `gorm:"foreignKey:OrderID"`
+	History   []OrderStatusHistory `gorm:"foreignKey:OrderID"`
+	Shipments []Shipment           `gorm:"foreignKey:OrderID"`
 }
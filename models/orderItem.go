@@ -1,6 +1,7 @@
 package models
 
 import (
+	"Market_backend/internal/common/money"
 	"Market_backend/internal/common/types"
 	"time"
 
@@ -16,7 +17,7 @@ type OrderItem struct {
 	ProductType types.ProductType `gorm:"type:product_type;not null"`
 
 	Quantity  int
-	UnitPrice float64 // цена за единицу на момент заказа (опт или розница)
+	UnitPrice money.Money // цена за единицу на момент заказа (опт или розница)
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
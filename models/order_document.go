@@ -0,0 +1,39 @@
+package models
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderDocument — выпущенный по заказу документ (счёт, УПД).
+// Номер сквозной в пределах типа и календарного года, без пропусков.
+type OrderDocument struct {
+	ID      uuid.UUID          `gorm:"type:uuid;primaryKey"`
+	OrderID uuid.UUID          `gorm:"type:uuid;not null;index"`
+	Type    types.DocumentType `gorm:"type:document_type;not null;uniqueIndex:idx_document_number"`
+	Year    int                `gorm:"not null;uniqueIndex:idx_document_number"`
+	Number  int                `gorm:"not null;uniqueIndex:idx_document_number"`
+
+	// Реквизиты покупателя на момент выпуска
+	BuyerName    string
+	BuyerINN     string
+	BuyerKPP     string
+	BuyerAddress string
+
+	VATRate   float64
+	VATAmount money.Money
+	Total     money.Money
+
+	ObjectKey string `json:"-"` // ключ PDF в MinIO, наружу отдаём только через скачивание
+	CreatedAt time.Time
+}
+
+// DocumentCounter хранит последний выданный номер документа данного типа за год
+type DocumentCounter struct {
+	Type       types.DocumentType `gorm:"type:document_type;primaryKey"`
+	Year       int                `gorm:"primaryKey"`
+	LastNumber int                `gorm:"not null;default:0"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrderMessage — сообщение в переписке по заказу между покупателем и магазином
+type OrderMessage struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	OrderID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	AuthorID  uuid.UUID `gorm:"type:uuid;not null"`
+	Author    User      `gorm:"foreignKey:AuthorID"`
+	FromStaff bool      `gorm:"not null;default:false"` // написал сотрудник магазина
+	Text      string
+
+	Attachments []OrderMessageAttachment `gorm:"foreignKey:MessageID;constraint:OnDelete:CASCADE;"`
+	CreatedAt   time.Time                `gorm:"index"`
+}
+
+type OrderMessageAttachment struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	MessageID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	FileName    string
+	ContentType string
+	Size        int64
+	ObjectKey   string `json:"-"` // файл в MinIO, отдаётся только через скачивание с проверкой доступа
+	CreatedAt   time.Time
+}
+
+// OrderThreadRead — до какого момента пользователь прочитал переписку по заказу
+type OrderThreadRead struct {
+	OrderID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	LastReadAt time.Time `gorm:"not null"`
+}
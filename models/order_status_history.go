@@ -0,0 +1,25 @@
+package models
+
+import (
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OrderStatusHistory struct {
+	ID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	OrderID uuid.UUID `gorm:"type:uuid;not null;index"`
+
+	FromStatus types.OrderStatus `gorm:"type:order_status;not null"`
+	ToStatus   types.OrderStatus `gorm:"type:order_status;not null"`
+
+	ChangedByID *uuid.UUID `gorm:"type:uuid"` // nil — изменено системой (вебхук, планировщик)
+	ChangedBy   *User      `gorm:"foreignKey:ChangedByID"`
+	Comment     string
+	CreatedAt   time.Time
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"Market_backend/internal/common/money"
 	"time"
 
 	"Market_backend/internal/common/types"
@@ -12,10 +13,37 @@ type Payment struct {
 	OrderID   uuid.UUID
 	Order     Order
 	Method    types.PaymentMethod `gorm:"type:payment_method;not null"`        // "bank_card", "sbp"
-	Status    types.PaymentStatus `gorm:"type:payment_status;default:pending"` // pending, succeeded, canceled
-	Amount    float64
+	Status    types.PaymentStatus `gorm:"type:payment_status;default:pending"` // см. types.PaymentStatus
+	Amount    money.Money
 	Currency  string
-	PaymentID string // ID платежа в ЮKassa
+	PaymentID string // ID платежа у шлюза
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	RefundedAmount money.Money `gorm:"not null;default:0"` // сумма успешных возвратов
+
+	// Двухстадийный платёж: при оплате деньги блокируются (HeldAmount), списываются при отгрузке.
+	// После списания Amount — фактически списанная сумма, она может быть меньше заблокированной.
+	TwoStage   bool        `gorm:"not null;default:false"`
+	HeldAmount money.Money `gorm:"not null;default:0"`
+	// Блокировка отменена у нас, но шлюз ещё не подтвердил её снятие
+	VoidPending bool `gorm:"not null;default:false;index"`
+	// Списание CaptureAmount записано у нас, но шлюз ещё не подтвердил его
+	CapturePending bool        `gorm:"not null;default:false;index"`
+	CaptureAmount  money.Money `gorm:"not null;default:0"`
+
+	ReceiptStatus string // регистрация чека 54-ФЗ у шлюза: pending, succeeded, canceled; пусто — чек не передавался
+
+	// Подтверждение офлайн-оплаты (по счёту, при получении) администратором
+	DocumentNumber string     // номер платёжного поручения или кассового документа
+	DocumentDate   *time.Time // дата платёжного документа
+	ConfirmedByID  *uuid.UUID
+	ConfirmedAt    *time.Time
+
+	// Сохранённые карты: покупатель попросил сохранить карту этой оплатой
+	// или оплатил уже сохранённой картой без перехода на страницу шлюза
+	SaveMethod    bool `gorm:"not null;default:false"`
+	SavedMethodID *uuid.UUID
+
+	GiftCardID *uuid.UUID `gorm:"type:uuid;index"` // оплата подарочной картой
 }
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentEvent — входящее уведомление платёжного шлюза. Сохраняется каждое прошедшее проверку подписи;
+// повторная доставка того же события не создаёт новую запись, а увеличивает Deliveries.
+type PaymentEvent struct {
+	ID       uuid.UUID                `gorm:"type:uuid;primaryKey"`
+	Provider string                   `gorm:"not null;uniqueIndex:idx_payment_event_object"`
+	Event    string                   `gorm:"not null;uniqueIndex:idx_payment_event_object"` // payment.succeeded, refund.succeeded, ...
+	ObjectID string                   `gorm:"not null;uniqueIndex:idx_payment_event_object"` // ID платежа или возврата у шлюза
+	Status   types.PaymentEventStatus `gorm:"type:payment_event_status;not null;default:received;index"`
+	Payload  string                   `gorm:"type:text"`
+	Error    string                   // причина последней неудачной обработки или отклонения
+
+	Deliveries  int `gorm:"not null;default:1"`
+	ReceivedAt  time.Time
+	ProcessedAt *time.Time
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"Market_backend/internal/common/money"
 	"github.com/google/uuid"
 	"time"
 )
@@ -10,10 +11,10 @@ type Processor struct {
 	SKU                string
 	Name               string
 	Brand              string
-	RetailPrice        float64 // цена для розницы
-	WholesalePrice     float64 // цена для опта
-	WholesaleMinQty    int     // минимальное количество для опта
-	Stock              int     // остаток
+	RetailPrice        money.Money // цена для розницы
+	WholesalePrice     money.Money // цена для опта
+	WholesaleMinQty    int         // минимальное количество для опта
+	Stock              int         // остаток
 	Line               string
 	Architecture       string
 	Socket             string
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Refund — возврат денег по платежу: через платёжный шлюз, на подарочную карту или баланс покупателя
+type Refund struct {
+	ID               uuid.UUID          `gorm:"type:uuid;primaryKey"`
+	PaymentID        uuid.UUID          `gorm:"type:uuid;not null;index"`
+	OrderID          uuid.UUID          `gorm:"type:uuid;not null;index"`
+	ProviderRefundID string             `gorm:"index"` // ID возврата у шлюза
+	Status           types.RefundStatus `gorm:"type:refund_status;not null;default:pending"`
+	Amount           money.Money        `gorm:"not null"`
+	Currency         string
+	Reason           string
+	ReceiptStatus    string // регистрация чека возврата прихода у шлюза
+	ToStoreCredit    bool   `gorm:"not null;default:false"` // деньги зачислены на баланс покупателя, а не в источник оплаты
+
+	Items []RefundItem `gorm:"foreignKey:RefundID;constraint:OnDelete:CASCADE;"` // пусто — возврат произвольной суммы
+
+	CreatedByID *uuid.UUID `gorm:"type:uuid"` // nil — возврат инициировала система (отмена заказа)
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// RefundItem — позиция заказа, за которую возвращаются деньги
+type RefundItem struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primaryKey"`
+	RefundID    uuid.UUID   `gorm:"type:uuid;not null;index"`
+	OrderItemID uuid.UUID   `gorm:"type:uuid;not null;index"`
+	Quantity    int         `gorm:"not null"`
+	Amount      money.Money `gorm:"not null"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"Market_backend/internal/common/money"
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReturnRequest — заявка на возврат (RMA)
+type ReturnRequest struct {
+	ID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	OrderID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Order   Order
+	UserID  uuid.UUID `gorm:"type:uuid;not null;index"`
+
+	Status       types.ReturnStatus `gorm:"type:return_status;default:requested"`
+	Reason       string             `gorm:"not null"`
+	AdminComment string
+	RefundAmount money.Money // сумма возврата по одобренным позициям
+	Refunded     bool        `gorm:"not null;default:false"`
+
+	Items  []ReturnItem  `gorm:"foreignKey:ReturnRequestID;constraint:OnDelete:CASCADE;"`
+	Photos []ReturnPhoto `gorm:"foreignKey:ReturnRequestID;constraint:OnDelete:CASCADE;"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ReturnItem struct {
+	ID              uuid.UUID              `gorm:"type:uuid;primaryKey"`
+	ReturnRequestID uuid.UUID              `gorm:"type:uuid;not null;index"`
+	OrderItemID     uuid.UUID              `gorm:"type:uuid;not null;index"`
+	OrderItem       OrderItem              `gorm:"foreignKey:OrderItemID"`
+	Quantity        int                    `gorm:"not null"`
+	Resolution      types.ReturnResolution `gorm:"size:16"` // заполняется при приёмке товара
+}
+
+type ReturnPhoto struct {
+	ID              uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ReturnRequestID uuid.UUID `gorm:"type:uuid;not null;index"`
+	URL             string
+	CreatedAt       time.Time
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedPaymentMethod — способ оплаты, сохранённый у шлюза по просьбе покупателя.
+// Хранится только токен шлюза и маскированные данные карты; номер карты магазин не видит.
+type SavedPaymentMethod struct {
+	ID               uuid.UUID           `gorm:"type:uuid;primaryKey"`
+	UserID           uuid.UUID           `gorm:"type:uuid;not null;index"`
+	Provider         string              `gorm:"not null;uniqueIndex:idx_saved_payment_method_provider"`
+	ProviderMethodID string              `gorm:"not null;uniqueIndex:idx_saved_payment_method_provider"` // токен шлюза для повторных оплат
+	Type             types.PaymentMethod `gorm:"type:payment_method;not null"`
+	Title            string              // "Bank card *4444"
+	CardType         string              // Visa, MasterCard, Mir, ...
+	Last4            string
+	ExpiryMonth      string
+	ExpiryYear       string
+	CreatedAt        time.Time
+	LastUsedAt       *time.Time
+}
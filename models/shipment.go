@@ -0,0 +1,30 @@
+package models
+
+import (
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Shipment struct {
+	ID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	OrderID uuid.UUID `gorm:"type:uuid;not null;index"`
+
+	Carrier        string
+	TrackingNumber string               `gorm:"index"`
+	Status         types.ShipmentStatus `gorm:"type:shipment_status;default:pending"`
+	ShippedAt      *time.Time
+	DeliveredAt    *time.Time
+
+	Items     []ShipmentItem `gorm:"foreignKey:ShipmentID;constraint:OnDelete:CASCADE;"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ShipmentItem struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	ShipmentID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	OrderItemID uuid.UUID `gorm:"type:uuid;not null;index"`
+	Quantity    int       `gorm:"not null"`
+}
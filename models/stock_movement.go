@@ -0,0 +1,20 @@
+package models
+
+import (
+	"Market_backend/internal/common/types"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StockMovement — журнал движения остатков
+type StockMovement struct {
+	ID          uuid.UUID                 `gorm:"type:uuid;primaryKey"`
+	ProductID   uuid.UUID                 `gorm:"type:uuid;not null;index"`
+	ProductType types.ProductType         `gorm:"type:product_type;not null"`
+	Delta       int                       `gorm:"not null"` // + приход на склад, - расход
+	Reason      types.StockMovementReason `gorm:"size:16;not null"`
+	OrderID     *uuid.UUID                `gorm:"type:uuid;index"`
+	Comment     string
+	CreatedAt   time.Time
+}